@@ -0,0 +1,131 @@
+// Package archive encrypts directory trees into a single OpenPGP message and
+// lists and extracts them again.
+//
+// The files are stored as a tar archive in the literal data of the message,
+// with their path, permissions and modification time, so that decrypted
+// archives can also be extracted with tar. Only regular files and directories
+// are supported, owners are not stored.
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+// Entry is a file or directory in an archive.
+type Entry struct {
+	// Path is the slash-separated path relative to the root of the archive.
+	Path string
+	// Mode contains the permission bits and fs.ModeDir for directories.
+	Mode    fs.FileMode
+	ModTime time.Time
+	// Size is the size of a file in bytes, 0 for directories.
+	Size int64
+}
+
+// IsDir returns true if the entry is a directory.
+func (entry *Entry) IsDir() bool {
+	return entry.Mode.IsDir()
+}
+
+// Limits restrict the archives that are read, to protect against archives
+// that exhaust the disk or memory. A limit of 0 means no limit.
+type Limits struct {
+	// MaxEntries is the maximum number of files and directories.
+	MaxEntries int
+	// MaxFileSize is the maximum size of a single file in bytes.
+	MaxFileSize int64
+	// MaxTotalSize is the maximum total size of all files in bytes.
+	MaxTotalSize int64
+}
+
+// DefaultLimits returns limits of one million entries, 16 GiB per file and
+// 64 GiB in total.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxEntries:   1 << 20,
+		MaxFileSize:  1 << 34,
+		MaxTotalSize: 1 << 36,
+	}
+}
+
+// WriteDir encrypts the directory tree at dir into an archive, see Write.
+// Symbolic links are not followed.
+func WriteDir(output io.Writer, dir string, encryption crypto.PGPEncryption, encoding int8) error {
+	return Write(output, os.DirFS(dir), encryption, encoding)
+}
+
+// Write encrypts the file system into an archive with the encryption handle
+// and writes the message to output in the given encoding, i.e. crypto.Bytes
+// or crypto.Armor. The archive is signed if the handle has signing keys.
+// The root directory itself is not stored. It returns an error for files that
+// are neither regular files nor directories.
+func Write(output io.Writer, fsys fs.FS, encryption crypto.PGPEncryption, encoding int8) error {
+	messageWriter, err := encryption.EncryptingWriter(output, encoding)
+	if err != nil {
+		return err
+	}
+	tarWriter := tar.NewWriter(messageWriter)
+	err = fs.WalkDir(fsys, ".", func(name string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		return writeEntry(tarWriter, fsys, name, info)
+	})
+	if err != nil {
+		return fmt.Errorf("gopenpgp: error in writing archive: %w", err)
+	}
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("gopenpgp: error in writing archive: %w", err)
+	}
+	return messageWriter.Close()
+}
+
+// writeEntry writes the header and the content of a file or directory.
+func writeEntry(tarWriter *tar.Writer, fsys fs.FS, name string, info fs.FileInfo) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    int64(info.Mode().Perm()),
+		ModTime: info.ModTime(),
+	}
+	switch {
+	case info.IsDir():
+		header.Typeflag = tar.TypeDir
+		header.Name += "/"
+		return tarWriter.WriteHeader(header)
+	case info.Mode().IsRegular():
+		header.Typeflag = tar.TypeReg
+		header.Size = info.Size()
+	default:
+		return fmt.Errorf("unsupported file type of %s", name)
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	file, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tarWriter, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if errors.Is(err, tar.ErrWriteTooLong) {
+		return fmt.Errorf("%s changed while writing the archive", name)
+	}
+	return err
+}
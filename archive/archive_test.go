@@ -0,0 +1,215 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+var testModTime = time.Unix(1717200000, 0) // 2024-06-01T00:00:00+00:00
+
+var testFS = fstest.MapFS{
+	"readme.txt":          {Data: []byte("hello\n"), Mode: 0o644, ModTime: testModTime},
+	"bin":                 {Mode: fs.ModeDir | 0o755, ModTime: testModTime},
+	"bin/run.sh":          {Data: []byte("#!/bin/sh\n"), Mode: 0o755, ModTime: testModTime},
+	"data/nested/big.bin": {Data: bytes.Repeat([]byte{0xa5}, 100000), Mode: 0o600, ModTime: testModTime},
+}
+
+func generateTestKey(t *testing.T) *crypto.Key {
+	key, err := crypto.PGP().KeyGeneration().AddUserId("archive", "archive@example.org").New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	return key
+}
+
+func encryptTestArchive(t *testing.T, key *crypto.Key, fsys fs.FS) []byte {
+	encryption, err := crypto.PGP().Encryption().Recipient(key).SigningKey(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	var encrypted bytes.Buffer
+	if err := Write(&encrypted, fsys, encryption, crypto.Armor); err != nil {
+		t.Fatal("Expected no error while writing archive, got:", err)
+	}
+	return encrypted.Bytes()
+}
+
+func newTestDecryption(t *testing.T, key *crypto.Key) crypto.PGPDecryption {
+	decryption, err := crypto.PGP().Decryption().DecryptionKey(key).VerificationKey(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryption handle, got:", err)
+	}
+	return decryption
+}
+
+func TestListArchive(t *testing.T) {
+	key := generateTestKey(t)
+	encrypted := encryptTestArchive(t, key, testFS)
+
+	entries, result, err := List(bytes.NewReader(encrypted), newTestDecryption(t, key), crypto.Armor, DefaultLimits())
+	if err != nil {
+		t.Fatal("Expected no error while listing archive, got:", err)
+	}
+	assert.NoError(t, result.SignatureError())
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+		if file, ok := testFS[entry.Path]; ok {
+			assert.True(t, testModTime.Equal(entry.ModTime), entry.Path)
+			assert.Exactly(t, file.Mode, entry.Mode, entry.Path)
+			assert.Exactly(t, int64(len(file.Data)), entry.Size, entry.Path)
+		}
+	}
+	assert.Exactly(t, []string{"bin", "bin/run.sh", "data", "data/nested", "data/nested/big.bin", "readme.txt"}, paths)
+	assert.True(t, entries[0].IsDir())
+	assert.False(t, entries[1].IsDir())
+}
+
+func TestExtractArchive(t *testing.T) {
+	key := generateTestKey(t)
+	encrypted := encryptTestArchive(t, key, testFS)
+
+	dir := filepath.Join(t.TempDir(), "extracted")
+	result, err := Extract(bytes.NewReader(encrypted), dir, newTestDecryption(t, key), crypto.Armor, DefaultLimits())
+	if err != nil {
+		t.Fatal("Expected no error while extracting archive, got:", err)
+	}
+	assert.NoError(t, result.SignatureError())
+	for name, file := range testFS {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		info, err := os.Stat(target)
+		if err != nil {
+			t.Fatal("Expected no error while reading extracted file, got:", err)
+		}
+		assert.Exactly(t, file.Mode, info.Mode(), name)
+		assert.True(t, testModTime.Equal(info.ModTime()), name)
+		if !file.Mode.IsDir() {
+			data, err := os.ReadFile(target) //nolint
+			if err != nil {
+				t.Fatal("Expected no error while reading extracted file, got:", err)
+			}
+			assert.Exactly(t, file.Data, data, name)
+		}
+	}
+
+	// Extracting again must not overwrite the files
+	_, err = Extract(bytes.NewReader(encrypted), dir, newTestDecryption(t, key), crypto.Armor, DefaultLimits())
+	assert.Error(t, err)
+}
+
+func TestWriteDirArchive(t *testing.T) {
+	key := generateTestKey(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("content"), 0o600); err != nil {
+		t.Fatal("Expected no error while writing file, got:", err)
+	}
+	encryption, err := crypto.PGP().Encryption().Recipient(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	var encrypted bytes.Buffer
+	if err := WriteDir(&encrypted, dir, encryption, crypto.Bytes); err != nil {
+		t.Fatal("Expected no error while writing archive, got:", err)
+	}
+
+	reader, err := NewReader(&encrypted, newTestDecryption(t, key), crypto.Bytes, Limits{})
+	if err != nil {
+		t.Fatal("Expected no error while reading archive, got:", err)
+	}
+	entry, err := reader.Next()
+	if err != nil {
+		t.Fatal("Expected no error while reading entry, got:", err)
+	}
+	assert.Exactly(t, "file.txt", entry.Path)
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading file content, got:", err)
+	}
+	assert.Exactly(t, "content", string(content))
+	_, err = reader.Next()
+	assert.ErrorIs(t, err, io.EOF)
+	result, err := reader.VerifySignature()
+	if err != nil {
+		t.Fatal("Expected no error while verifying archive, got:", err)
+	}
+	assert.Error(t, result.SignatureError())
+}
+
+func TestArchiveLimits(t *testing.T) {
+	key := generateTestKey(t)
+	encrypted := encryptTestArchive(t, key, testFS)
+	for _, limits := range []Limits{
+		{MaxEntries: 5},
+		{MaxFileSize: 99999},
+		{MaxTotalSize: 100010},
+	} {
+		_, _, err := List(bytes.NewReader(encrypted), newTestDecryption(t, key), crypto.Armor, limits)
+		assert.Error(t, err, limits)
+	}
+	_, _, err := List(bytes.NewReader(encrypted), newTestDecryption(t, key), crypto.Armor, Limits{MaxEntries: 6, MaxFileSize: 100000})
+	assert.NoError(t, err)
+}
+
+func TestExtractUnsafeArchive(t *testing.T) {
+	key := generateTestKey(t)
+	for _, header := range []*tar.Header{
+		{Name: "../escaped.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "/absolute.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "dir/../../escaped.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+	} {
+		var plaintext bytes.Buffer
+		tarWriter := tar.NewWriter(&plaintext)
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal("Expected no error while writing tar header, got:", err)
+		}
+		if err := tarWriter.Close(); err != nil {
+			t.Fatal("Expected no error while closing tar writer, got:", err)
+		}
+		encryption, err := crypto.PGP().Encryption().Recipient(key).New()
+		if err != nil {
+			t.Fatal("Expected no error while creating encryption handle, got:", err)
+		}
+		message, err := encryption.Encrypt(plaintext.Bytes())
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+
+		parent := t.TempDir()
+		dir := filepath.Join(parent, "extracted")
+		_, err = Extract(bytes.NewReader(message.Bytes()), dir, newTestDecryption(t, key), crypto.Bytes, DefaultLimits())
+		assert.Error(t, err, header.Name)
+		files, err := os.ReadDir(parent)
+		if err != nil {
+			t.Fatal("Expected no error while reading directory, got:", err)
+		}
+		assert.Len(t, files, 1, header.Name)
+	}
+}
+
+func TestExtractThroughSymlink(t *testing.T) {
+	key := generateTestKey(t)
+	encrypted := encryptTestArchive(t, key, testFS)
+	dir, outside := t.TempDir(), t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "data")); err != nil {
+		t.Fatal("Expected no error while creating symbolic link, got:", err)
+	}
+	_, err := Extract(bytes.NewReader(encrypted), dir, newTestDecryption(t, key), crypto.Armor, DefaultLimits())
+	assert.Error(t, err)
+	files, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal("Expected no error while reading directory, got:", err)
+	}
+	assert.Empty(t, files)
+}
@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+// Extract decrypts the archive into the directory dir, which is created if
+// it does not exist, and returns the result of verifying its signature, see
+// NewReader.
+// Existing files are never overwritten and paths through symbolic links are
+// rejected, so that no file outside of dir is changed.
+// The files are written before the signature can be verified. If the
+// signature is required, the caller must remove dir if the verification
+// fails.
+func Extract(input io.Reader, dir string, decryption crypto.PGPDecryption, encoding int8, limits Limits) (*crypto.VerifyResult, error) {
+	reader, err := NewReader(input, decryption, encoding, limits)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in creating directory: %w", err)
+	}
+	// The modes and times of directories are set at the end, as extracting
+	// their content would change them.
+	var directories []*Entry
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if entry.IsDir() {
+			directories = append(directories, entry)
+		}
+		if err := extractEntry(dir, entry, reader); err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in extracting %q: %w", entry.Path, err)
+		}
+	}
+	for i := len(directories) - 1; i >= 0; i-- {
+		entry := directories[i]
+		target := filepath.Join(dir, filepath.FromSlash(entry.Path))
+		if err := os.Chmod(target, entry.Mode.Perm()); err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in extracting %q: %w", entry.Path, err)
+		}
+		if err := os.Chtimes(target, entry.ModTime, entry.ModTime); err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in extracting %q: %w", entry.Path, err)
+		}
+	}
+	return reader.VerifySignature()
+}
+
+// extractEntry creates the directory or file of the entry in dir.
+func extractEntry(dir string, entry *Entry, content io.Reader) error {
+	if err := createParents(dir, entry.Path); err != nil {
+		return err
+	}
+	target := filepath.Join(dir, filepath.FromSlash(entry.Path))
+	if entry.IsDir() {
+		err := os.Mkdir(target, entry.Mode.Perm()|0o700)
+		if errors.Is(err, fs.ErrExist) {
+			info, statErr := os.Lstat(target)
+			if statErr == nil && info.IsDir() {
+				return nil
+			}
+		}
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, entry.Mode.Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(target, entry.ModTime, entry.ModTime)
+}
+
+// createParents creates the missing parent directories of the path in dir,
+// and returns an error if one of them is not a directory, e.g. a symbolic
+// link.
+func createParents(dir, name string) error {
+	parent := dir
+	components := strings.Split(name, "/")
+	for _, component := range components[:len(components)-1] {
+		parent = filepath.Join(parent, component)
+		info, err := os.Lstat(parent)
+		if errors.Is(err, fs.ErrNotExist) {
+			if err := os.Mkdir(parent, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", parent)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+// Reader decrypts an archive and reads its entries one after the other.
+type Reader struct {
+	message *crypto.VerifyDataReader
+	tar     *tar.Reader
+	limits  Limits
+
+	entries   int
+	totalSize int64
+}
+
+// NewReader decrypts the archive in the given encoding, i.e. crypto.Bytes or
+// crypto.Armor, with the decryption handle.
+// The entries are checked against the limits while reading.
+func NewReader(input io.Reader, decryption crypto.PGPDecryption, encoding int8, limits Limits) (*Reader, error) {
+	message, err := decryption.DecryptingReader(input, encoding)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		message: message,
+		tar:     tar.NewReader(message),
+		limits:  limits,
+	}, nil
+}
+
+// Next advances to the next entry of the archive, whose content can then be
+// read from the Reader. It returns io.EOF at the end of the archive.
+// Entries with paths outside of the root of the archive, e.g. with "..",
+// are rejected with an error.
+func (r *Reader) Next() (*Entry, error) {
+	header, err := r.tar.Next()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in reading archive: %w", err)
+	}
+	r.entries++
+	if r.limits.MaxEntries > 0 && r.entries > r.limits.MaxEntries {
+		return nil, fmt.Errorf("gopenpgp: the archive has more than %d entries", r.limits.MaxEntries)
+	}
+	name := strings.TrimSuffix(header.Name, "/")
+	if !fs.ValidPath(name) || name == "." || strings.Contains(name, `\`) || path.Clean(name) != name {
+		return nil, fmt.Errorf("gopenpgp: invalid path %q in archive", header.Name)
+	}
+	entry := &Entry{
+		Path:    name,
+		Mode:    fs.FileMode(header.Mode).Perm(),
+		ModTime: header.ModTime,
+	}
+	switch header.Typeflag {
+	case tar.TypeDir:
+		entry.Mode |= fs.ModeDir
+	case tar.TypeReg:
+		entry.Size = header.Size
+	default:
+		return nil, fmt.Errorf("gopenpgp: unsupported type of %q in archive", header.Name)
+	}
+	if r.limits.MaxFileSize > 0 && entry.Size > r.limits.MaxFileSize {
+		return nil, fmt.Errorf("gopenpgp: %q exceeds the maximum file size", header.Name)
+	}
+	r.totalSize += entry.Size
+	if r.limits.MaxTotalSize > 0 && r.totalSize > r.limits.MaxTotalSize {
+		return nil, errors.New("gopenpgp: the archive exceeds the maximum total size")
+	}
+	return entry, nil
+}
+
+// Read reads the content of the current file.
+func (r *Reader) Read(b []byte) (int, error) {
+	return r.tar.Read(b)
+}
+
+// VerifySignature verifies the signature of the archive once Next returned
+// io.EOF, see crypto.VerifyDataReader.VerifySignature.
+func (r *Reader) VerifySignature() (*crypto.VerifyResult, error) {
+	// The literal data may continue after the end of the tar archive
+	if err := r.message.DiscardAll(); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in reading archive: %w", err)
+	}
+	return r.message.VerifySignature()
+}
+
+// List decrypts the archive and returns its entries without extracting them,
+// and the result of verifying its signature, see NewReader.
+func List(input io.Reader, decryption crypto.PGPDecryption, encoding int8, limits Limits) ([]*Entry, *crypto.VerifyResult, error) {
+	reader, err := NewReader(input, decryption, encoding, limits)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []*Entry
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, entry)
+	}
+	result, err := reader.VerifySignature()
+	if err != nil {
+		return nil, nil, err
+	}
+	return entries, result, nil
+}
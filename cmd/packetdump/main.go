@@ -0,0 +1,104 @@
+// Command packetdump lists the packets of OpenPGP keys, messages and
+// signatures, armored or binary, for debugging interoperability issues.
+//
+// Usage:
+//
+//	packetdump [-json] [-session-key algo:hex] [file...]
+//
+// The input is read from the files or from the standard input. The session
+// key, e.g. "9:0123..." for AES256 as printed by gpg --show-session-key,
+// decrypts encrypted data to list its packets as well. The algorithm may be
+// omitted for SEIPDv2 data.
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lovoo/gopenpgp/v3/constants"
+	"github.com/lovoo/gopenpgp/v3/crypto"
+	"github.com/lovoo/gopenpgp/v3/packetdump"
+)
+
+var cipherNames = map[int]string{
+	2: constants.TripleDES,
+	3: constants.CAST5,
+	7: constants.AES128,
+	8: constants.AES192,
+	9: constants.AES256,
+}
+
+func main() {
+	jsonOutput := flag.Bool("json", false, "print the packets as JSON")
+	sessionKeyFlag := flag.String("session-key", "", "session key as algo:hex, e.g. 9:0123..., to decrypt encrypted data")
+	flag.Parse()
+
+	sessionKey, err := parseSessionKey(*sessionKeyFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "packetdump:", err)
+		os.Exit(2)
+	}
+	inputs := flag.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	failed := false
+	for _, input := range inputs {
+		if err := dump(input, sessionKey, *jsonOutput); err != nil {
+			fmt.Fprintln(os.Stderr, "packetdump:", err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func dump(input string, sessionKey *crypto.SessionKey, jsonOutput bool) error {
+	var reader io.Reader = os.Stdin
+	if input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+	packets, dumpErr := packetdump.Dump(reader, sessionKey)
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(packets); err != nil {
+			return err
+		}
+	} else if err := packetdump.Print(os.Stdout, packets); err != nil {
+		return err
+	}
+	return dumpErr
+}
+
+func parseSessionKey(value string) (*crypto.SessionKey, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var algo string
+	if algoID, keyHex, found := strings.Cut(value, ":"); found {
+		id, err := strconv.Atoi(algoID)
+		if err != nil || cipherNames[id] == "" {
+			return nil, fmt.Errorf("unsupported session key algorithm %q", algoID)
+		}
+		algo, value = cipherNames[id], keyHex
+	}
+	key, err := hex.DecodeString(value)
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("invalid session key")
+	}
+	return crypto.NewSessionKeyFromToken(key, algo), nil
+}
@@ -0,0 +1,230 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+)
+
+// BatchDecryption decrypts batches of messages with a bounded pool of
+// workers that share the decryption handle, i.e., its unlocked decryption
+// keys and passwords.
+// The session keys of the messages are cached by the hash of their key
+// packets, such that the key packets of messages sharing them are only
+// decrypted once. The cache is kept across batches until ClearCache is called.
+type BatchDecryption struct {
+	decryption  PGPDecryption
+	workers     int
+	lock        sync.Mutex
+	sessionKeys map[[sha256.Size]byte]*cachedSessionKey
+}
+
+// BatchDecryptionResult is the result of decrypting a message of a batch.
+type BatchDecryptionResult struct {
+	// Result is the decrypted message, nil if the decryption failed.
+	Result *VerifiedDataResult
+	// Err is the error of the decryption of the message, if any.
+	Err error
+}
+
+// cachedSessionKey is a session key of the cache, decrypted once.
+type cachedSessionKey struct {
+	once       sync.Once
+	sessionKey *SessionKey
+	origin     *sessionKeyOrigin
+	err        error
+}
+
+// NewBatchDecryption creates a batch decryption with the decryption handle.
+// The number of workers bounds the number of messages that are decrypted
+// concurrently. If it is not positive, runtime.GOMAXPROCS(0) workers are used.
+func NewBatchDecryption(decryption PGPDecryption, workers int) *BatchDecryption {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &BatchDecryption{
+		decryption:  decryption,
+		workers:     workers,
+		sessionKeys: make(map[[sha256.Size]byte]*cachedSessionKey),
+	}
+}
+
+// Decrypt decrypts the messages and returns the result of each message at
+// its index. A failing message does not abort the batch, its error is
+// returned in its result.
+func (bd *BatchDecryption) Decrypt(messages []*PGPMessage) []*BatchDecryptionResult {
+	return bd.run(len(messages), func(index int) (*VerifiedDataResult, error) {
+		return bd.decryptMessage(messages[index])
+	})
+}
+
+// DecryptReaders decrypts the messages read from the inputs and returns the
+// result of each message at its index. The encoding indicates if the input
+// messages should be unarmored or not, i.e., Bytes/Armor/Auto where Auto
+// tries to detect automatically. A failing message does not abort the batch,
+// its error is returned in its result.
+func (bd *BatchDecryption) DecryptReaders(inputs []Reader, encoding int8) []*BatchDecryptionResult {
+	return bd.run(len(inputs), func(index int) (*VerifiedDataResult, error) {
+		return bd.decryptReader(inputs[index], encoding)
+	})
+}
+
+// ClearCache clears the cached session keys from memory.
+// It must not be called while a batch is decrypted.
+func (bd *BatchDecryption) ClearCache() {
+	bd.lock.Lock()
+	defer bd.lock.Unlock()
+	for _, entry := range bd.sessionKeys {
+		if entry.sessionKey != nil {
+			entry.sessionKey.Clear()
+		}
+	}
+	bd.sessionKeys = make(map[[sha256.Size]byte]*cachedSessionKey)
+}
+
+// run decrypts count messages with the workers.
+func (bd *BatchDecryption) run(count int, decrypt func(index int) (*VerifiedDataResult, error)) []*BatchDecryptionResult {
+	results := make([]*BatchDecryptionResult, count)
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < min(bd.workers, count); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indices {
+				result, err := decrypt(index)
+				results[index] = &BatchDecryptionResult{Result: result, Err: err}
+			}
+		}()
+	}
+	for index := 0; index < count; index++ {
+		indices <- index
+	}
+	close(indices)
+	wg.Wait()
+	return results
+}
+
+func (bd *BatchDecryption) decryptMessage(message *PGPMessage) (*VerifiedDataResult, error) {
+	decryption := bd.decryption
+	if handle, ok := bd.cachingHandle(); ok && len(message.KeyPacket) > 0 {
+		entry := bd.sessionKey(handle, message.KeyPacket)
+		if entry.err != nil {
+			return nil, entry.err
+		}
+		decryption = handle.withSessionKey(entry)
+	}
+	if message.DetachedSignature != nil {
+		return decryption.DecryptDetached(message.Bytes(), message.DetachedSignature, Bytes)
+	}
+	return decryption.Decrypt(message.Bytes(), Bytes)
+}
+
+func (bd *BatchDecryption) decryptReader(input Reader, encoding int8) (*VerifiedDataResult, error) {
+	handle, ok := bd.cachingHandle()
+	if !ok {
+		reader, err := bd.decryption.DecryptingReader(input, encoding)
+		if err != nil {
+			return nil, err
+		}
+		return reader.ReadAllAndVerifySignature()
+	}
+	input, armored := unarmorInput(encoding, input)
+	if armored {
+		armoredBlock, err := armor.Decode(input)
+		if err != nil {
+			return nil, fmt.Errorf("gopenpgp: unarmor failed for pgp message: %w", err)
+		}
+		input = armoredBlock.Body
+	}
+	keyPackets, dataHeader, _, err := readKeyPacketSection(input)
+	if err != nil {
+		return nil, err
+	}
+	message := io.MultiReader(bytes.NewReader(keyPackets), bytes.NewReader(dataHeader), input)
+	if len(keyPackets) == 0 {
+		reader, err := bd.decryption.DecryptingReader(message, Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return reader.ReadAllAndVerifySignature()
+	}
+	entry := bd.sessionKey(handle, keyPackets)
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	reader, err := handle.withSessionKey(entry).DecryptingReader(message, Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return reader.ReadAllAndVerifySignature()
+}
+
+// cachingHandle returns the decryption handle if its session keys can be
+// cached, i.e., if it decrypts session keys with decryption keys or passwords.
+func (bd *BatchDecryption) cachingHandle() (*decryptionHandle, bool) {
+	handle, ok := bd.decryption.(*decryptionHandle)
+	if !ok || len(handle.SessionKeys) > 0 || (handle.DecryptionKeyRing == nil && len(handle.Passwords) == 0) {
+		return nil, false
+	}
+	return handle, true
+}
+
+// sessionKey returns the cache entry of the key packets, decrypting the
+// session key with the handle on the first use.
+// Failed decryptions are not cached, such that later messages with the same
+// key packets are decrypted again.
+func (bd *BatchDecryption) sessionKey(handle *decryptionHandle, keyPackets []byte) *cachedSessionKey {
+	hash := sha256.Sum256(keyPackets)
+	bd.lock.Lock()
+	entry, ok := bd.sessionKeys[hash]
+	if !ok {
+		entry = &cachedSessionKey{}
+		bd.sessionKeys[hash] = entry
+	}
+	bd.lock.Unlock()
+	entry.once.Do(func() {
+		entry.sessionKey, entry.origin, entry.err = decryptSessionKeyWithOrigin(handle, keyPackets)
+		if entry.err != nil {
+			bd.lock.Lock()
+			if bd.sessionKeys[hash] == entry {
+				delete(bd.sessionKeys, hash)
+			}
+			bd.lock.Unlock()
+		}
+	})
+	return entry
+}
+
+// decryptSessionKeyWithOrigin decrypts the session key from the key packets
+// with the decryption keys or the passwords of the handle.
+func decryptSessionKeyWithOrigin(handle *decryptionHandle, keyPackets []byte) (sessionKey *SessionKey, origin *sessionKeyOrigin, err error) {
+	if handle.DecryptionKeyRing != nil {
+		var decryptedWith *openpgp.Key
+		if sessionKey, decryptedWith, err = decryptSessionKeyWithKey(handle.DecryptionKeyRing, keyPackets, handle.decryptionConfig(handle.clock().Unix())); err == nil {
+			return sessionKey, &sessionKeyOrigin{decryptedWith: *decryptedWith}, nil
+		}
+	}
+	for _, password := range handle.Passwords {
+		if sessionKey, err = decryptSessionKeyWithPassword(keyPackets, password); err == nil {
+			return sessionKey, &sessionKeyOrigin{password: true}, nil
+		}
+	}
+	return nil, nil, err
+}
+
+// withSessionKey returns a copy of the handle that decrypts with the cached
+// session key instead of decrypting the key packets.
+func (dh *decryptionHandle) withSessionKey(entry *cachedSessionKey) *decryptionHandle {
+	handle := *dh
+	handle.SessionKeys = []*SessionKey{entry.sessionKey}
+	handle.sessionKeyOrigin = entry.origin
+	handle.Passwords = nil
+	return &handle
+}
@@ -0,0 +1,138 @@
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchDecryption(t *testing.T) {
+	encHandle, _ := testPGP.Encryption().Recipients(keyRingTestPublic).SigningKeys(keyRingTestPrivate).New()
+	var messages []*PGPMessage
+	for i := 0; i < 10; i++ {
+		message, err := encHandle.Encrypt([]byte(fmt.Sprintf("message %d", i)))
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		messages = append(messages, message)
+	}
+	// Copies of a message share their key packets
+	messages = append(messages, messages[0], messages[1])
+	// A message with a broken data packet and a message for another key
+	broken := NewPGPSplitMessage(messages[2].KeyPacket, messages[2].DataPacket[:20])
+	wrongHandle, _ := testPGP.Encryption().Recipients(keyRingTestPublic).Password([]byte("password")).New()
+	wrong, err := testPGP.Encryption().Password([]byte("password")).New()
+	if err != nil || wrongHandle == nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	wrongMessage, err := wrong.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	messages = append(messages, broken, wrongMessage)
+
+	decHandle, _ := testPGP.Decryption().DecryptionKeys(keyRingTestPrivate).VerificationKeys(keyRingTestPublic).New()
+	batch := NewBatchDecryption(decHandle, 4)
+	results := batch.Decrypt(messages)
+	assert.Len(t, results, len(messages))
+	for i, result := range results[:12] {
+		if result.Err != nil {
+			t.Fatal("Expected no error while decrypting, got:", result.Err)
+		}
+		assert.Exactly(t, fmt.Sprintf("message %d", i%10), result.Result.String())
+		assert.NoError(t, result.Result.SignatureError())
+	}
+	assert.Error(t, results[12].Err)
+	assert.Nil(t, results[12].Result)
+	assert.Error(t, results[13].Err)
+	// Failed decryptions of session keys are not cached
+	assert.Len(t, batch.sessionKeys, 10)
+	// Session keys are only returned on request
+	assert.Nil(t, results[0].Result.SessionKey())
+
+	// The cache is kept across batches
+	var inputs []Reader
+	for _, message := range messages[:10] {
+		armored, err := message.Armor()
+		if err != nil {
+			t.Fatal("Expected no error while armoring, got:", err)
+		}
+		inputs = append(inputs, bytes.NewReader([]byte(armored)))
+	}
+	results = batch.DecryptReaders(inputs, Auto)
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatal("Expected no error while decrypting, got:", result.Err)
+		}
+		assert.Exactly(t, fmt.Sprintf("message %d", i), result.Result.String())
+		assert.NoError(t, result.Result.SignatureError())
+		assert.Nil(t, result.Result.SessionKey())
+	}
+	assert.Len(t, batch.sessionKeys, 10)
+
+	batch.ClearCache()
+	assert.Empty(t, batch.sessionKeys)
+}
+
+func TestBatchDecryptionWithSessionKey(t *testing.T) {
+	sessionKey, err := testPGP.GenerateSessionKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating session key, got:", err)
+	}
+	encHandle, _ := testPGP.Encryption().SessionKey(sessionKey).Recipients(keyRingTestPublic).New()
+	message, err := encHandle.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, _ := testPGP.Decryption().SessionKey(sessionKey).New()
+	batch := NewBatchDecryption(decHandle, 0)
+	results := batch.DecryptReaders([]Reader{bytes.NewReader(message.Bytes())}, Bytes)
+	if results[0].Err != nil {
+		t.Fatal("Expected no error while decrypting, got:", results[0].Err)
+	}
+	assert.Exactly(t, testMessageString, results[0].Result.String())
+	assert.Empty(t, batch.sessionKeys)
+}
+
+func TestBatchDecryptionRetrieveSessionKey(t *testing.T) {
+	encHandle, _ := testPGP.Encryption().Recipients(keyRingTestPublic).New()
+	message, err := encHandle.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, _ := testPGP.Decryption().DecryptionKeys(keyRingTestPrivate).RetrieveSessionKey().New()
+	sessionKey, err := decHandle.DecryptSessionKey(message.KeyPacket)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	batch := NewBatchDecryption(decHandle, 1)
+	results := batch.Decrypt([]*PGPMessage{message})
+	if results[0].Err != nil {
+		t.Fatal("Expected no error while decrypting, got:", results[0].Err)
+	}
+	if assert.NotNil(t, results[0].Result.SessionKey()) {
+		assert.Exactly(t, sessionKey.Key, results[0].Result.SessionKey().Key)
+	}
+}
+
+func TestBatchDecryptionUnencrypted(t *testing.T) {
+	signer, _ := testPGP.Sign().SigningKeys(keyRingTestPrivate).New()
+	signed, err := signer.Sign([]byte(testMessageString), Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	decHandle, _ := testPGP.Decryption().DecryptionKeys(keyRingTestPrivate).VerificationKeys(keyRingTestPublic).New()
+	batch := NewBatchDecryption(decHandle, 1)
+	results := batch.Decrypt([]*PGPMessage{NewPGPMessage(signed)})
+	readerResults := batch.DecryptReaders([]Reader{bytes.NewReader(signed)}, Bytes)
+	for _, result := range append(results, readerResults...) {
+		if result.Err != nil {
+			t.Fatal("Expected no error while decrypting, got:", result.Err)
+		}
+		assert.Exactly(t, testMessageString, result.Result.String())
+		assert.NoError(t, result.Result.SignatureError())
+	}
+	assert.Empty(t, batch.sessionKeys)
+}
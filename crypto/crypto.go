@@ -15,14 +15,25 @@ package crypto
 import (
 	"time"
 
-	"github.com/ProtonMail/gopenpgp/v3/profile"
+	"github.com/lovoo/gopenpgp/v3/policy"
+	"github.com/lovoo/gopenpgp/v3/profile"
 )
 
 type PGPHandle struct {
-	profile     *profile.Custom
+	profile     Profile
+	policy      *policy.Policy
 	defaultTime Clock
 }
 
+// Profile configures the algorithms of a PGPHandle. It is implemented by
+// *profile.Custom of this module and of github.com/ProtonMail/gopenpgp/v3/profile.
+type Profile interface {
+	KeyGenerationProfile
+	EncryptionProfile
+	KeyEncryptionProfile
+	SignProfile
+}
+
 // PGP creates a PGPHandle to interact with the API.
 // Uses the default profile for configuration.
 func PGP() *PGPHandle {
@@ -30,36 +41,49 @@ func PGP() *PGPHandle {
 }
 
 // PGPWithProfile creates a PGPHandle to interact with the API.
-// Uses the provided profile for configuration.
-func PGPWithProfile(profile *profile.Custom) *PGPHandle {
+// Uses the provided profile for configuration, e.g. profile.PostQuantum().
+func PGPWithProfile(profile Profile) *PGPHandle {
 	return &PGPHandle{
 		profile:     profile,
 		defaultTime: time.Now,
 	}
 }
 
+// WithPolicy returns a copy of the handle that enforces the cryptographic
+// policy in the handles it creates for encryption, decryption, signing, and
+// verification.
+// Signatures that violate the policy are reported as signature errors in the
+// verification result, while encryption, decryption, and signing fail with an
+// error.
+// The violations can be inspected with errors.As and *policy.Violation.
+func (p *PGPHandle) WithPolicy(policy *policy.Policy) *PGPHandle {
+	handle := *p
+	handle.policy = policy
+	return &handle
+}
+
 // Encryption returns a builder to create an EncryptionHandle
 // for encrypting messages.
 func (p *PGPHandle) Encryption() *EncryptionHandleBuilder {
-	return newEncryptionHandleBuilder(p.profile, p.defaultTime)
+	return newEncryptionHandleBuilder(p.profile, p.policy, p.defaultTime)
 }
 
 // Decryption returns a builder to create a DecryptionHandle
 // for decrypting pgp messages.
 func (p *PGPHandle) Decryption() *DecryptionHandleBuilder {
-	return newDecryptionHandleBuilder(p.profile, p.defaultTime)
+	return newDecryptionHandleBuilder(p.profile, p.policy, p.defaultTime)
 }
 
 // Sign returns a builder to create a SignHandle
 // for signing messages.
 func (p *PGPHandle) Sign() *SignHandleBuilder {
-	return newSignHandleBuilder(p.profile, p.defaultTime)
+	return newSignHandleBuilder(p.profile, p.policy, p.defaultTime)
 }
 
 // Verify returns a builder to create an VerifyHandle
 // for verifying signatures.
 func (p *PGPHandle) Verify() *VerifyHandleBuilder {
-	return newVerifyHandleBuilder(p.profile, p.defaultTime)
+	return newVerifyHandleBuilder(p.profile, p.policy, p.defaultTime)
 }
 
 // KeyGeneration returns a builder to create a KeyGeneration handle.
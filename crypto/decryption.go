@@ -28,6 +28,10 @@ type PGPDecryption interface {
 	// DecryptSessionKey decrypts an encrypted session key.
 	// To decrypt a session key, the decryption handle must contain either a decryption key or a password.
 	DecryptSessionKey(keyPackets []byte) (*SessionKey, error)
+	// ResumableDecryptingReader returns a reader that decrypts a binary message
+	// with SEIPDv2 data, whose state can be saved in checkpoints to resume
+	// reading the message with ResumeDecryptingReader.
+	ResumableDecryptingReader(input Reader) (*ResumableDecryptionReader, error)
 	// ClearPrivateParams clears all private key material contained in EncryptionHandle from memory.
 	ClearPrivateParams()
 }
@@ -36,6 +36,9 @@ func NewPGPSplitReader(pgpMessage Reader, pgpEncryptedSignature Reader) *pgpSpli
 
 // decryptStream decrypts the stream either with the secret keys or a password.
 func (dh *decryptionHandle) decryptStream(encryptedMessage Reader) (plainMessage *VerifyDataReader, err error) {
+	// Counts the padding packets outside of the encrypted data packet, which
+	// go-crypto skips without buffering them
+	padding := newPaddingReader(encryptedMessage)
 	var entries openpgp.EntityList
 
 	config := dh.decryptionConfig(dh.clock().Unix())
@@ -54,14 +57,14 @@ func (dh *decryptionHandle) decryptStream(encryptedMessage Reader) (plainMessage
 	var messageDetails *openpgp.MessageDetails
 	if dh.DecryptionKeyRing != nil {
 		// Private key based decryption
-		messageDetails, err = openpgp.ReadMessage(encryptedMessage, entries, nil, config)
+		messageDetails, err = openpgp.ReadMessage(padding, entries, nil, config)
 		if err != nil {
 			return nil, fmt.Errorf("gopenpgp: decrypting message with private keys failed: %w", err)
 		}
 	} else {
 		// Password based decryption
 		var foundPassword = false
-		resetReader := internal.NewResetReader(encryptedMessage)
+		resetReader := internal.NewResetReader(padding)
 		for _, password := range dh.Passwords {
 			prompt := createPasswordPrompt(password)
 			messageDetails, err = openpgp.ReadMessage(resetReader, entries, prompt, config)
@@ -80,6 +83,9 @@ func (dh *decryptionHandle) decryptStream(encryptedMessage Reader) (plainMessage
 			return nil, errors.New("gopenpgp: error in reading password protected message: wrong password or malformed message")
 		}
 	}
+	if err := dh.checkPolicy(messageDetails); err != nil {
+		return nil, err
+	}
 
 	// Add utf8 sanitizer if signature has type packet.SigTypeText
 	internalReader := messageDetails.UnverifiedBody
@@ -99,11 +105,13 @@ func (dh *decryptionHandle) decryptStream(encryptedMessage Reader) (plainMessage
 		dh.DisableVerifyTimeCheck,
 		false,
 		dh.VerificationContext,
+		padding,
+		dh.policy,
 	}, nil
 }
 
 func (dh *decryptionHandle) decryptStreamWithSession(dataPacketReader Reader) (plainMessage *VerifyDataReader, err error) {
-	messageDetails, verifyTime, err := dh.decryptStreamWithSessionAndParse(dataPacketReader)
+	messageDetails, verifyTime, padding, err := dh.decryptStreamWithSessionAndParse(dataPacketReader)
 	if err != nil {
 		return nil, fmt.Errorf("gopenpgp: error in reading message: %w", err)
 	}
@@ -126,24 +134,27 @@ func (dh *decryptionHandle) decryptStreamWithSession(dataPacketReader Reader) (p
 		dh.DisableVerifyTimeCheck,
 		false,
 		dh.VerificationContext,
+		padding,
+		dh.policy,
 	}, err
 }
 
-func (dh *decryptionHandle) decryptStreamWithSessionAndParse(messageReader io.Reader) (*openpgp.MessageDetails, int64, error) {
+func (dh *decryptionHandle) decryptStreamWithSessionAndParse(messageReader io.Reader) (*openpgp.MessageDetails, int64, *paddingReader, error) {
 	var keyring openpgp.EntityList
 	var decrypted io.ReadCloser
 	var selectedSessionKey *SessionKey
+	var cipher packet.CipherFunction
 	var err error
 	// Read symmetrically encrypted data packet
 	for _, sessionKeyCandidate := range dh.SessionKeys {
-		decrypted, err = decryptStreamWithSessionKey(sessionKeyCandidate, messageReader)
+		decrypted, cipher, err = decryptStreamWithSessionKey(sessionKeyCandidate, messageReader)
 		if err == nil { // No error occurred
 			selectedSessionKey = sessionKeyCandidate
 			break
 		}
 	}
 	if selectedSessionKey == nil {
-		return nil, 0, fmt.Errorf("gopenpgp: unable to decrypt message with session key: %w", err)
+		return nil, 0, nil, fmt.Errorf("gopenpgp: unable to decrypt message with session key: %w", err)
 	}
 
 	config := dh.decryptionConfig(dh.clock().Unix())
@@ -161,74 +172,96 @@ func (dh *decryptionHandle) decryptStreamWithSessionAndParse(messageReader io.Re
 	if dh.DecryptionKeyRing != nil {
 		keyring = append(keyring, dh.DecryptionKeyRing.entities...)
 	}
-	md, err := openpgp.ReadMessage(decrypted, keyring, nil, config)
+	padding := newPaddingReader(decrypted)
+	md, err := openpgp.ReadMessage(padding, keyring, nil, config)
 	if err != nil {
-		return nil, 0, fmt.Errorf("gopenpgp: unable to decode symmetric packet: %w", err)
+		return nil, 0, nil, fmt.Errorf("gopenpgp: unable to decode symmetric packet: %w", err)
+	}
+	if origin := dh.sessionKeyOrigin; origin != nil {
+		// Signatures are verified once the body is read.
+		md.IsEncrypted = true
+		md.IsSymmetricallyEncrypted = origin.password
+		md.DecryptedWith = origin.decryptedWith
+	}
+	md.DecryptedWithAlgorithm = cipher
+	if err := dh.checkPolicy(md); err != nil {
+		return nil, 0, nil, err
+	}
+	if dh.sessionKeyOrigin == nil || dh.RetrieveSessionKey {
+		// Session keys decrypted from the key packets are only returned on request
+		md.SessionKey = selectedSessionKey.Key
 	}
-	md.SessionKey = selectedSessionKey.Key
 	md.UnverifiedBody = checkReader{decrypted, md.UnverifiedBody}
-	return md, config.Time().Unix(), nil
+	return md, config.Time().Unix(), padding, nil
 }
 
-func decryptStreamWithSessionKey(sessionKey *SessionKey, messageReader io.Reader) (io.ReadCloser, error) {
+func decryptStreamWithSessionKey(sessionKey *SessionKey, messageReader io.Reader) (io.ReadCloser, packet.CipherFunction, error) {
 	var decrypted io.ReadCloser
+	var cipher packet.CipherFunction
 	// Read symmetrically encrypted data packet
 Loop:
 	for {
 		packets := packet.NewReader(messageReader)
 		p, err := packets.Next()
 		if err != nil {
-			return nil, fmt.Errorf("gopenpgp: unable to read symmetric packet: %w", err)
+			return nil, 0, fmt.Errorf("gopenpgp: unable to read symmetric packet: %w", err)
 		}
 
 		// Decrypt data packet
 		switch p := p.(type) {
-		case *packet.EncryptedKey, *packet.SymmetricKeyEncrypted:
-			// Ignore potential key packets
+		case *packet.EncryptedKey, *packet.SymmetricKeyEncrypted, packet.Padding:
+			// Ignore potential key packets and padding
 			continue
 		case *packet.SymmetricallyEncrypted, *packet.AEADEncrypted:
 			if symPacket, ok := p.(*packet.SymmetricallyEncrypted); ok {
 				if !symPacket.IntegrityProtected {
-					return nil, errors.New("gopenpgp: message is not authenticated")
+					return nil, 0, errors.New("gopenpgp: message is not authenticated")
+				}
+				if symPacket.Version == 2 {
+					cipher = symPacket.Cipher
 				}
 			}
 			var dc packet.CipherFunction
 			if sessionKey.hasAlgorithm() {
 				dc, err = sessionKey.GetCipherFunc()
 				if err != nil {
-					return nil, fmt.Errorf("gopenpgp: unable to decrypt with session key: %w", err)
+					return nil, 0, fmt.Errorf("gopenpgp: unable to decrypt with session key: %w", err)
+				}
+				if cipher == 0 {
+					cipher = dc
 				}
 			}
 			encryptedDataPacket, isDataPacket := p.(packet.EncryptedDataPacket)
 			if !isDataPacket {
-				return nil, fmt.Errorf("gopenpgp: unknown data packet: %w", err)
+				return nil, 0, fmt.Errorf("gopenpgp: unknown data packet: %w", err)
 			}
 			decrypted, err = encryptedDataPacket.Decrypt(dc, sessionKey.Key)
 			if err != nil {
-				return nil, fmt.Errorf("gopenpgp: unable to decrypt symmetric packet: %w", err)
+				return nil, 0, fmt.Errorf("gopenpgp: unable to decrypt symmetric packet: %w", err)
 			}
 			break Loop
 		default:
-			return nil, errors.New("gopenpgp: invalid packet type")
+			return nil, 0, errors.New("gopenpgp: invalid packet type")
 		}
 	}
-	return decrypted, nil
+	return decrypted, cipher, nil
 }
 
 func (dh *decryptionHandle) decryptStreamAndVerifyDetached(encryptedData, encryptedSignature Reader, isPlaintextSignature bool) (plainMessage *VerifyDataReader, err error) {
 	verifyTime := dh.clock().Unix()
 	var mdData *openpgp.MessageDetails
+	var padding *paddingReader
 	signature := encryptedSignature
 	// Decrypt both messages
 	if len(dh.SessionKeys) > 0 {
 		// Decrypt with session key.
-		mdData, _, err = dh.decryptStreamWithSessionAndParse(encryptedData)
+		mdData, _, padding, err = dh.decryptStreamWithSessionAndParse(encryptedData)
 		if err != nil {
 			return nil, fmt.Errorf("gopenpgp: error in reading data message: %w", err)
 		}
 		if !isPlaintextSignature {
 			// Decrypting reader for the encrypted signature
-			mdSig, _, err := dh.decryptStreamWithSessionAndParse(encryptedSignature)
+			mdSig, _, _, err := dh.decryptStreamWithSessionAndParse(encryptedSignature)
 			if err != nil {
 				return nil, fmt.Errorf("gopenpgp: error in reading detached signature message: %w", err)
 			}
@@ -267,6 +300,9 @@ func (dh *decryptionHandle) decryptStreamAndVerifyDetached(encryptedData, encryp
 				return nil, fmt.Errorf("gopenpgp: error in reading data message: %w", err)
 			}
 		}
+		if err := dh.checkPolicy(mdData); err != nil {
+			return nil, err
+		}
 
 		if !isPlaintextSignature {
 			// Decrypting reader for the encrypted signature
@@ -293,6 +329,7 @@ func (dh *decryptionHandle) decryptStreamAndVerifyDetached(encryptedData, encryp
 		dh.DisableAutomaticTextSanitize,
 		config,
 		NewConstantClock(verifyTime),
+		dh.policy,
 	)
 	if err != nil {
 		return nil, err
@@ -300,6 +337,7 @@ func (dh *decryptionHandle) decryptStreamAndVerifyDetached(encryptedData, encryp
 	// Update message details with information from the data of the pgp message
 	sigVerifyReader.details.LiteralData = mdData.LiteralData
 	sigVerifyReader.details.SessionKey = mdData.SessionKey
+	sigVerifyReader.padding = padding
 	return sigVerifyReader, nil
 }
 
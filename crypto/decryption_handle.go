@@ -6,8 +6,10 @@ import (
 	"fmt"
 
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
 
 	"github.com/lovoo/gopenpgp/v3/internal"
+	"github.com/lovoo/gopenpgp/v3/policy"
 )
 
 // decryptionHandle collects the configuration parameters to decrypt a pgp message.
@@ -46,14 +48,29 @@ type decryptionHandle struct {
 	IsUTF8                                      bool
 	clock                                       Clock
 	profile                                     EncryptionProfile
+	// policy is the cryptographic policy enforced for the decryption and
+	// the verification of signatures, if any.
+	policy *policy.Policy
+	// sessionKeyOrigin records how the session keys were decrypted from the
+	// key packets of the message, if known.
+	sessionKeyOrigin *sessionKeyOrigin
+}
+
+// sessionKeyOrigin describes the decryption of a session key from the key
+// packets, such that the intended recipients of signatures are checked as
+// for a decryption of the key packets.
+type sessionKeyOrigin struct {
+	decryptedWith openpgp.Key
+	password      bool
 }
 
 // --- Default decryption handle to build from
 
-func defaultDecryptionHandle(profile EncryptionProfile, clock Clock) *decryptionHandle {
+func defaultDecryptionHandle(profile EncryptionProfile, policy *policy.Policy, clock Clock) *decryptionHandle {
 	return &decryptionHandle{
 		clock:   clock,
 		profile: profile,
+		policy:  policy,
 	}
 }
 
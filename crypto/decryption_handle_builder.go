@@ -1,5 +1,7 @@
 package crypto
 
+import "github.com/lovoo/gopenpgp/v3/policy"
+
 // DecryptionHandleBuilder allows to configure a decryption handle
 // to decrypt a pgp message.
 type DecryptionHandleBuilder struct {
@@ -7,13 +9,15 @@ type DecryptionHandleBuilder struct {
 	defaultClock Clock
 	err          error
 	profile      EncryptionProfile
+	policy       *policy.Policy
 }
 
-func newDecryptionHandleBuilder(profile EncryptionProfile, clock Clock) *DecryptionHandleBuilder {
+func newDecryptionHandleBuilder(profile EncryptionProfile, policy *policy.Policy, clock Clock) *DecryptionHandleBuilder {
 	return &DecryptionHandleBuilder{
-		handle:       defaultDecryptionHandle(profile, clock),
+		handle:       defaultDecryptionHandle(profile, policy, clock),
 		defaultClock: clock,
 		profile:      profile,
+		policy:       policy,
 	}
 }
 
@@ -199,7 +203,7 @@ func (dpb *DecryptionHandleBuilder) New() (PGPDecryption, error) {
 		return nil, dpb.err
 	}
 	handle := dpb.handle
-	dpb.handle = defaultDecryptionHandle(dpb.profile, dpb.defaultClock)
+	dpb.handle = defaultDecryptionHandle(dpb.profile, dpb.policy, dpb.defaultClock)
 	return handle, nil
 }
 
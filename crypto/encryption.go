@@ -26,6 +26,16 @@ type PGPEncryption interface {
 	// GenerateSessionKey generates a random session key for the given encryption handle
 	// considering the algorithm preferences of the recipient keys.
 	GenerateSessionKey() (*SessionKey, error)
+	// NegotiateAlgorithms reports the cipher, the AEAD mode and the SEIPD
+	// version that encrypting with the handle would use, and which
+	// recipients constrained them, without encrypting anything.
+	NegotiateAlgorithms() (*AlgorithmNegotiation, error)
+	// ResumableEncryptingWriter returns a writer that encrypts into a binary
+	// message with SEIPDv2 data, whose state can be saved in checkpoints to
+	// resume writing the message with ResumeEncryptingWriter.
+	// The sourceID identifies the plaintext, which must not change when
+	// resuming.
+	ResumableEncryptingWriter(output Writer, sourceID []byte) (*ResumableEncryptionWriter, error)
 	// ClearPrivateParams clears all private key material contained in EncryptionHandle from memory.
 	ClearPrivateParams()
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
@@ -225,6 +226,16 @@ func (eh *encryptionHandle) encryptStreamWithSessionKeyHelper(
 		}
 	}
 
+	recipients := slices.Concat(eh.Recipients.getEntities(), eh.HiddenRecipients.getEntities())
+	date := config.Now()
+	if eh.encryptionTimeOverride != nil {
+		date = eh.encryptionTimeOverride()
+	}
+	if len(recipients) > 0 {
+		// Compress as when encrypting to the recipients with go-crypto
+		config.DefaultCompressionAlgo = negotiateCompression(recipients, date, config)
+	}
+
 	encryptWriter, err = packet.SerializeSymmetricallyEncrypted(
 		dataPacketWriter,
 		config.Cipher(),
@@ -237,6 +248,9 @@ func (eh *encryptionHandle) encryptStreamWithSessionKeyHelper(
 	if err != nil {
 		return nil, nil, fmt.Errorf("gopenpgp: unable to encrypt: %w", err)
 	}
+	if eh.Padding != nil {
+		encryptWriter = newPaddingWriteCloser(encryptWriter, eh.Padding, config.Random())
+	}
 
 	if algo := config.Compression(); algo != packet.CompressionNone {
 		encryptWriter, err = packet.SerializeCompressed(encryptWriter, algo, config.CompressionConfig)
@@ -245,7 +259,34 @@ func (eh *encryptionHandle) encryptStreamWithSessionKeyHelper(
 		}
 	}
 
-	if signers != nil {
+	switch {
+	case signers != nil && len(recipients) > 0:
+		// The signatures list the recipients as intended recipients,
+		// which SignWithParams does not support
+		var intendedRecipients []*packet.Recipient
+		if config.IntendedRecipients() {
+			for _, entity := range eh.Recipients.getEntities() {
+				intendedRecipients = append(intendedRecipients, &packet.Recipient{
+					KeyVersion:  entity.PrimaryKey.Version,
+					Fingerprint: entity.PrimaryKey.Fingerprint,
+				})
+			}
+		}
+		signWriter, err = signWithIntendedRecipients(
+			encryptWriter,
+			signers,
+			recipients,
+			intendedRecipients,
+			hints,
+			eh.IsUTF8,
+			eh.ExternalSignature,
+			date,
+			config,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+	case signers != nil:
 		signWriter, err = openpgp.SignWithParams(encryptWriter, signers, &openpgp.SignParams{
 			Hints:      hints,
 			TextSig:    eh.IsUTF8,
@@ -255,7 +296,7 @@ func (eh *encryptionHandle) encryptStreamWithSessionKeyHelper(
 		if err != nil {
 			return nil, nil, fmt.Errorf("gopenpgp: unable to sign: %w", err)
 		}
-	} else {
+	default:
 		encryptWriter, err = packet.SerializeLiteral(
 			encryptWriter,
 			!plainMessageMetadata.IsUtf8(),
@@ -346,6 +387,42 @@ func (eh *encryptionHandle) encryptSignDetachedStreamToRecipients(
 	encryptedDataWriter io.Writer,
 	keyPacketWriter io.Writer,
 	encryptSignature bool,
+) (plaintextWriter io.WriteCloser, err error) {
+	if keyPacketWriter == nil {
+		// If no separate keyPacketWriter is given, write the key packets
+		// as prefix to the encrypted data and encrypted signature.
+		keyPacketWriter = io.MultiWriter(encryptedDataWriter, encryptedSignatureWriter)
+	}
+	return eh.encryptSessionKeyAndStream(keyPacketWriter, func() (io.WriteCloser, error) {
+		// Use the session key to encrypt message + signature of the message.
+		return eh.encryptSignDetachedStreamWithSessionKey(
+			plainMessageMetadata,
+			encryptedSignatureWriter,
+			encryptedDataWriter,
+			encryptSignature,
+		)
+	})
+}
+
+// encryptStreamWithGeneratedSessionKey encrypts the message with the session
+// key of the handle or a generated session key, which is encrypted to the
+// recipients and the password.
+func (eh *encryptionHandle) encryptStreamWithGeneratedSessionKey(
+	keyPacketWriter Writer,
+	dataPacketWriter Writer,
+	plainMessageMetadata *LiteralMetadata,
+) (plaintextWriter WriteCloser, err error) {
+	return eh.encryptSessionKeyAndStream(keyPacketWriter, func() (io.WriteCloser, error) {
+		return eh.encryptStreamWithSessionKey(dataPacketWriter, plainMessageMetadata)
+	})
+}
+
+// encryptSessionKeyAndStream writes the key packets of the session key of the
+// handle, or of a generated session key, and returns the writer of encrypt,
+// which encrypts with the session key.
+func (eh *encryptionHandle) encryptSessionKeyAndStream(
+	keyPacketWriter io.Writer,
+	encrypt func() (io.WriteCloser, error),
 ) (plaintextWriter io.WriteCloser, err error) {
 	configInput := eh.profile.EncryptionConfig()
 	configInput.Time = NewConstantClock(eh.clock().Unix())
@@ -360,11 +437,6 @@ func (eh *encryptionHandle) encryptSignDetachedStreamToRecipients(
 			eh.SessionKey = nil
 		}()
 	}
-	if keyPacketWriter == nil {
-		// If no separate keyPacketWriter is given, write the key packets
-		// as prefix to the encrypted data and encrypted signature.
-		keyPacketWriter = io.MultiWriter(encryptedDataWriter, encryptedSignatureWriter)
-	}
 
 	encryptionTimeOverride := configInput.Now()
 	if eh.encryptionTimeOverride != nil {
@@ -398,17 +470,7 @@ func (eh *encryptionHandle) encryptSignDetachedStreamToRecipients(
 		return nil, errors.New("openpgp: no key material to encrypt")
 	}
 
-	// Use the session key to encrypt message + signature of the message.
-	plaintextWriter, err = eh.encryptSignDetachedStreamWithSessionKey(
-		plainMessageMetadata,
-		encryptedSignatureWriter,
-		encryptedDataWriter,
-		encryptSignature,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return plaintextWriter, err
+	return encrypt()
 }
 
 func (eh *encryptionHandle) selectCompression() (config *packet.Config) {
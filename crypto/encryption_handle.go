@@ -9,6 +9,7 @@ import (
 	"github.com/ProtonMail/gopenpgp/v3/constants"
 
 	"github.com/lovoo/gopenpgp/v3/internal"
+	"github.com/lovoo/gopenpgp/v3/policy"
 )
 
 // encryptionHandle collects the configuration parameters for encrypting a message.
@@ -55,11 +56,17 @@ type encryptionHandle struct {
 	// PlainDetachedSignature indicates that the detached signature should not be encrypted.
 	// Is only considered if DetachedSignature is not set.
 	PlainDetachedSignature bool
-	IsUTF8                 bool
+	// Padding determines the length to which the message is padded with
+	// padding packets inside the encrypted data packet.
+	// If nil, no padding is added.
+	Padding PaddingStrategy
+	IsUTF8  bool
 	// ExternalSignature allows to include an external signature into
 	// the encrypted message.
 	ExternalSignature []byte
 	profile           EncryptionProfile
+	// policy is the cryptographic policy enforced for the encryption, if any.
+	policy *policy.Policy
 
 	encryptionTimeOverride Clock
 	clock                  Clock
@@ -67,9 +74,10 @@ type encryptionHandle struct {
 
 // --- Default decryption handle to build from
 
-func defaultEncryptionHandle(profile EncryptionProfile, clock Clock) *encryptionHandle {
+func defaultEncryptionHandle(profile EncryptionProfile, policy *policy.Policy, clock Clock) *encryptionHandle {
 	return &encryptionHandle{
 		profile: profile,
+		policy:  policy,
 		clock:   clock,
 	}
 }
@@ -117,6 +125,9 @@ func (eh *encryptionHandle) Encrypt(message []byte) (*PGPMessage, error) {
 // EncryptSessionKey encrypts a session key with the encryption handle.
 // To encrypt a session key, the handle must contain either recipients or a password.
 func (eh *encryptionHandle) EncryptSessionKey(sessionKey *SessionKey) ([]byte, error) {
+	if err := eh.checkSessionKeyPolicy(sessionKey); err != nil {
+		return nil, err
+	}
 	config := eh.profile.EncryptionConfig()
 	config.Time = NewConstantClock(eh.clock().Unix())
 	switch {
@@ -276,6 +287,9 @@ func (eh *encryptionHandle) encryptingWriters(keys, data, detachedSignature Writ
 	if err = eh.validate(); err != nil {
 		return nil, err
 	}
+	if err = eh.checkPolicy(); err != nil {
+		return nil, err
+	}
 
 	doDetachedSignature := eh.DetachedSignature || eh.PlainDetachedSignature
 	if doDetachedSignature && detachedSignature == nil {
@@ -300,19 +314,26 @@ func (eh *encryptionHandle) encryptingWriters(keys, data, detachedSignature Writ
 	switch {
 	case eh.Recipients.CountEntities() > 0 || eh.HiddenRecipients.CountEntities() > 0:
 		// Encrypt towards recipients
-		if !doDetachedSignature {
-			// Signature is inside the ciphertext.
-			messageWriter, err = eh.encryptStream(keys, data, meta)
-		} else {
+		switch {
+		case doDetachedSignature:
 			// Encrypted detached signature separate from the ciphertext.
 			messageWriter, err = eh.encryptSignDetachedStreamToRecipients(meta, detachedSignature, data, keys, eh.DetachedSignature)
+		case eh.Padding != nil:
+			// The padding is written into the data packet encrypted with the session key.
+			messageWriter, err = eh.encryptStreamWithGeneratedSessionKey(keys, data, meta)
+		default:
+			// Signature is inside the ciphertext.
+			messageWriter, err = eh.encryptStream(keys, data, meta)
 		}
 	case eh.Password != nil:
 		// Encrypt with a password
-		if !doDetachedSignature {
-			messageWriter, err = eh.encryptStreamWithPassword(keys, data, meta)
-		} else {
+		switch {
+		case doDetachedSignature:
 			messageWriter, err = eh.encryptSignDetachedStreamToRecipients(meta, detachedSignature, data, keys, eh.DetachedSignature)
+		case eh.Padding != nil:
+			messageWriter, err = eh.encryptStreamWithGeneratedSessionKey(keys, data, meta)
+		default:
+			messageWriter, err = eh.encryptStreamWithPassword(keys, data, meta)
 		}
 	case eh.SessionKey != nil:
 		// Encrypt towards session key
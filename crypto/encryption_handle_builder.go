@@ -1,6 +1,10 @@
 package crypto
 
-import "github.com/ProtonMail/gopenpgp/v3/constants"
+import (
+	"github.com/ProtonMail/gopenpgp/v3/constants"
+
+	"github.com/lovoo/gopenpgp/v3/policy"
+)
 
 // EncryptionHandleBuilder allows to configure a decryption handle to decrypt an OpenPGP message.
 type EncryptionHandleBuilder struct {
@@ -9,9 +13,9 @@ type EncryptionHandleBuilder struct {
 	err          error
 }
 
-func newEncryptionHandleBuilder(profile EncryptionProfile, clock Clock) *EncryptionHandleBuilder {
+func newEncryptionHandleBuilder(profile EncryptionProfile, policy *policy.Policy, clock Clock) *EncryptionHandleBuilder {
 	return &EncryptionHandleBuilder{
-		handle:       defaultEncryptionHandle(profile, clock),
+		handle:       defaultEncryptionHandle(profile, policy, clock),
 		defaultClock: clock,
 	}
 }
@@ -166,6 +170,16 @@ func (ehb *EncryptionHandleBuilder) PlainDetachedSignature() *EncryptionHandleBu
 	return ehb
 }
 
+// Padding sets the padding strategy to hide the length of the message.
+// Padding packets are added inside the encrypted data packet, after the
+// optionally compressed and signed message, until the length of the packets
+// reaches the length determined by the strategy, e.g. NewPadmePadding.
+// Padding applies to SEIPDv1 and SEIPDv2 data packets.
+func (ehb *EncryptionHandleBuilder) Padding(strategy PaddingStrategy) *EncryptionHandleBuilder {
+	ehb.handle.Padding = strategy
+	return ehb
+}
+
 // IncludeExternalSignature indicates that the provided signature should be included
 // in the produced encrypted message.
 // Special feature: should not be used in normal use-cases,
@@ -206,7 +220,7 @@ func (ehb *EncryptionHandleBuilder) New() (PGPEncryption, error) {
 		return nil, ehb.err
 	}
 	params := ehb.handle
-	ehb.handle = defaultEncryptionHandle(ehb.handle.profile, ehb.defaultClock)
+	ehb.handle = defaultEncryptionHandle(ehb.handle.profile, ehb.handle.policy, ehb.defaultClock)
 	return params, nil
 }
 
@@ -0,0 +1,206 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+)
+
+// AlgorithmNegotiation reports the algorithms an encryption handle would
+// use to encrypt a message, and which recipients constrained them.
+type AlgorithmNegotiation struct {
+	// SEIPDVersion is 2 if the data is encrypted with AEAD, or 1.
+	SEIPDVersion int `json:"seipdVersion"`
+	// Cipher is the cipher of SEIPDv1 data and of v3 public-key encrypted
+	// session keys, e.g. constants.AES256.
+	Cipher string `json:"cipher"`
+	// AEADCipher and AEADMode are the cipher suite of SEIPDv2 data.
+	// AEADMode is one of packet.AEADMode.
+	AEADCipher string `json:"aeadCipher,omitempty"`
+	AEADMode   int    `json:"aeadMode,omitempty"`
+	// Recipients lists the recipients and hidden recipients with the
+	// algorithms their preferences excluded.
+	Recipients []*RecipientNegotiation `json:"recipients"`
+}
+
+// RecipientNegotiation reports how the preferences of a recipient
+// constrained the algorithm negotiation.
+type RecipientNegotiation struct {
+	Fingerprint string `json:"fingerprint"`
+	Hidden      bool   `json:"hidden"`
+	// SEIPDv2 is false if the recipient does not support SEIPDv2, which
+	// forces SEIPDv1 for all recipients.
+	SEIPDv2 bool `json:"seipdv2"`
+	// ExcludedCiphers are the candidate ciphers that the recipient does not
+	// prefer, e.g. constants.AES256.
+	ExcludedCiphers []string `json:"excludedCiphers"`
+	// ExcludedCipherSuites are the candidate AEAD cipher suites that the
+	// recipient does not prefer, as pairs of cipher and AEAD mode.
+	ExcludedCipherSuites [][2]int `json:"excludedCipherSuites"`
+}
+
+// Candidate algorithms of the negotiation, in order of preference, as used
+// by go-crypto when encrypting to recipients.
+// TestNegotiateAlgorithmsCiphertext checks the negotiation against the
+// ciphertext of every profile, so that it fails if go-crypto changes them.
+var (
+	candidateCiphers = []uint8{
+		uint8(packet.CipherAES256),
+		uint8(packet.CipherAES128),
+	}
+	candidateCipherSuites = [][2]uint8{
+		{uint8(packet.CipherAES256), uint8(packet.AEADModeGCM)},
+		{uint8(packet.CipherAES256), uint8(packet.AEADModeEAX)},
+		{uint8(packet.CipherAES256), uint8(packet.AEADModeOCB)},
+		{uint8(packet.CipherAES128), uint8(packet.AEADModeGCM)},
+		{uint8(packet.CipherAES128), uint8(packet.AEADModeEAX)},
+		{uint8(packet.CipherAES128), uint8(packet.AEADModeOCB)},
+	}
+)
+
+// NegotiateAlgorithms reports the algorithms that encrypting with the handle
+// would use, without encrypting anything.
+func (eh *encryptionHandle) NegotiateAlgorithms() (*AlgorithmNegotiation, error) {
+	if err := eh.validate(); err != nil {
+		return nil, err
+	}
+	config := eh.profile.EncryptionConfig()
+	config.Time = NewConstantClock(eh.clock().Unix())
+	date := config.Now()
+	if eh.encryptionTimeOverride != nil {
+		date = eh.encryptionTimeOverride()
+	}
+
+	negotiation := &AlgorithmNegotiation{}
+	recipients := slices.Concat(eh.Recipients.getEntities(), eh.HiddenRecipients.getEntities())
+	ciphers := slices.Clone(candidateCiphers)
+	cipherSuites := slices.Clone(candidateCipherSuites)
+	aeadSupport := config.AEAD() != nil
+	for i, entity := range recipients {
+		recipient, sig, err := negotiateRecipient(entity, date, config)
+		if err != nil {
+			return nil, err
+		}
+		recipient.Hidden = i >= eh.Recipients.CountEntities()
+		negotiation.Recipients = append(negotiation.Recipients, recipient)
+
+		aeadSupport = aeadSupport && sig.SEIPDv2
+		ciphers = intersectPreferences(ciphers, sig.PreferredSymmetric)
+		cipherSuites = intersectCipherSuites(cipherSuites, sig.PreferredCipherSuites)
+	}
+	if len(ciphers) == 0 {
+		ciphers = []uint8{uint8(packet.CipherAES128)}
+	}
+	cipher := packet.CipherFunction(ciphers[0])
+	if slices.Contains(ciphers, uint8(config.Cipher())) {
+		cipher = config.Cipher()
+	}
+
+	// Detached signatures and padding encrypt with a session key
+	sessionKeyEncryption := eh.DetachedSignature || eh.PlainDetachedSignature || eh.Padding != nil
+	switch {
+	case len(recipients) > 0 && !sessionKeyEncryption:
+		// The cipher suite is negotiated as well
+		if len(cipherSuites) == 0 {
+			cipherSuites = [][2]uint8{{uint8(packet.CipherAES128), uint8(packet.AEADModeOCB)}}
+			if allPQ(recipients, date, config) {
+				cipherSuites = [][2]uint8{{uint8(packet.CipherAES256), uint8(packet.AEADModeOCB)}}
+			}
+		}
+		negotiation.setCipherSuite(aeadSupport, cipher, packet.CipherSuite{
+			Cipher: packet.CipherFunction(cipherSuites[0][0]),
+			Mode:   packet.AEADMode(cipherSuites[0][1]),
+		})
+	case eh.SessionKey != nil || sessionKeyEncryption:
+		// The data is encrypted with the given session key, or with a
+		// session key generated with the negotiated cipher
+		if eh.SessionKey != nil {
+			aeadSupport = eh.SessionKey.v6
+			cipher = config.Cipher()
+			if eh.SessionKey.hasAlgorithm() {
+				var err error
+				if cipher, err = eh.SessionKey.GetCipherFunc(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		negotiation.setCipherSuite(aeadSupport, cipher, packet.CipherSuite{Cipher: cipher, Mode: config.AEAD().Mode()})
+	default:
+		// Password-based encryption uses the algorithms of the profile
+		negotiation.setCipherSuite(config.AEAD() != nil, config.Cipher(), packet.CipherSuite{
+			Cipher: config.Cipher(),
+			Mode:   config.AEAD().Mode(),
+		})
+	}
+	return negotiation, nil
+}
+
+func (negotiation *AlgorithmNegotiation) setCipherSuite(aeadSupport bool, cipher packet.CipherFunction, cipherSuite packet.CipherSuite) {
+	negotiation.SEIPDVersion = 1
+	negotiation.Cipher = getAlgo(cipher)
+	if aeadSupport {
+		negotiation.SEIPDVersion = 2
+		negotiation.AEADCipher = getAlgo(cipherSuite.Cipher)
+		negotiation.AEADMode = int(cipherSuite.Mode)
+	}
+}
+
+// negotiateRecipient reports which candidate algorithms the preferences of
+// the recipient exclude, and returns the self-signature with the
+// preferences.
+func negotiateRecipient(
+	entity *openpgp.Entity,
+	date time.Time,
+	config *packet.Config,
+) (*RecipientNegotiation, *packet.Signature, error) {
+	if _, err := entity.EncryptionKeyWithError(date, config); err != nil {
+		return nil, nil, fmt.Errorf("gopenpgp: no encryption key for recipient %x: %w", entity.PrimaryKey.Fingerprint, err)
+	}
+	sig, err := entity.PrimarySelfSignature(date, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gopenpgp: recipient %x has no self-signature: %w", entity.PrimaryKey.Fingerprint, err)
+	}
+	recipient := &RecipientNegotiation{
+		Fingerprint:          hex.EncodeToString(entity.PrimaryKey.Fingerprint),
+		SEIPDv2:              sig.SEIPDv2,
+		ExcludedCiphers:      []string{},
+		ExcludedCipherSuites: [][2]int{},
+	}
+	for _, cipher := range candidateCiphers {
+		if !slices.Contains(sig.PreferredSymmetric, cipher) {
+			recipient.ExcludedCiphers = append(recipient.ExcludedCiphers, getAlgo(packet.CipherFunction(cipher)))
+		}
+	}
+	for _, cipherSuite := range candidateCipherSuites {
+		if !slices.Contains(sig.PreferredCipherSuites, cipherSuite) {
+			recipient.ExcludedCipherSuites = append(recipient.ExcludedCipherSuites, [2]int{int(cipherSuite[0]), int(cipherSuite[1])})
+		}
+	}
+	return recipient, sig, nil
+}
+
+// allPQ returns true if the encryption keys of all recipients are
+// post-quantum keys.
+func allPQ(recipients []*openpgp.Entity, date time.Time, config *packet.Config) bool {
+	for _, entity := range recipients {
+		if key, err := entity.EncryptionKeyWithError(date, config); err != nil || !key.PublicKey.IsPQ() {
+			return false
+		}
+	}
+	return true
+}
+
+func intersectCipherSuites(a [][2]uint8, b [][2]uint8) (intersection [][2]uint8) {
+	var currentIndex int
+	for _, valueFirst := range a {
+		if slices.Contains(b, valueFirst) {
+			a[currentIndex] = valueFirst
+			currentIndex++
+		}
+	}
+	return a[:currentIndex]
+}
@@ -0,0 +1,189 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ProtonMail/gopenpgp/v3/constants"
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+func TestNegotiateAlgorithms(t *testing.T) {
+	pgp := PGPWithProfile(profile.RFC9580())
+	v6Key, err := pgp.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	v6Key, err = pgp.SetKeyPreferences(v6Key, &KeyPreferences{
+		Ciphers:      []byte{byte(packet.CipherAES128), byte(packet.CipherAES256)},
+		CipherSuites: []byte{byte(packet.CipherAES128), byte(packet.AEADModeOCB)},
+	})
+	if err != nil {
+		t.Fatal("Expected no error while setting preferences, got:", err)
+	}
+	v4Key, err := PGPWithProfile(profile.RFC4880()).KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+
+	encHandle, err := pgp.Encryption().Recipient(v6Key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	negotiation, err := encHandle.NegotiateAlgorithms()
+	if err != nil {
+		t.Fatal("Expected no error while negotiating algorithms, got:", err)
+	}
+	assert.Exactly(t, 2, negotiation.SEIPDVersion)
+	assert.Exactly(t, constants.AES128, negotiation.AEADCipher)
+	assert.Exactly(t, int(packet.AEADModeOCB), negotiation.AEADMode)
+	if assert.Len(t, negotiation.Recipients, 1) {
+		recipient := negotiation.Recipients[0]
+		assert.Exactly(t, v6Key.GetFingerprint(), recipient.Fingerprint)
+		assert.True(t, recipient.SEIPDv2)
+		assert.Empty(t, recipient.ExcludedCiphers)
+		assert.Len(t, recipient.ExcludedCipherSuites, 5)
+	}
+	assertNegotiatedDataPacket(t, encHandle, negotiation)
+
+	encHandle, err = pgp.Encryption().Recipient(v6Key).HiddenRecipient(v4Key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	negotiation, err = encHandle.NegotiateAlgorithms()
+	if err != nil {
+		t.Fatal("Expected no error while negotiating algorithms, got:", err)
+	}
+	assert.Exactly(t, 1, negotiation.SEIPDVersion)
+	assert.Exactly(t, constants.AES256, negotiation.Cipher)
+	if assert.Len(t, negotiation.Recipients, 2) {
+		assert.True(t, negotiation.Recipients[1].Hidden)
+		assert.False(t, negotiation.Recipients[1].SEIPDv2)
+	}
+	assertNegotiatedDataPacket(t, encHandle, negotiation)
+
+	encHandle, err = pgp.Encryption().Password([]byte("password")).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	negotiation, err = encHandle.NegotiateAlgorithms()
+	if err != nil {
+		t.Fatal("Expected no error while negotiating algorithms, got:", err)
+	}
+	assert.Exactly(t, 2, negotiation.SEIPDVersion)
+	assert.Empty(t, negotiation.Recipients)
+	assertNegotiatedDataPacket(t, encHandle, negotiation)
+}
+
+func TestNegotiateAlgorithmsCiphertext(t *testing.T) {
+	// The negotiation must match the algorithms go-crypto uses to encrypt,
+	// which are read back from the ciphertext
+	unknownSuite := []byte{byte(packet.CipherAES192), byte(packet.AEADModeOCB)}
+	testCases := []struct {
+		name        string
+		profile     *profile.Custom
+		preferences *KeyPreferences
+		// expected algorithms, checked along with the ciphertext
+		seipdVersion int
+		cipher       string
+		aeadCipher   string
+		aeadMode     packet.AEADMode
+	}{
+		{name: "Default", profile: profile.Default(), seipdVersion: 1, cipher: constants.AES256},
+		{name: "RFC4880", profile: profile.RFC4880(), seipdVersion: 1, cipher: constants.AES256},
+		{
+			name: "RFC4880 AES-128", profile: profile.RFC4880(),
+			preferences:  &KeyPreferences{Ciphers: []byte{byte(packet.CipherAES128)}},
+			seipdVersion: 1, cipher: constants.AES128,
+		},
+		{
+			name: "RFC9580", profile: profile.RFC9580(),
+			seipdVersion: 2, cipher: constants.AES256, aeadCipher: constants.AES256, aeadMode: packet.AEADModeOCB,
+		},
+		{
+			name: "RFC9580 AES-256 GCM", profile: profile.RFC9580(),
+			preferences:  &KeyPreferences{CipherSuites: []byte{byte(packet.CipherAES256), byte(packet.AEADModeGCM)}},
+			seipdVersion: 2, cipher: constants.AES256, aeadCipher: constants.AES256, aeadMode: packet.AEADModeGCM,
+		},
+		{
+			name: "RFC9580 AES-256 EAX", profile: profile.RFC9580(),
+			preferences:  &KeyPreferences{CipherSuites: []byte{byte(packet.CipherAES256), byte(packet.AEADModeEAX)}},
+			seipdVersion: 2, cipher: constants.AES256, aeadCipher: constants.AES256, aeadMode: packet.AEADModeEAX,
+		},
+		{
+			name: "RFC9580 fallback", profile: profile.RFC9580(),
+			preferences:  &KeyPreferences{CipherSuites: unknownSuite},
+			seipdVersion: 2, cipher: constants.AES256, aeadCipher: constants.AES128, aeadMode: packet.AEADModeOCB,
+		},
+		{
+			name: "PostQuantum", profile: profile.PostQuantum(),
+			seipdVersion: 2, cipher: constants.AES256, aeadCipher: constants.AES256, aeadMode: packet.AEADModeOCB,
+		},
+		{
+			name: "PostQuantum fallback", profile: profile.PostQuantum(),
+			preferences:  &KeyPreferences{CipherSuites: unknownSuite},
+			seipdVersion: 2, cipher: constants.AES256, aeadCipher: constants.AES256, aeadMode: packet.AEADModeOCB,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pgp := PGPWithProfile(testCase.profile)
+			key, err := pgp.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+			if err != nil {
+				t.Fatal("Expected no error while generating key, got:", err)
+			}
+			if testCase.preferences != nil {
+				if key, err = pgp.SetKeyPreferences(key, testCase.preferences); err != nil {
+					t.Fatal("Expected no error while setting preferences, got:", err)
+				}
+			}
+			encHandle, err := pgp.Encryption().Recipient(key).New()
+			if err != nil {
+				t.Fatal("Expected no error while creating encryption handle, got:", err)
+			}
+			negotiation, err := encHandle.NegotiateAlgorithms()
+			if err != nil {
+				t.Fatal("Expected no error while negotiating algorithms, got:", err)
+			}
+			assert.Exactly(t, testCase.seipdVersion, negotiation.SEIPDVersion)
+			assert.Exactly(t, testCase.cipher, negotiation.Cipher)
+			assert.Exactly(t, testCase.aeadCipher, negotiation.AEADCipher)
+			assert.Exactly(t, int(testCase.aeadMode), negotiation.AEADMode)
+
+			message := assertNegotiatedDataPacket(t, encHandle, negotiation)
+			if negotiation.SEIPDVersion == 1 {
+				// The cipher of SEIPDv1 data is in the encrypted session key
+				decHandle, _ := pgp.Decryption().DecryptionKey(key).New()
+				sessionKey, err := decHandle.DecryptSessionKey(message.KeyPacket)
+				if err != nil {
+					t.Fatal("Expected no error while decrypting session key, got:", err)
+				}
+				assert.Exactly(t, negotiation.Cipher, sessionKey.Algo)
+			}
+		})
+	}
+}
+
+func assertNegotiatedDataPacket(t *testing.T, encHandle PGPEncryption, negotiation *AlgorithmNegotiation) *PGPMessage {
+	message, err := encHandle.Encrypt([]byte("negotiation"))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	p, err := packet.Read(bytes.NewReader(message.DataPacket))
+	if err != nil {
+		t.Fatal("Expected no error while reading data packet, got:", err)
+	}
+	encrypted, ok := p.(*packet.SymmetricallyEncrypted)
+	if !assert.True(t, ok) {
+		return message
+	}
+	assert.Exactly(t, negotiation.SEIPDVersion, encrypted.Version)
+	if encrypted.Version == 2 {
+		assert.Exactly(t, negotiation.AEADCipher, getAlgo(encrypted.Cipher))
+		assert.Exactly(t, negotiation.AEADMode, int(encrypted.Mode))
+	}
+	return message
+}
@@ -9,12 +9,22 @@ import (
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
 )
 
 // decryptSessionKey returns the decrypted session key from one or multiple binary encrypted session key packets.
 func decryptSessionKey(keyRing *KeyRing, keyPacket []byte) (*SessionKey, error) {
+	sk, _, err := decryptSessionKeyWithKey(keyRing, keyPacket, &packet.Config{})
+	return sk, err
+}
+
+// decryptSessionKeyWithKey returns the decrypted session key from one or multiple binary encrypted session key packets,
+// and the key that decrypted it.
+// The config determines which keys are decryption keys.
+func decryptSessionKeyWithKey(keyRing *KeyRing, keyPacket []byte, config *packet.Config) (*SessionKey, *openpgp.Key, error) {
 	var p packet.Packet
 	var ek *packet.EncryptedKey
+	var decryptedWith openpgp.Key
 
 	var err error
 	var hasPacket = false
@@ -35,14 +45,15 @@ Loop:
 			ek = p
 			unverifiedEntities := keyRing.entities.EntitiesById(p.KeyId)
 			for _, unverifiedEntity := range unverifiedEntities {
-				keys := unverifiedEntity.DecryptionKeys(p.KeyId, time.Time{}, &packet.Config{})
+				keys := unverifiedEntity.DecryptionKeys(p.KeyId, time.Time{}, config)
 				for _, key := range keys {
 					priv := key.PrivateKey
-					if priv.Encrypted {
+					if priv == nil || priv.Encrypted {
 						continue
 					}
 
-					if decryptErr = ek.Decrypt(priv, nil); decryptErr == nil {
+					if decryptErr = ek.Decrypt(priv, config); decryptErr == nil {
+						decryptedWith = key
 						break Loop
 					}
 				}
@@ -60,21 +71,25 @@ Loop:
 
 	if !hasPacket {
 		if err != nil {
-			return nil, fmt.Errorf("gopenpgp: couldn't find a session key packet: %w", err)
+			return nil, nil, fmt.Errorf("gopenpgp: couldn't find a session key packet: %w", err)
 		} else {
-			return nil, errors.New("gopenpgp: couldn't find a session key packet")
+			return nil, nil, errors.New("gopenpgp: couldn't find a session key packet")
 		}
 	}
 
 	if decryptErr != nil {
-		return nil, fmt.Errorf("gopenpgp: error in decrypting: %w", decryptErr)
+		return nil, nil, fmt.Errorf("gopenpgp: error in decrypting: %w", decryptErr)
 	}
 
 	if ek == nil || ek.Key == nil {
-		return nil, errors.New("gopenpgp: unable to decrypt session key: no valid decryption key")
+		return nil, nil, errors.New("gopenpgp: unable to decrypt session key: no valid decryption key")
 	}
 
-	return newSessionKeyFromEncrypted(ek)
+	sk, err := newSessionKeyFromEncrypted(ek)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sk, &decryptedWith, nil
 }
 
 // encryptSessionKey encrypts the session key with the unarmored
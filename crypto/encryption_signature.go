@@ -0,0 +1,334 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+
+	"github.com/lovoo/gopenpgp/v3/internal"
+)
+
+// The signing in this file replaces go-crypto's when gopenpgp writes the
+// encrypted data packet itself, e.g. for padded messages, since
+// openpgp.EncryptWithParams cannot write a padding packet into the encrypted
+// data packet. It must negotiate the same algorithms as go-crypto, which
+// TestPaddingSignaturesMatchGoCrypto checks, and can be removed once
+// go-crypto supports padding.
+
+// candidateSignatureHashes are the hash algorithms of signatures inside
+// messages encrypted to recipients, in order of preference, as in go-crypto.
+var candidateSignatureHashes = []crypto.Hash{
+	crypto.SHA256,
+	crypto.SHA384,
+	crypto.SHA512,
+	crypto.SHA3_256,
+	crypto.SHA3_512,
+}
+
+// candidateCompression are the compression algorithms of messages encrypted
+// to recipients, as in go-crypto.
+var candidateCompression = []uint8{
+	uint8(packet.CompressionNone),
+	uint8(packet.CompressionZIP),
+	uint8(packet.CompressionZLIB),
+}
+
+// negotiateCompression returns the compression algorithm of the config if
+// all recipients prefer it, or else no compression, as go-crypto does when
+// encrypting to recipients.
+func negotiateCompression(recipients []*openpgp.Entity, date time.Time, config *packet.Config) packet.CompressionAlgo {
+	candidates := slices.Clone(candidateCompression)
+	for _, entity := range recipients {
+		sig, err := entity.PrimarySelfSignature(date, config)
+		if err != nil {
+			return packet.CompressionNone
+		}
+		candidates = intersectPreferences(candidates, sig.PreferredCompression)
+	}
+	if slices.Contains(candidates, uint8(config.Compression())) {
+		return config.Compression()
+	}
+	return packet.CompressionNone
+}
+
+// intendedRecipientsSignWriter writes a literal data packet signed with
+// one-pass signatures that carry the Intended Recipient Fingerprint
+// subpackets of the recipients, which go-crypto only adds when it encrypts
+// the message itself.
+type intendedRecipientsSignWriter struct {
+	payload    io.WriteCloser
+	literal    io.WriteCloser
+	signatures []*signatureContext
+	outsideSig *packet.Signature
+	sigType    packet.SignatureType
+	metadata   *packet.LiteralData
+	recipients []*packet.Recipient
+	config     *packet.Config
+}
+
+type signatureContext struct {
+	signer *packet.PrivateKey
+	hash   crypto.Hash
+	salt   []byte
+	h      hash.Hash
+	// wrappedHash canonicalizes the line endings of text signatures.
+	wrappedHash hash.Hash
+}
+
+// signWithIntendedRecipients returns a writer that writes the one-pass
+// signatures of the signers and the literal data into payload, and the
+// signatures with the intended recipients when closed.
+// Closing the writer does not close payload.
+// The hash of each signature is negotiated with the preferences of all
+// recipients and of the signing key.
+func signWithIntendedRecipients(
+	payload io.WriteCloser,
+	signers []*openpgp.Entity,
+	recipients []*openpgp.Entity,
+	intendedRecipients []*packet.Recipient,
+	hints *openpgp.FileHints,
+	textSig bool,
+	outsideSig []byte,
+	date time.Time,
+	config *packet.Config,
+) (io.WriteCloser, error) {
+	w := &intendedRecipientsSignWriter{
+		payload: payload,
+		sigType: packet.SigTypeBinary,
+		metadata: &packet.LiteralData{
+			Format:   'b',
+			FileName: hints.FileName,
+			Time:     uint32(hints.ModTime.Unix()),
+		},
+		recipients: intendedRecipients,
+		config:     config,
+	}
+	if textSig {
+		w.sigType = packet.SigTypeText
+	}
+	if hints.IsUTF8 {
+		w.metadata.Format = 'u'
+	}
+	if hints.ModTime.IsZero() {
+		w.metadata.Time = 0
+	}
+	hashes, err := recipientSignatureHashes(recipients, date, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if outsideSig != nil {
+		sig, err := parseOutsideSignature(outsideSig)
+		if err != nil {
+			return nil, err
+		}
+		ops := &packet.OnePassSignature{
+			Version:    3,
+			SigType:    sig.SigType,
+			Hash:       sig.Hash,
+			PubKeyAlgo: sig.PubKeyAlgo,
+			KeyId:      *sig.IssuerKeyId,
+			IsLast:     len(signers) == 0,
+		}
+		if sig.Version == 6 {
+			ops.Version = 6
+			ops.KeyFingerprint = sig.IssuerFingerprint
+			ops.Salt = sig.Salt()
+		}
+		if err := ops.Serialize(payload); err != nil {
+			return nil, err
+		}
+		w.outsideSig = sig
+	}
+	for i, entity := range signers {
+		signKey, ok := entity.SigningKeyById(config.Now(), config.SigningKey(), config)
+		if !ok || signKey.PrivateKey == nil {
+			return nil, errors.New("gopenpgp: no valid signing key")
+		}
+		if signKey.PrivateKey.Encrypted {
+			return nil, errors.New("gopenpgp: signing key must be unlocked")
+		}
+		if signKey.PrimarySelfSignature == nil {
+			return nil, errors.New("gopenpgp: signing key has no self-signature")
+		}
+		ctx := &signatureContext{signer: signKey.PrivateKey}
+		ctx.hash = selectSignatureHash(
+			intersectHashPreferences(slices.Clone(hashes), signKey.PrimarySelfSignature.PreferredHash),
+			config.Hash(),
+			&signKey.PrivateKey.PublicKey,
+		)
+		ops := &packet.OnePassSignature{
+			Version:    3,
+			SigType:    w.sigType,
+			Hash:       ctx.hash,
+			PubKeyAlgo: ctx.signer.PubKeyAlgo,
+			KeyId:      ctx.signer.KeyId,
+			IsLast:     i == len(signers)-1,
+		}
+		if ctx.signer.Version == 6 {
+			salt, err := packet.SignatureSaltForHash(ctx.hash, config.Random())
+			if err != nil {
+				return nil, err
+			}
+			ops.Version = 6
+			ops.KeyFingerprint = ctx.signer.Fingerprint
+			ops.Salt = salt
+			ctx.salt = salt
+		}
+		if err := ops.Serialize(payload); err != nil {
+			return nil, err
+		}
+		ctx.h = ctx.hash.New()
+		if ctx.salt != nil {
+			_, _ = ctx.h.Write(ctx.salt)
+		}
+		ctx.wrappedHash = ctx.h
+		if w.sigType == packet.SigTypeText {
+			ctx.wrappedHash = openpgp.NewCanonicalTextHash(ctx.h)
+		}
+		// Prepend, since the signature of the last one-pass signature is written first
+		w.signatures = append([]*signatureContext{ctx}, w.signatures...)
+	}
+
+	literal, err := packet.SerializeLiteral(internal.NewNoOpWriteCloser(payload), !hints.IsUTF8, hints.FileName, w.metadata.Time)
+	if err != nil {
+		return nil, err
+	}
+	w.literal = literal
+	return w, nil
+}
+
+func (w *intendedRecipientsSignWriter) Write(data []byte) (int, error) {
+	for _, ctx := range w.signatures {
+		if _, err := ctx.wrappedHash.Write(data); err != nil {
+			return 0, err
+		}
+	}
+	return w.literal.Write(data)
+}
+
+func (w *intendedRecipientsSignWriter) Close() error {
+	if err := w.literal.Close(); err != nil {
+		return err
+	}
+	for _, ctx := range w.signatures {
+		sigLifetimeSecs := w.config.SigLifetime()
+		sig := &packet.Signature{
+			Version:            ctx.signer.Version,
+			SigType:            w.sigType,
+			PubKeyAlgo:         ctx.signer.PubKeyAlgo,
+			Hash:               ctx.hash,
+			CreationTime:       w.config.Now(),
+			IssuerKeyId:        &ctx.signer.KeyId,
+			IssuerFingerprint:  ctx.signer.Fingerprint,
+			Notations:          w.config.Notations(),
+			SigLifetimeSecs:    &sigLifetimeSecs,
+			Metadata:           w.metadata,
+			IntendedRecipients: w.recipients,
+		}
+		if err := sig.SetSalt(ctx.salt); err != nil {
+			return err
+		}
+		if err := sig.Sign(ctx.h, ctx.signer, w.config); err != nil {
+			return fmt.Errorf("gopenpgp: unable to sign: %w", err)
+		}
+		if err := sig.Serialize(w.payload); err != nil {
+			return err
+		}
+	}
+	if w.outsideSig != nil {
+		return w.outsideSig.Serialize(w.payload)
+	}
+	return nil
+}
+
+// recipientSignatureHashes returns the candidate hashes of signatures inside
+// messages encrypted to the recipients, i.e., the hashes that all recipients
+// prefer.
+func recipientSignatureHashes(recipients []*openpgp.Entity, date time.Time, config *packet.Config) ([]crypto.Hash, error) {
+	hashes := slices.Clone(candidateSignatureHashes)
+	for _, entity := range recipients {
+		sig, err := entity.PrimarySelfSignature(date, config)
+		if err != nil {
+			return nil, fmt.Errorf("gopenpgp: recipient %x has no self-signature: %w", entity.PrimaryKey.Fingerprint, err)
+		}
+		hashes = intersectHashPreferences(hashes, sig.PreferredHash)
+	}
+	if len(hashes) == 0 {
+		hashes = []crypto.Hash{crypto.SHA256}
+	}
+	return hashes, nil
+}
+
+// selectSignatureHash returns the configured hash if it is a candidate, or
+// else the first candidate, among the hashes that are strong enough for
+// the signing key.
+func selectSignatureHash(candidates []crypto.Hash, configured crypto.Hash, signer *packet.PublicKey) crypto.Hash {
+	minSize := minSignatureHashSize(signer)
+	candidates = slices.DeleteFunc(candidates, func(h crypto.Hash) bool {
+		return !h.Available() || h.Size() < minSize
+	})
+	if slices.Contains(candidates, configured) {
+		return configured
+	}
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	switch minSize {
+	case crypto.SHA512.Size():
+		return crypto.SHA512
+	case crypto.SHA384.Size():
+		return crypto.SHA384
+	}
+	return crypto.SHA256
+}
+
+// minSignatureHashSize returns the minimum digest size of hashes for
+// signatures of the key, which increases with the size of its curve.
+func minSignatureHashSize(signer *packet.PublicKey) int {
+	switch signer.PubKeyAlgo {
+	case packet.PubKeyAlgoEd448, packet.PubKeyAlgoMldsa87Ed448:
+		return crypto.SHA512.Size()
+	case packet.PubKeyAlgoECDSA, packet.PubKeyAlgoEdDSA:
+		curve, err := signer.Curve()
+		if err != nil {
+			break
+		}
+		switch curve {
+		case packet.Curve448, packet.CurveNistP521, packet.CurveBrainpoolP512:
+			return crypto.SHA512.Size()
+		case packet.CurveNistP384, packet.CurveBrainpoolP384:
+			return crypto.SHA384.Size()
+		}
+	}
+	return crypto.SHA256.Size()
+}
+
+// intersectHashPreferences keeps the hashes in a that are in the preferred
+// hash IDs b, in the order of a.
+func intersectHashPreferences(a []crypto.Hash, b []uint8) []crypto.Hash {
+	return slices.DeleteFunc(a, func(h crypto.Hash) bool {
+		id, ok := openpgp.HashToHashId(h)
+		return !ok || !slices.Contains(b, id)
+	})
+}
+
+func parseOutsideSignature(outsideSig []byte) (*packet.Signature, error) {
+	p, err := packet.Read(bytes.NewReader(outsideSig))
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: unable to parse external signature: %w", err)
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok || sig.IssuerKeyId == nil {
+		return nil, errors.New("gopenpgp: external signature is not a signature packet with an issuer")
+	}
+	return sig, nil
+}
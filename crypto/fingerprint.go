@@ -0,0 +1,168 @@
+package crypto
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FingerprintURIScheme is the scheme of fingerprint URIs, e.g. encoded in QR
+// codes to verify keys.
+const FingerprintURIScheme = "OPENPGP4FPR"
+
+// Parameters of the fingerprint representations.
+const (
+	// randomArtWidth and randomArtHeight are the size of the random art field.
+	randomArtWidth  = 17
+	randomArtHeight = 9
+	// randomArtSymbols are the symbols for the number of visits of a field
+	// cell, followed by the symbols of the start and the end cell.
+	randomArtSymbols = " .o+=*BOX@%&#/^SE"
+	// safetyNumberIterations is the number of SHA-512 iterations per key.
+	safetyNumberIterations = 5200
+	// safetyNumberChunks is the number of 5-digit chunks per key.
+	safetyNumberChunks = 6
+)
+
+// GetFingerprintWords returns the fingerprint of the primary key as words of
+// the PGP word list, which are easy to read aloud, e.g. on the phone.
+func (key *Key) GetFingerprintWords() string {
+	return fingerprintWords(key.GetFingerprintBytes())
+}
+
+// fingerprintWords returns the words of the PGP word list for the fingerprint.
+func fingerprintWords(fingerprint []byte) string {
+	words := make([]string, len(fingerprint))
+	for i, b := range fingerprint {
+		words[i] = pgpWords[b][i%2]
+	}
+	return strings.Join(words, " ")
+}
+
+// GetFingerprintBlocks returns the fingerprint of the primary key as
+// upper-case hex in blocks of four characters, with the halves separated by
+// two spaces, as displayed by GnuPG.
+func (key *Key) GetFingerprintBlocks() string {
+	fingerprint := strings.ToUpper(key.GetFingerprint())
+	blocks := make([]string, 0, len(fingerprint)/4)
+	for i := 0; i < len(fingerprint); i += 4 {
+		blocks = append(blocks, fingerprint[i:min(i+4, len(fingerprint))])
+	}
+	half := len(blocks) / 2
+	return strings.Join(blocks[:half], " ") + "  " + strings.Join(blocks[half:], " ")
+}
+
+// GetFingerprintRandomArt returns the fingerprint of the primary key as
+// random art image of the drunken bishop algorithm, as displayed by
+// ssh-keygen.
+func (key *Key) GetFingerprintRandomArt() string {
+	return randomArt(key.GetFingerprintBytes(), fmt.Sprintf("[OpenPGP v%d]", key.GetVersion()))
+}
+
+// randomArt returns the random art image of the data with the title.
+func randomArt(data []byte, title string) string {
+	var field [randomArtWidth][randomArtHeight]int
+	x, y := randomArtWidth/2, randomArtHeight/2
+	maxVisits := len(randomArtSymbols) - 3
+	for _, b := range data {
+		// Each byte moves the bishop four times, starting with the low bits
+		for step := 0; step < 4; step++ {
+			if b&1 != 0 {
+				x = min(x+1, randomArtWidth-1)
+			} else {
+				x = max(x-1, 0)
+			}
+			if b&2 != 0 {
+				y = min(y+1, randomArtHeight-1)
+			} else {
+				y = max(y-1, 0)
+			}
+			field[x][y] = min(field[x][y]+1, maxVisits)
+			b >>= 2
+		}
+	}
+	field[randomArtWidth/2][randomArtHeight/2] = len(randomArtSymbols) - 2
+	field[x][y] = len(randomArtSymbols) - 1
+
+	var art strings.Builder
+	art.WriteString(randomArtBorder(title))
+	for row := 0; row < randomArtHeight; row++ {
+		art.WriteByte('|')
+		for column := 0; column < randomArtWidth; column++ {
+			art.WriteByte(randomArtSymbols[field[column][row]])
+		}
+		art.WriteString("|\n")
+	}
+	art.WriteString(randomArtBorder(""))
+	return art.String()
+}
+
+// randomArtBorder returns the top or bottom border of random art with the
+// centered title.
+func randomArtBorder(title string) string {
+	padding := randomArtWidth - len(title)
+	return "+" + strings.Repeat("-", padding/2) + title + strings.Repeat("-", padding-padding/2) + "+\n"
+}
+
+// GetSafetyNumber returns a numeric safety number of 60 digits in blocks of
+// five, which is derived from the fingerprints of the primary keys of both
+// keys.
+// Both parties compute the same safety number from their own and the other
+// key, thus they can compare it to verify each other's key, similar to the
+// safety numbers of Signal.
+func (key *Key) GetSafetyNumber(other *Key) string {
+	numbers := []string{safetyNumber(key.GetFingerprintBytes()), safetyNumber(other.GetFingerprintBytes())}
+	if numbers[1] < numbers[0] {
+		numbers[0], numbers[1] = numbers[1], numbers[0]
+	}
+	digits := numbers[0] + numbers[1]
+	blocks := make([]string, 0, len(digits)/5)
+	for i := 0; i < len(digits); i += 5 {
+		blocks = append(blocks, digits[i:i+5])
+	}
+	return strings.Join(blocks, " ")
+}
+
+// safetyNumber returns the 30-digit half of a safety number for the
+// fingerprint.
+func safetyNumber(fingerprint []byte) string {
+	// Version 0 of the safety number
+	digest := sha512.Sum512(append([]byte{0, 0}, fingerprint...))
+	for i := 1; i < safetyNumberIterations; i++ {
+		digest = sha512.Sum512(append(digest[:], fingerprint...))
+	}
+	var number strings.Builder
+	for chunk := 0; chunk < safetyNumberChunks; chunk++ {
+		var value [8]byte
+		copy(value[3:], digest[chunk*5:chunk*5+5])
+		fmt.Fprintf(&number, "%05d", binary.BigEndian.Uint64(value[:])%100000)
+	}
+	return number.String()
+}
+
+// GetFingerprintURI returns the fingerprint of the primary key as URI, e.g.
+// "OPENPGP4FPR:" followed by the upper-case hex fingerprint, as payload of QR
+// codes.
+func (key *Key) GetFingerprintURI() string {
+	return FingerprintURIScheme + ":" + strings.ToUpper(key.GetFingerprint())
+}
+
+// ParseFingerprintURI parses a fingerprint URI, as returned by
+// Key.GetFingerprintURI, and returns the fingerprint, e.g. to find the key
+// with KeyRing.GetKeyByFingerprint.
+func ParseFingerprintURI(uri string) ([]byte, error) {
+	scheme, value, found := strings.Cut(strings.TrimSpace(uri), ":")
+	if !found || !strings.EqualFold(scheme, FingerprintURIScheme) {
+		return nil, errors.New("gopenpgp: not a fingerprint uri")
+	}
+	// Parameters, e.g. the email address, may follow the fingerprint after '#'
+	value, _, _ = strings.Cut(value, "#")
+	fingerprint, err := hex.DecodeString(value)
+	if err != nil || (len(fingerprint) != 20 && len(fingerprint) != 32) {
+		return nil, errors.New("gopenpgp: invalid fingerprint in uri")
+	}
+	return fingerprint, nil
+}
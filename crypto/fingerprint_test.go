@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+func TestFingerprintWords(t *testing.T) {
+	// Example of the PGP word list
+	fingerprint, _ := hex.DecodeString("E58294F2E9A227486E8B061B31CC528FD7FA3F19")
+	assert.Exactly(t,
+		"topmost Istanbul Pluto vagabond treadmill Pacific brackish dictator goldfish Medusa "+
+			"afflict bravado chatter revolver Dupont midsummer stopwatch whimsical cowbell bottomless",
+		fingerprintWords(fingerprint),
+	)
+	assert.Len(t, strings.Fields(keyTestEC.GetFingerprintWords()), 20)
+}
+
+func TestFingerprintBlocks(t *testing.T) {
+	v6Key, err := PGPWithProfile(profile.RFC9580()).KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		New().
+		GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	for key, blocks := range map[*Key]int{keyTestEC: 10, v6Key: 16} {
+		formatted := key.GetFingerprintBlocks()
+		halves := strings.Split(formatted, "  ")
+		if assert.Len(t, halves, 2) {
+			assert.Len(t, strings.Fields(halves[0]), blocks/2)
+			assert.Len(t, strings.Fields(halves[1]), blocks/2)
+		}
+		assert.Regexp(t, regexp.MustCompile("^[0-9A-F ]+$"), formatted)
+		assert.Exactly(t, strings.ToUpper(key.GetFingerprint()), strings.ReplaceAll(formatted, " ", ""))
+	}
+}
+
+func TestFingerprintRandomArt(t *testing.T) {
+	// Random art of an SSH key fingerprint, as displayed by ssh-keygen
+	fingerprint, _ := hex.DecodeString("1317495cdfe003f2f433bbfcce5c5b9762d6bdc3472f9d0e974e9f49c0da1983")
+	assert.Exactly(t, ""+
+		"+--[ED25519 256]--+\n"+
+		"|         o+o+ .  |\n"+
+		"|          o= = o |\n"+
+		"|        . . . B .|\n"+
+		"|         o  o  = |\n"+
+		"|        S  E =.  |\n"+
+		"|         .  o.*.=|\n"+
+		"|           . B+XB|\n"+
+		"|            o OB&|\n"+
+		"|              .XO|\n"+
+		"+-----------------+\n",
+		randomArt(fingerprint, "[ED25519 256]"),
+	)
+
+	art := strings.Split(strings.TrimSuffix(keyTestEC.GetFingerprintRandomArt(), "\n"), "\n")
+	assert.Len(t, art, 11)
+	assert.Exactly(t, "+--[OpenPGP v4]---+", art[0])
+	for _, line := range art {
+		assert.Len(t, line, 19)
+	}
+}
+
+func TestSafetyNumber(t *testing.T) {
+	safetyNumber := keyTestEC.GetSafetyNumber(keyTestRSA)
+	assert.Regexp(t, regexp.MustCompile(`^\d{5}( \d{5}){11}$`), safetyNumber)
+	assert.Exactly(t, safetyNumber, keyTestRSA.GetSafetyNumber(keyTestEC))
+	assert.NotEqual(t, safetyNumber, keyTestEC.GetSafetyNumber(keyTestEC))
+}
+
+func TestFingerprintURI(t *testing.T) {
+	uri := keyTestEC.GetFingerprintURI()
+	assert.Exactly(t, "OPENPGP4FPR:"+strings.ToUpper(keyTestEC.GetFingerprint()), uri)
+
+	keyRing, err := NewKeyRing(keyTestRSA)
+	if err != nil {
+		t.Fatal("Expected no error while creating keyring, got:", err)
+	}
+	if err = keyRing.AddKey(keyTestEC); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+	for _, scanned := range []string{uri, strings.ToLower(uri), uri + "#a=max.mustermann%40protonmail.ch"} {
+		fingerprint, err := ParseFingerprintURI(scanned)
+		if err != nil {
+			t.Fatal("Expected no error while parsing fingerprint uri, got:", err)
+		}
+		key, err := keyRing.GetKeyByFingerprint(fingerprint)
+		if err != nil {
+			t.Fatal("Expected no error while finding key, got:", err)
+		}
+		assert.Exactly(t, keyTestEC.GetFingerprint(), key.GetFingerprint())
+	}
+
+	for _, invalid := range []string{"", keyTestEC.GetFingerprint(), "OPENPGP4FPR:", "OPENPGP4FPR:ABCD", "mailto:max@example.com"} {
+		_, err := ParseFingerprintURI(invalid)
+		assert.Error(t, err, invalid)
+	}
+	_, err = keyRing.GetKeyByFingerprint(make([]byte, 20))
+	assert.Error(t, err)
+}
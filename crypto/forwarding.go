@@ -0,0 +1,145 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+
+	"github.com/ProtonMail/gopenpgp/v3/constants"
+)
+
+// ForwardingInstance holds the proxy parameter that transforms the key
+// packets of messages encrypted to an encryption subkey of the forwarder into
+// key packets for the corresponding subkey of the forwardee, without
+// decrypting them.
+// It must be kept secret by the proxy, as it allows to decrypt forwarded
+// messages together with the forwardee key.
+type ForwardingInstance struct {
+	KeyVersion           int    `json:"keyVersion"`
+	ForwarderFingerprint []byte `json:"forwarderFingerprint"`
+	ForwardeeFingerprint []byte `json:"forwardeeFingerprint"`
+	ProxyParameter       []byte `json:"proxyParameter"`
+}
+
+func newForwardingInstance(instance packet.ForwardingInstance) *ForwardingInstance {
+	return &ForwardingInstance{
+		KeyVersion:           instance.KeyVersion,
+		ForwarderFingerprint: clone(instance.ForwarderFingerprint),
+		ForwardeeFingerprint: clone(instance.ForwardeeFingerprint),
+		ProxyParameter:       clone(instance.ProxyParameter),
+	}
+}
+
+func (instance *ForwardingInstance) forwardingInstance() packet.ForwardingInstance {
+	return packet.ForwardingInstance{
+		KeyVersion:           instance.KeyVersion,
+		ForwarderFingerprint: instance.ForwarderFingerprint,
+		ForwardeeFingerprint: instance.ForwardeeFingerprint,
+		ProxyParameter:       instance.ProxyParameter,
+	}
+}
+
+// CanForward returns true if messages encrypted to the key at the given unix
+// time can be forwarded, i.e. if the key is a v4 key and all its valid
+// encryption subkeys are ECDH Curve25519 keys.
+func (key *Key) CanForward(unixTime int64) bool {
+	if key.entity.PrimaryKey.Version != 4 {
+		return false
+	}
+	now := time.Unix(unixTime, 0)
+	canForward := false
+	for _, subkey := range key.entity.Subkeys {
+		if !subkey.PublicKey.PubKeyAlgo.CanEncrypt() {
+			continue
+		}
+		if _, err := subkey.Verify(now, nil); err != nil {
+			continue
+		}
+		if curve, err := subkey.PublicKey.Curve(); subkey.PublicKey.PubKeyAlgo != packet.PubKeyAlgoECDH ||
+			err != nil || curve != packet.Curve25519 {
+			return false
+		}
+		canForward = true
+	}
+	return canForward
+}
+
+// GenerateForwardingKey generates a forwardee key with the given user ID for
+// the unlocked private key of the forwarder, see Key.CanForward.
+// It returns one forwarding instance for each valid encryption subkey of the
+// forwarder, to transform messages for the forwardee with
+// PGPMessage.ProxyTransform.
+// The encryption subkeys of the forwardee key are flagged for forwarded
+// communication only, and are not exported with its public key.
+func (p *PGPHandle) GenerateForwardingKey(forwarder *Key, name, email string) (*Key, []*ForwardingInstance, error) {
+	if !forwarder.IsPrivate() {
+		return nil, nil, errors.New("gopenpgp: forwarding requires the private key of the forwarder")
+	}
+	if unlocked, err := forwarder.IsUnlocked(); err != nil || !unlocked {
+		return nil, nil, errors.New("gopenpgp: key is not unlocked")
+	}
+	config := p.profile.KeyGenerationConfig(constants.StandardSecurity)
+	config.Time = NewConstantClock(p.defaultTime().Unix())
+	entity, instances, err := forwarder.entity.NewForwardingEntity(name, "", email, config, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gopenpgp: error in generating forwarding key: %w", err)
+	}
+	forwardingInstances := make([]*ForwardingInstance, len(instances))
+	for i, instance := range instances {
+		forwardingInstances[i] = newForwardingInstance(instance)
+	}
+	return &Key{entity: entity}, forwardingInstances, nil
+}
+
+// ProxyTransform transforms the key packets of the message that are
+// encrypted to a forwarder subkey of the instances into key packets for the
+// forwardee, who can decrypt the returned message with the forwardee key.
+// Key packets of anonymous recipients are transformed with each instance.
+// Other key packets are dropped, and the data packet is not changed.
+func (msg *PGPMessage) ProxyTransform(instances ...*ForwardingInstance) (*PGPMessage, error) {
+	packets := packet.NewReader(bytes.NewReader(msg.KeyPacket))
+	var keyPackets bytes.Buffer
+	for {
+		p, err := packets.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in reading key packets: %w", err)
+		}
+		encryptedKey, ok := p.(*packet.EncryptedKey)
+		if !ok || encryptedKey.Algo != packet.PubKeyAlgoECDH || encryptedKey.Version != 3 {
+			continue
+		}
+		for _, instance := range instances {
+			if instance.KeyVersion != 4 || len(instance.ForwarderFingerprint) != 20 || len(instance.ForwardeeFingerprint) != 20 {
+				return nil, errors.New("gopenpgp: invalid forwarding instance, forwarding requires v4 keys")
+			}
+			forwardingInstance := instance.forwardingInstance()
+			if encryptedKey.KeyId != 0 && encryptedKey.KeyId != forwardingInstance.GetForwarderKeyId() {
+				continue
+			}
+			transformed, err := encryptedKey.ProxyTransform(forwardingInstance)
+			if err != nil {
+				return nil, fmt.Errorf("gopenpgp: error in transforming key packet: %w", err)
+			}
+			if err := transformed.Serialize(&keyPackets); err != nil {
+				return nil, fmt.Errorf("gopenpgp: error in serializing key packet: %w", err)
+			}
+		}
+	}
+	if keyPackets.Len() == 0 {
+		return nil, errors.New("gopenpgp: the message has no key packet for the forwarding instances")
+	}
+	return &PGPMessage{
+		KeyPacket:                keyPackets.Bytes(),
+		DataPacket:               msg.DataPacket,
+		DetachedSignature:        msg.DetachedSignature,
+		detachedSignatureIsPlain: msg.detachedSignatureIsPlain,
+		omitArmorChecksum:        msg.omitArmorChecksum,
+	}, nil
+}
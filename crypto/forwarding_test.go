@@ -0,0 +1,130 @@
+package crypto
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+func TestForwarding(t *testing.T) {
+	forwarderKey, err := testPGP.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	assert.True(t, forwarderKey.CanForward(testTime))
+	forwardeeKey, instances, err := testPGP.GenerateForwardingKey(forwarderKey, "forwardee", "forwardee@example.com")
+	if err != nil {
+		t.Fatal("Expected no error while generating forwarding key, got:", err)
+	}
+	if !assert.Len(t, instances, 1) {
+		return
+	}
+	assert.Exactly(t, forwarderKey.entity.Subkeys[0].PublicKey.Fingerprint, instances[0].ForwarderFingerprint)
+	assert.Exactly(t, forwardeeKey.entity.Subkeys[0].PublicKey.Fingerprint, instances[0].ForwardeeFingerprint)
+
+	// The instances can be stored by the proxy
+	serialized, err := json.Marshal(instances)
+	if err != nil {
+		t.Fatal("Expected no error while serializing instances, got:", err)
+	}
+	var storedInstances []*ForwardingInstance
+	if err := json.Unmarshal(serialized, &storedInstances); err != nil {
+		t.Fatal("Expected no error while parsing instances, got:", err)
+	}
+
+	forwarderPublicKey, err := forwarderKey.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	armoredForwardeeKey, err := forwardeeKey.Armor()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	forwardeeKey, err = NewKeyFromArmored(armoredForwardeeKey)
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+	decryptor, err := testPGP.Decryption().DecryptionKey(forwardeeKey).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryptor, got:", err)
+	}
+	for name, builder := range map[string]*EncryptionHandleBuilder{
+		"recipient": testPGP.Encryption().Recipient(forwarderPublicKey),
+		"hidden":    testPGP.Encryption().HiddenRecipient(forwarderPublicKey),
+	} {
+		encryptor, err := builder.New()
+		if err != nil {
+			t.Fatal("Expected no error while creating encryptor, got:", err)
+		}
+		message, err := encryptor.Encrypt([]byte(testMessageString))
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		_, err = decryptor.Decrypt(message.Bytes(), Bytes)
+		assert.Error(t, err, name)
+
+		forwarded, err := message.ProxyTransform(storedInstances...)
+		if err != nil {
+			t.Fatal("Expected no error while transforming message, got:", err)
+		}
+		result, err := decryptor.Decrypt(forwarded.Bytes(), Bytes)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting forwarded message, got:", err)
+		}
+		assert.Exactly(t, testMessageString, string(result.Bytes()), name)
+	}
+
+	// The forwarding subkeys are not exported with the public key
+	serializedPublicKey, err := forwardeeKey.GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing public key, got:", err)
+	}
+	forwardeePublicKey, err := NewKey(serializedPublicKey)
+	if err != nil {
+		t.Fatal("Expected no error while parsing public key, got:", err)
+	}
+	assert.Empty(t, forwardeePublicKey.entity.Subkeys)
+}
+
+func TestForwardingErrors(t *testing.T) {
+	v6Key, err := PGPWithProfile(profile.RFC9580()).KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		GenerationTime(testTime).
+		New().
+		GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	assert.False(t, v6Key.CanForward(testTime))
+	_, _, err = testPGP.GenerateForwardingKey(v6Key, "forwardee", "forwardee@example.com")
+	assert.Error(t, err)
+
+	forwarderKey, err := testPGP.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	publicKey, err := forwarderKey.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	_, _, err = testPGP.GenerateForwardingKey(publicKey, "forwardee", "forwardee@example.com")
+	assert.Error(t, err)
+
+	_, instances, err := testPGP.GenerateForwardingKey(forwarderKey, "forwardee", "forwardee@example.com")
+	if err != nil {
+		t.Fatal("Expected no error while generating forwarding key, got:", err)
+	}
+	encryptor, err := testPGP.Encryption().Recipient(v6Key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryptor, got:", err)
+	}
+	message, err := encryptor.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	_, err = message.ProxyTransform(instances...)
+	assert.Error(t, err)
+}
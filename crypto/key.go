@@ -28,6 +28,46 @@ type KeyEncryptionProfile interface {
 	KeyEncryptionConfig() *packet.Config
 }
 
+// Availability of the secret key material of a primary key or subkey.
+// int8 type for go-mobile clients.
+const (
+	// SecretKeyMissing indicates that only the public key is available.
+	SecretKeyMissing int8 = 0
+	// SecretKeyStripped indicates that the secret key material was removed,
+	// e.g. of an offline primary key (GnuPG gnu-dummy S2K).
+	SecretKeyStripped int8 = 1
+	// SecretKeyLocked indicates that the secret key material is encrypted.
+	SecretKeyLocked int8 = 2
+	// SecretKeyUnlocked indicates that the secret key material is usable.
+	SecretKeyUnlocked int8 = 3
+	// SecretKeyExternal indicates that private key operations are delegated
+	// to a KeyBackend.
+	SecretKeyExternal int8 = 4
+)
+
+// SecretKeyStatus reports the availability of the secret key material of the
+// primary key or a subkey.
+type SecretKeyStatus struct {
+	KeyID   uint64
+	Primary bool
+	Status  int8
+}
+
+func secretKeyStatus(privateKey *packet.PrivateKey) int8 {
+	switch {
+	case privateKey == nil:
+		return SecretKeyMissing
+	case privateKey.Dummy():
+		return SecretKeyStripped
+	case privateKey.Encrypted:
+		return SecretKeyLocked
+	}
+	if _, ok := privateKey.PrivateKey.(*backendPrivateKey); ok {
+		return SecretKeyExternal
+	}
+	return SecretKeyUnlocked
+}
+
 // --- Create Key object
 
 // NewKeyFromReader reads binary or armored data into a Key object.
@@ -272,6 +312,32 @@ func (key *Key) GetPublicKey() (b []byte, err error) {
 	return outBuf.Bytes(), nil
 }
 
+// ToSecretSubkeys returns a copy of the private key without the secret key
+// material of the primary key, as exported by gpg --export-secret-subkeys.
+// The primary secret key is replaced by a GnuPG dummy (gnu-dummy S2K), such
+// that the subkeys can be deployed for signing and decryption while the
+// primary key is kept offline. Locked subkeys stay locked.
+func (key *Key) ToSecretSubkeys() (*Key, error) {
+	if !key.IsPrivate() {
+		return nil, errors.New("gopenpgp: a public key has no secret subkeys")
+	}
+	subkeysKey, err := key.Copy()
+	if err != nil {
+		return nil, err
+	}
+	if subkeysKey.entity.PrivateKey.Dummy() {
+		return subkeysKey, nil
+	}
+	_ = clearPrivateKey(subkeysKey.entity.PrivateKey.PrivateKey)
+	dummy, err := newDummyPrivateKey(subkeysKey.entity.PrimaryKey)
+	if err != nil {
+		return nil, err
+	}
+	subkeysKey.entity.PrivateKey = dummy
+	subkeysKey.entity.PrimaryKey = &dummy.PublicKey
+	return subkeysKey, nil
+}
+
 // --- Key object properties
 
 // CanVerify returns true if any of the subkeys can be used for verification.
@@ -298,43 +364,53 @@ func (key *Key) IsExpired(unixTime int64) bool {
 }
 
 // IsRevoked checks whether the key or the primary identity has a valid revocation signature.
+// Designated revokers are ignored: a key that is only revoked by a designated
+// revoker is reported as not revoked, since the revocation can only be
+// verified with the key of the revoker. Use IsRevokedBy to check them.
 func (key *Key) IsRevoked(unixTime int64) bool {
 	current := time.Unix(unixTime, 0)
 	return key.entity.Revoked(current)
 }
 
+// IsRevokedBy checks whether the key or the primary identity has a valid revocation signature,
+// including revocations by the designated revokers in the revokers keyring,
+// see PGPHandle.AddDesignatedRevoker.
+func (key *Key) IsRevokedBy(unixTime int64, revokers *KeyRing) bool {
+	if key.IsRevoked(unixTime) {
+		return true
+	}
+	if revokers == nil {
+		return false
+	}
+	return key.isRevokedByDesignatedRevoker(time.Unix(unixTime, 0), revokers.GetKeys())
+}
+
 // IsPrivate returns true if the key is private.
 func (key *Key) IsPrivate() bool {
 	return key.entity.PrivateKey != nil
 }
 
 // IsLocked checks if a private key is locked.
+// Stripped secret keys, e.g. of an offline primary key, are ignored.
 func (key *Key) IsLocked() (bool, error) {
 	if key.entity.PrivateKey == nil {
 		return false, errors.New("gopenpgp: a public key cannot be locked")
 	}
 
-	encryptedKeys := 0
-
-	for _, sub := range key.entity.Subkeys {
-		if sub.PrivateKey != nil && !sub.PrivateKey.Dummy() && sub.PrivateKey.Encrypted {
-			encryptedKeys++
-		}
-	}
-
-	if key.entity.PrivateKey.Encrypted {
-		encryptedKeys++
-	}
-
-	return encryptedKeys > 0, nil
+	return key.countEncryptedKeys() > 0, nil
 }
 
 // IsUnlocked checks if a private key is unlocked.
+// Stripped secret keys, e.g. of an offline primary key, are ignored.
 func (key *Key) IsUnlocked() (bool, error) {
 	if key.entity.PrivateKey == nil {
 		return true, errors.New("gopenpgp: a public key cannot be unlocked")
 	}
 
+	return key.countEncryptedKeys() == 0, nil
+}
+
+func (key *Key) countEncryptedKeys() int {
 	encryptedKeys := 0
 
 	for _, sub := range key.entity.Subkeys {
@@ -343,11 +419,36 @@ func (key *Key) IsUnlocked() (bool, error) {
 		}
 	}
 
-	if key.entity.PrivateKey.Encrypted {
+	if !key.entity.PrivateKey.Dummy() && key.entity.PrivateKey.Encrypted {
 		encryptedKeys++
 	}
 
-	return encryptedKeys == 0, nil
+	return encryptedKeys
+}
+
+// GetSecretKeyStatus reports which components of the key hold secret key
+// material: the primary key first, followed by the subkeys in key order.
+func (key *Key) GetSecretKeyStatus() []SecretKeyStatus {
+	statuses := []SecretKeyStatus{{
+		KeyID:   key.entity.PrimaryKey.KeyId,
+		Primary: true,
+		Status:  secretKeyStatus(key.entity.PrivateKey),
+	}}
+	for _, sub := range key.entity.Subkeys {
+		statuses = append(statuses, SecretKeyStatus{
+			KeyID:  sub.PublicKey.KeyId,
+			Status: secretKeyStatus(sub.PrivateKey),
+		})
+	}
+	return statuses
+}
+
+// HasPrimarySecret returns true if the key holds the secret key material of
+// the primary key, which is required to certify other keys or to change
+// self-signatures.
+func (key *Key) HasPrimarySecret() bool {
+	status := secretKeyStatus(key.entity.PrivateKey)
+	return status != SecretKeyMissing && status != SecretKeyStripped
 }
 
 // Check verifies if the public keys match the private key parameters by
@@ -0,0 +1,228 @@
+package crypto
+
+import (
+	"bytes"
+	gocrypto "crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ProtonMail/go-crypto/openpgp/ecdsa"
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// KeyBackend performs the private key operations of keys whose secret key
+// material is not available in process memory, e.g. keys stored in a KMS,
+// an HSM or an agent.
+// The keys are identified by the fingerprint of the primary key or subkey.
+// Only RSA keys (signing and decryption) and ECDSA keys (signing) can be
+// used with a backend.
+type KeyBackend interface {
+	// Sign signs the digest, which was hashed with opts.HashFunc(), with the
+	// private key with the given fingerprint.
+	// As with crypto.Signer, RSA signatures are PKCS #1 v1.5 signatures and
+	// ECDSA signatures are ASN.1 encoded.
+	Sign(fingerprint, digest []byte, opts gocrypto.SignerOpts) ([]byte, error)
+	// Decrypt decrypts a PKCS #1 v1.5 encrypted session key with the RSA
+	// private key with the given fingerprint.
+	Decrypt(fingerprint, ciphertext []byte) ([]byte, error)
+}
+
+// NewKeyWithBackend returns a private key that delegates all private key
+// operations to the backend.
+// The key is built from the public parts of the given key. The primary key
+// and subkeys of an algorithm that is not supported by backends cannot be
+// used for private key operations.
+// The returned key can be used as signing and decryption key of all handles,
+// but it cannot be serialized, locked or unlocked.
+func NewKeyWithBackend(key *Key, backend KeyBackend) (*Key, error) {
+	if backend == nil {
+		return nil, errors.New("gopenpgp: nil key backend provided")
+	}
+	publicKey, err := key.GetPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	backendKey, err := NewKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := backendKey.entity
+	if isBackendAlgorithm(entity.PrimaryKey.PubKeyAlgo) {
+		entity.PrivateKey = newBackendPrivateKey(entity.PrimaryKey, backend)
+	} else {
+		// The primary key is required to mark the key as private key
+		if entity.PrivateKey, err = newDummyPrivateKey(entity.PrimaryKey); err != nil {
+			return nil, err
+		}
+	}
+	entity.PrimaryKey = &entity.PrivateKey.PublicKey
+	for i := range entity.Subkeys {
+		subkey := &entity.Subkeys[i]
+		if !isBackendAlgorithm(subkey.PublicKey.PubKeyAlgo) {
+			continue
+		}
+		subkey.PrivateKey = newBackendPrivateKey(subkey.PublicKey, backend)
+		subkey.PublicKey = &subkey.PrivateKey.PublicKey
+	}
+	return backendKey, nil
+}
+
+func isBackendAlgorithm(algorithm packet.PublicKeyAlgorithm) bool {
+	switch algorithm {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSASignOnly, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoECDSA:
+		return true
+	}
+	return false
+}
+
+func newBackendPrivateKey(publicKey *packet.PublicKey, backend KeyBackend) *packet.PrivateKey {
+	return &packet.PrivateKey{
+		PublicKey: *publicKey,
+		PrivateKey: &backendPrivateKey{
+			backend:     backend,
+			fingerprint: publicKey.Fingerprint,
+			public:      publicKey.PublicKey,
+		},
+	}
+}
+
+// newDummyPrivateKey returns a private key without secret key material,
+// using the GNU dummy S2K extension.
+func newDummyPrivateKey(publicKey *packet.PublicKey) (*packet.PrivateKey, error) {
+	var prefixed bytes.Buffer
+	if err := publicKey.SerializeForHash(&prefixed); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in serializing public key: %w", err)
+	}
+	// Strip the fingerprint prefix of the public key packet body
+	body := prefixed.Bytes()[3:]
+	// S2K usage, AES-128 and GNU S2K with SHA-1 and mode 1 (no secret key)
+	dummy := []byte{254, 7, 101, 2, 'G', 'N', 'U', 1}
+	if publicKey.Version == 6 {
+		body = prefixed.Bytes()[5:]
+		dummy = []byte{254, 8, 7, 6, 101, 2, 'G', 'N', 'U', 1}
+	}
+	body = append(body, dummy...)
+
+	packetData := []byte{0xc0 | 5, 0xff}
+	packetData = binary.BigEndian.AppendUint32(packetData, uint32(len(body)))
+	p, err := packet.Read(bytes.NewReader(append(packetData, body...)))
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in creating dummy private key: %w", err)
+	}
+	privateKey, ok := p.(*packet.PrivateKey)
+	if !ok || !privateKey.Dummy() {
+		return nil, errors.New("gopenpgp: error in creating dummy private key")
+	}
+	return privateKey, nil
+}
+
+// backendPrivateKey implements crypto.Signer and crypto.Decrypter with a
+// key backend, as used by go-crypto for RSA and ECDSA keys.
+type backendPrivateKey struct {
+	backend     KeyBackend
+	fingerprint []byte
+	public      gocrypto.PublicKey
+}
+
+func (priv *backendPrivateKey) Public() gocrypto.PublicKey {
+	return priv.public
+}
+
+func (priv *backendPrivateKey) Sign(_ io.Reader, digest []byte, opts gocrypto.SignerOpts) ([]byte, error) {
+	signature, err := priv.backend.Sign(priv.fingerprint, digest, opts)
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in signing with key backend: %w", err)
+	}
+	return signature, nil
+}
+
+func (priv *backendPrivateKey) Decrypt(_ io.Reader, ciphertext []byte, _ gocrypto.DecrypterOpts) ([]byte, error) {
+	plaintext, err := priv.backend.Decrypt(priv.fingerprint, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in decrypting with key backend: %w", err)
+	}
+	return plaintext, nil
+}
+
+// SoftwareKeyBackend is a key backend that keeps unlocked private keys in
+// memory. It is the reference implementation of KeyBackend and allows to
+// test code that uses external keys.
+type SoftwareKeyBackend struct {
+	privateKeys map[string]*packet.PrivateKey
+}
+
+// NewSoftwareKeyBackend creates a key backend with the private keys and
+// subkeys of the given unlocked keys.
+func NewSoftwareKeyBackend(keys ...*Key) (*SoftwareKeyBackend, error) {
+	backend := &SoftwareKeyBackend{privateKeys: make(map[string]*packet.PrivateKey)}
+	for _, key := range keys {
+		unlocked, err := key.IsUnlocked()
+		if err != nil {
+			return nil, err
+		}
+		if !unlocked {
+			return nil, errors.New("gopenpgp: a key backend requires unlocked keys")
+		}
+		backend.addPrivateKey(key.entity.PrivateKey)
+		for _, subkey := range key.entity.Subkeys {
+			backend.addPrivateKey(subkey.PrivateKey)
+		}
+	}
+	return backend, nil
+}
+
+func (backend *SoftwareKeyBackend) addPrivateKey(privateKey *packet.PrivateKey) {
+	if privateKey == nil || privateKey.Dummy() {
+		return
+	}
+	backend.privateKeys[hex.EncodeToString(privateKey.Fingerprint)] = privateKey
+}
+
+func (backend *SoftwareKeyBackend) privateKey(fingerprint []byte) (*packet.PrivateKey, error) {
+	privateKey, ok := backend.privateKeys[hex.EncodeToString(fingerprint)]
+	if !ok {
+		return nil, fmt.Errorf("gopenpgp: no private key with fingerprint %x", fingerprint)
+	}
+	return privateKey, nil
+}
+
+// Sign implements KeyBackend.
+func (backend *SoftwareKeyBackend) Sign(fingerprint, digest []byte, opts gocrypto.SignerOpts) ([]byte, error) {
+	privateKey, err := backend.privateKey(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	switch priv := privateKey.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		return priv.Sign(rand.Reader, digest, opts)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest)
+		if err != nil {
+			return nil, err
+		}
+		return asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	default:
+		return nil, fmt.Errorf("gopenpgp: key backend does not support signing with algorithm %d", privateKey.PubKeyAlgo)
+	}
+}
+
+// Decrypt implements KeyBackend.
+func (backend *SoftwareKeyBackend) Decrypt(fingerprint, ciphertext []byte) ([]byte, error) {
+	privateKey, err := backend.privateKey(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := privateKey.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("gopenpgp: key backend does not support decryption with algorithm %d", privateKey.PubKeyAlgo)
+	}
+	return rsa.DecryptPKCS1v15(rand.Reader, priv, ciphertext)
+}
@@ -0,0 +1,229 @@
+package crypto
+
+import (
+	"bytes"
+	gocrypto "crypto"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// backendRequest is the request of the stand-in key server, which mimics a
+// remote KMS.
+type backendRequest struct {
+	Fingerprint []byte
+	Data        []byte
+	Hash        gocrypto.Hash
+}
+
+type backendResponse struct {
+	Result []byte
+	Error  string
+}
+
+// newTestKeyServer starts a local stand-in for a remote key service that
+// performs private key operations with a software backend.
+func newTestKeyServer(t *testing.T, keys ...*Key) *httptest.Server {
+	backend, err := NewSoftwareKeyBackend(keys...)
+	if err != nil {
+		t.Fatal("Expected no error while creating software backend, got:", err)
+	}
+	handle := func(operation func(request *backendRequest) ([]byte, error)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var request backendRequest
+			var response backendResponse
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			result, err := operation(&request)
+			if err != nil {
+				response.Error = err.Error()
+			}
+			response.Result = result
+			_ = json.NewEncoder(w).Encode(&response)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/sign", handle(func(request *backendRequest) ([]byte, error) {
+		return backend.Sign(request.Fingerprint, request.Data, request.Hash)
+	}))
+	mux.Handle("/decrypt", handle(func(request *backendRequest) ([]byte, error) {
+		return backend.Decrypt(request.Fingerprint, request.Data)
+	}))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// remoteKeyBackend is the client of the stand-in key server.
+type remoteKeyBackend struct {
+	url string
+}
+
+func (backend *remoteKeyBackend) call(operation string, request *backendRequest) ([]byte, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	httpResponse, err := http.Post(backend.url+"/"+operation, "application/json", bytes.NewReader(body)) //nolint:noctx
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	var response backendResponse
+	if err := json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+	return response.Result, nil
+}
+
+func (backend *remoteKeyBackend) Sign(fingerprint, digest []byte, opts gocrypto.SignerOpts) ([]byte, error) {
+	return backend.call("sign", &backendRequest{Fingerprint: fingerprint, Data: digest, Hash: opts.HashFunc()})
+}
+
+func (backend *remoteKeyBackend) Decrypt(fingerprint, ciphertext []byte) ([]byte, error) {
+	return backend.call("decrypt", &backendRequest{Fingerprint: fingerprint, Data: ciphertext})
+}
+
+func newRemoteTestKey(t *testing.T, key *Key) *Key {
+	server := newTestKeyServer(t, key)
+	backendKey, err := NewKeyWithBackend(key, &remoteKeyBackend{url: server.URL})
+	if err != nil {
+		t.Fatal("Expected no error while creating backend key, got:", err)
+	}
+	return backendKey
+}
+
+func generateECDSATestKey(t *testing.T) *Key {
+	entity, err := openpgp.NewEntity(keyTestName, "", keyTestDomain, &packet.Config{
+		Algorithm: packet.PubKeyAlgoECDSA,
+		Curve:     packet.CurveNistP256,
+		Time:      func() time.Time { return time.Unix(testTime, 0) },
+	})
+	if err != nil {
+		t.Fatal("Expected no error while generating ECDSA key, got:", err)
+	}
+	key, err := NewKeyFromEntity(entity)
+	if err != nil {
+		t.Fatal("Expected no error while creating key, got:", err)
+	}
+	return key
+}
+
+func TestKeyBackendSignVerify(t *testing.T) {
+	for name, key := range map[string]*Key{"RSA": keyTestRSA, "ECDSA": generateECDSATestKey(t)} {
+		backendKey := newRemoteTestKey(t, key)
+		assert.True(t, backendKey.IsPrivate(), name)
+		unlocked, err := backendKey.IsUnlocked()
+		if err != nil {
+			t.Fatal("Expected no error while checking the backend key, got:", err)
+		}
+		assert.True(t, unlocked, name)
+		assert.Exactly(t, SecretKeyExternal, backendKey.GetSecretKeyStatus()[0].Status, name)
+
+		signer, err := testPGP.Sign().SigningKey(backendKey).Detached().New()
+		if err != nil {
+			t.Fatal("Expected no error while creating signer, got:", err)
+		}
+		signature, err := signer.Sign([]byte(testMessage), Armor)
+		if err != nil {
+			t.Fatal("Expected no error while signing with backend key, got:", err)
+		}
+		verifier, err := testPGP.Verify().VerificationKey(key).New()
+		if err != nil {
+			t.Fatal("Expected no error while creating verifier, got:", err)
+		}
+		result, err := verifier.VerifyDetached([]byte(testMessage), signature, Armor)
+		if err != nil {
+			t.Fatal("Expected no error while verifying, got:", err)
+		}
+		assert.NoError(t, result.SignatureError(), name)
+	}
+}
+
+func TestKeyBackendEncryptDecrypt(t *testing.T) {
+	backendKey := newRemoteTestKey(t, keyTestRSA)
+	encHandle, err := testPGP.Encryption().Recipient(keyTestRSA).SigningKey(backendKey).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	message, err := encHandle.Encrypt([]byte(testMessage))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, err := testPGP.Decryption().DecryptionKey(backendKey).VerificationKey(keyTestRSA).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryption handle, got:", err)
+	}
+	result, err := decHandle.Decrypt(message.Bytes(), Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with backend key, got:", err)
+	}
+	assert.Exactly(t, testMessage, string(result.Bytes()))
+	assert.NoError(t, result.SignatureError())
+
+	_, err = backendKey.Serialize()
+	assert.Error(t, err)
+}
+
+func TestKeyBackendUnsupportedAlgorithm(t *testing.T) {
+	// EdDSA and ECDH keys cannot be used with a backend
+	backendKey := newRemoteTestKey(t, keyTestEC)
+	assert.True(t, backendKey.IsPrivate())
+	assert.True(t, backendKey.entity.PrivateKey.Dummy())
+	assert.Exactly(t, keyTestEC.GetFingerprint(), backendKey.GetFingerprint())
+
+	signer, err := testPGP.Sign().SigningKey(backendKey).Detached().New()
+	if err != nil {
+		t.Fatal("Expected no error while creating signer, got:", err)
+	}
+	_, err = signer.Sign([]byte(testMessage), Bytes)
+	assert.Error(t, err)
+
+	message, err := testPGP.Encryption().Recipient(keyTestEC).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	encrypted, err := message.Encrypt([]byte(testMessage))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, err := testPGP.Decryption().DecryptionKey(backendKey).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryption handle, got:", err)
+	}
+	_, err = decHandle.Decrypt(encrypted.Bytes(), Bytes)
+	assert.Error(t, err)
+}
+
+func TestKeyBackendMissingKey(t *testing.T) {
+	server := newTestKeyServer(t, keyTestEC)
+	backendKey, err := NewKeyWithBackend(keyTestRSA, &remoteKeyBackend{url: server.URL})
+	if err != nil {
+		t.Fatal("Expected no error while creating backend key, got:", err)
+	}
+	signer, err := testPGP.Sign().SigningKey(backendKey).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating signer, got:", err)
+	}
+	_, err = signer.Sign([]byte(testMessage), Bytes)
+	assert.Error(t, err)
+
+	publicKey, err := keyTestRSA.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	_, err = NewSoftwareKeyBackend(publicKey)
+	assert.Error(t, err)
+}
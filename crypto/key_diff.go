@@ -0,0 +1,213 @@
+package crypto
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// KeyDiff describes what changed between two versions of a key, e.g. after
+// refreshing a key from a key server or WKD.
+// It can be serialized to JSON, see Key.DiffJson.
+type KeyDiff struct {
+	Fingerprint string `json:"fingerprint"`
+	// PrimaryKeyRevoked is true if the primary key is only revoked in the
+	// new version.
+	PrimaryKeyRevoked bool          `json:"primaryKeyRevoked"`
+	AddedUserIDs      []*UserIDInfo `json:"addedUserIds"`
+	RemovedUserIDs    []*UserIDInfo `json:"removedUserIds"`
+	// RevokedUserIDs are the user IDs that are only revoked in the new
+	// version.
+	RevokedUserIDs []*UserIDInfo       `json:"revokedUserIds"`
+	AddedSubkeys   []*KeyComponentInfo `json:"addedSubkeys"`
+	RemovedSubkeys []*KeyComponentInfo `json:"removedSubkeys"`
+	// RevokedSubkeys and ExpiredSubkeys are the subkeys that are only
+	// revoked or expired in the new version.
+	RevokedSubkeys    []*KeyComponentInfo `json:"revokedSubkeys"`
+	ExpiredSubkeys    []*KeyComponentInfo `json:"expiredSubkeys"`
+	ExpirationChanges []*ExpirationChange `json:"expirationChanges"`
+	// OldPreferences and NewPreferences are set if the algorithm
+	// preferences of the key changed.
+	OldPreferences *PreferencesInfo `json:"oldPreferences,omitempty"`
+	NewPreferences *PreferencesInfo `json:"newPreferences,omitempty"`
+	// NewCertifications are the third-party certifications and
+	// certification revocations that are only in the new version.
+	// They are not verified, as the keys of the issuers are not known.
+	NewCertifications []*CertificationInfo `json:"newCertifications"`
+}
+
+// ExpirationChange describes a changed expiration time of the primary key or
+// a subkey. Expiration times are unix times, or 0 if the key does not expire.
+type ExpirationChange struct {
+	Fingerprint       string `json:"fingerprint"`
+	Primary           bool   `json:"primary"`
+	OldExpirationTime int64  `json:"oldExpirationTime"`
+	NewExpirationTime int64  `json:"newExpirationTime"`
+}
+
+// CertificationInfo describes a third-party certification of a user ID.
+type CertificationInfo struct {
+	UserID      string `json:"userId"`
+	IssuerKeyID string `json:"issuerKeyId,omitempty"`
+	// IssuerFingerprint is only set if the signature contains it.
+	IssuerFingerprint string `json:"issuerFingerprint,omitempty"`
+	CreationTime      int64  `json:"creationTime"`
+	// Revocation is true for certification revocations.
+	Revocation bool `json:"revocation"`
+}
+
+// IsEmpty returns true if the two versions of the key do not differ.
+func (diff *KeyDiff) IsEmpty() bool {
+	return !diff.PrimaryKeyRevoked &&
+		len(diff.AddedUserIDs) == 0 && len(diff.RemovedUserIDs) == 0 && len(diff.RevokedUserIDs) == 0 &&
+		len(diff.AddedSubkeys) == 0 && len(diff.RemovedSubkeys) == 0 &&
+		len(diff.RevokedSubkeys) == 0 && len(diff.ExpiredSubkeys) == 0 &&
+		len(diff.ExpirationChanges) == 0 && diff.OldPreferences == nil && diff.NewPreferences == nil &&
+		len(diff.NewCertifications) == 0
+}
+
+// Diff compares the key with a newer version of it, e.g. after refreshing
+// it from a key server, at the given unix time.
+// Both keys must have the same primary key.
+func (key *Key) Diff(newKey *Key, unixTime int64) (*KeyDiff, error) {
+	if !bytes.Equal(key.GetFingerprintBytes(), newKey.GetFingerprintBytes()) {
+		return nil, errors.New("gopenpgp: cannot compare keys with different primary keys")
+	}
+	oldInfo, err := key.GetKeyInfo(unixTime)
+	if err != nil {
+		return nil, err
+	}
+	newInfo, err := newKey.GetKeyInfo(unixTime)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &KeyDiff{
+		Fingerprint:       newInfo.Fingerprint,
+		PrimaryKeyRevoked: newInfo.PrimaryKey.Revoked && !oldInfo.PrimaryKey.Revoked,
+	}
+	if !reflect.DeepEqual(oldInfo.Preferences, newInfo.Preferences) {
+		diff.OldPreferences, diff.NewPreferences = oldInfo.Preferences, newInfo.Preferences
+	}
+	diff.addExpirationChange(oldInfo.PrimaryKey, newInfo.PrimaryKey)
+
+	oldUserIDs := make(map[string]*UserIDInfo, len(oldInfo.UserIDs))
+	for _, userID := range oldInfo.UserIDs {
+		oldUserIDs[userID.UserID] = userID
+	}
+	for _, userID := range newInfo.UserIDs {
+		oldUserID, ok := oldUserIDs[userID.UserID]
+		switch {
+		case !ok:
+			diff.AddedUserIDs = append(diff.AddedUserIDs, userID)
+		case userID.Revoked && !oldUserID.Revoked:
+			diff.RevokedUserIDs = append(diff.RevokedUserIDs, userID)
+		}
+		delete(oldUserIDs, userID.UserID)
+	}
+	for _, userID := range oldInfo.UserIDs {
+		if _, ok := oldUserIDs[userID.UserID]; ok {
+			diff.RemovedUserIDs = append(diff.RemovedUserIDs, userID)
+		}
+	}
+
+	oldSubkeys := make(map[string]*KeyComponentInfo, len(oldInfo.Subkeys))
+	for _, subkey := range oldInfo.Subkeys {
+		oldSubkeys[subkey.Fingerprint] = subkey
+	}
+	for _, subkey := range newInfo.Subkeys {
+		oldSubkey, ok := oldSubkeys[subkey.Fingerprint]
+		if !ok {
+			diff.AddedSubkeys = append(diff.AddedSubkeys, subkey)
+			continue
+		}
+		delete(oldSubkeys, subkey.Fingerprint)
+		if subkey.Revoked && !oldSubkey.Revoked {
+			diff.RevokedSubkeys = append(diff.RevokedSubkeys, subkey)
+		}
+		if subkey.Expired && !oldSubkey.Expired {
+			diff.ExpiredSubkeys = append(diff.ExpiredSubkeys, subkey)
+		}
+		diff.addExpirationChange(oldSubkey, subkey)
+	}
+	for _, subkey := range oldInfo.Subkeys {
+		if _, ok := oldSubkeys[subkey.Fingerprint]; ok {
+			diff.RemovedSubkeys = append(diff.RemovedSubkeys, subkey)
+		}
+	}
+
+	oldCertifications := make(map[string]bool)
+	for _, certification := range key.certifications() {
+		oldCertifications[certification.id()] = true
+	}
+	for _, certification := range newKey.certifications() {
+		if !oldCertifications[certification.id()] {
+			diff.NewCertifications = append(diff.NewCertifications, certification)
+		}
+	}
+	return diff, nil
+}
+
+// DiffJson compares the key with a newer version of it at the given unix
+// time, and returns the KeyDiff serialized as JSON for go-mobile clients.
+func (key *Key) DiffJson(newKey *Key, unixTime int64) ([]byte, error) {
+	diff, err := key.Diff(newKey, unixTime)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(diff)
+}
+
+func (diff *KeyDiff) addExpirationChange(oldKey, newKey *KeyComponentInfo) {
+	if oldKey.ExpirationTime != newKey.ExpirationTime {
+		diff.ExpirationChanges = append(diff.ExpirationChanges, &ExpirationChange{
+			Fingerprint:       newKey.Fingerprint,
+			Primary:           newKey.Primary,
+			OldExpirationTime: oldKey.ExpirationTime,
+			NewExpirationTime: newKey.ExpirationTime,
+		})
+	}
+}
+
+// certifications returns the third-party certifications of the user IDs,
+// sorted by user ID and creation time.
+func (key *Key) certifications() []*CertificationInfo {
+	var certifications []*CertificationInfo
+	for _, identity := range key.entity.Identities {
+		for _, certification := range identity.OtherCertifications {
+			sig := certification.Packet
+			info := &CertificationInfo{
+				UserID:            identity.Name,
+				IssuerFingerprint: hex.EncodeToString(sig.IssuerFingerprint),
+				CreationTime:      sig.CreationTime.Unix(),
+				Revocation:        sig.SigType == packet.SigTypeCertificationRevocation,
+			}
+			if sig.IssuerKeyId != nil {
+				info.IssuerKeyID = keyIDToHex(*sig.IssuerKeyId)
+			}
+			certifications = append(certifications, info)
+		}
+	}
+	slices.SortStableFunc(certifications, func(a, b *CertificationInfo) int {
+		if c := strings.Compare(a.UserID, b.UserID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.CreationTime, b.CreationTime)
+	})
+	return certifications
+}
+
+// id identifies the certification to find it in another version of the key.
+func (certification *CertificationInfo) id() string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%d\x00%t",
+		certification.UserID, certification.IssuerKeyID, certification.IssuerFingerprint,
+		certification.CreationTime, certification.Revocation)
+}
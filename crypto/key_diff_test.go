@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyDiff(t *testing.T) {
+	key, err := testPGP.KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		GenerationTime(testTime).
+		CertificationOnlyPrimaryKey().
+		AddSigningSubkey(0, 86400).
+		AddEncryptionSubkey(0, 0).
+		New().
+		GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	oldKey, err := key.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	certifier, err := testPGP.KeyGeneration().AddUserId("certifier", keyTestDomain).GenerationTime(testTime).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+
+	config := &packet.Config{Time: func() time.Time { return time.Unix(testTime+3600, 0) }}
+	entity := key.entity
+	if err := entity.AddUserId("New", "", "new@example.com", config); err != nil {
+		t.Fatal("Expected no error while adding user ID, got:", err)
+	}
+	if err := entity.AddEncryptionSubkey(config); err != nil {
+		t.Fatal("Expected no error while adding subkey, got:", err)
+	}
+	if err := entity.Subkeys[1].Revoke(packet.KeyRetired, "retired", config); err != nil {
+		t.Fatal("Expected no error while revoking subkey, got:", err)
+	}
+	bindingSig, err := entity.Subkeys[0].LatestValidBindingSignature(time.Time{}, config)
+	if err != nil {
+		t.Fatal("Expected no error while getting binding signature, got:", err)
+	}
+	lifetime := uint32(2 * 86400)
+	bindingSig.KeyLifetimeSecs = &lifetime
+	if err := entity.Subkeys[0].ReSign(config); err != nil {
+		t.Fatal("Expected no error while re-signing subkey, got:", err)
+	}
+	userID := keyTestName + " <" + keyTestDomain + ">"
+	if err := entity.Identities[userID].SignIdentity(certifier.entity, config); err != nil {
+		t.Fatal("Expected no error while certifying user ID, got:", err)
+	}
+	newKey, err := key.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+
+	diff, err := oldKey.Diff(newKey, testTime+7200)
+	if err != nil {
+		t.Fatal("Expected no error while comparing keys, got:", err)
+	}
+	assert.False(t, diff.IsEmpty())
+	assert.False(t, diff.PrimaryKeyRevoked)
+	if assert.Len(t, diff.AddedUserIDs, 1) {
+		assert.Exactly(t, "new@example.com", diff.AddedUserIDs[0].Email)
+	}
+	assert.Empty(t, diff.RemovedUserIDs)
+	if assert.Len(t, diff.AddedSubkeys, 1) {
+		assert.True(t, diff.AddedSubkeys[0].CanEncrypt)
+	}
+	if assert.Len(t, diff.RevokedSubkeys, 1) {
+		assert.Exactly(t, "retired", diff.RevokedSubkeys[0].RevocationReasonText)
+	}
+	if assert.Len(t, diff.ExpirationChanges, 1) {
+		change := diff.ExpirationChanges[0]
+		assert.False(t, change.Primary)
+		assert.Exactly(t, int64(testTime+86400), change.OldExpirationTime)
+		assert.Exactly(t, int64(testTime+2*86400), change.NewExpirationTime)
+	}
+	if assert.Len(t, diff.NewCertifications, 1) {
+		certification := diff.NewCertifications[0]
+		assert.Exactly(t, userID, certification.UserID)
+		assert.Exactly(t, certifier.GetFingerprint(), certification.IssuerFingerprint)
+		assert.False(t, certification.Revocation)
+	}
+
+	diff, err = newKey.Diff(oldKey, testTime+86400+3600)
+	if err != nil {
+		t.Fatal("Expected no error while comparing keys, got:", err)
+	}
+	assert.Len(t, diff.RemovedUserIDs, 1)
+	assert.Len(t, diff.RemovedSubkeys, 1)
+	assert.Len(t, diff.ExpiredSubkeys, 1)
+	assert.Empty(t, diff.NewCertifications)
+
+	diff, err = oldKey.Diff(oldKey, testTime)
+	if err != nil {
+		t.Fatal("Expected no error while comparing keys, got:", err)
+	}
+	assert.True(t, diff.IsEmpty())
+
+	_, err = oldKey.Diff(certifier, testTime)
+	assert.Error(t, err)
+}
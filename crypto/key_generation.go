@@ -14,6 +14,21 @@ const (
 	KeyGenerationCurve25519 int = 3
 	// KeyGenerationCurve448 allows to override the output key algorithm in key generation to curve448 (as defined in RFC9580).
 	KeyGenerationCurve448 int = 4
+	// KeyGenerationMLDSA65Ed25519 allows to override the output key algorithm in key generation to the
+	// post-quantum hybrid ML-DSA-65 + Ed25519 with an ML-KEM-768 + X25519 encryption subkey (as defined in
+	// draft-ietf-openpgp-pqc). The key is always a v6 key.
+	KeyGenerationMLDSA65Ed25519 int = 5
+	// KeyGenerationMLDSA87Ed448 allows to override the output key algorithm in key generation to the
+	// post-quantum hybrid ML-DSA-87 + Ed448 with an ML-KEM-1024 + X448 encryption subkey (as defined in
+	// draft-ietf-openpgp-pqc). The key is always a v6 key.
+	KeyGenerationMLDSA87Ed448 int = 6
+	// KeyGenerationMLKEM768X25519 selects the post-quantum hybrid ML-KEM-768 + X25519 algorithm
+	// (as defined in draft-ietf-openpgp-pqc) for encryption subkeys, see KeyGenerationBuilder.AddEncryptionSubkey.
+	KeyGenerationMLKEM768X25519 int = 7
+	// KeyGenerationMLKEM1024X448 selects the post-quantum hybrid ML-KEM-1024 + X448 algorithm
+	// (as defined in draft-ietf-openpgp-pqc) for encryption subkeys of v6 keys,
+	// see KeyGenerationBuilder.AddEncryptionSubkey.
+	KeyGenerationMLKEM1024X448 int = 8
 )
 
 type KeyGenerationProfile interface {
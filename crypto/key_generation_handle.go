@@ -1,24 +1,63 @@
 package crypto
 
 import (
+	"crypto/sha512"
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
 	"github.com/ProtonMail/gopenpgp/v3/constants"
+	"golang.org/x/crypto/hkdf"
 )
 
 type identity struct {
 	name, comment, email string
 }
 
+// Parameters of the key derivation from a seed.
+const (
+	minSeedLength = 16
+	seedSalt      = "gopenpgp key generation"
+)
+
+// Usages of additional subkeys.
+const (
+	subkeyUsageSign = iota
+	subkeyUsageEncrypt
+	subkeyUsageAuthenticate
+)
+
+// subkeySpec describes an additional subkey of generated keys.
+type subkeySpec struct {
+	usage           int
+	algorithm       int
+	keyLifetimeSecs uint32
+}
+
+// algorithmPreferences overrides the algorithm preferences of the profile
+// in the self-signatures of generated keys.
+// The algorithms are given as OpenPGP identifiers.
+type algorithmPreferences struct {
+	ciphers     []uint8
+	hashes      []uint8
+	compression []uint8
+	// cipherSuites are pairs of the identifiers of a cipher and an AEAD mode.
+	cipherSuites []uint8
+}
+
 type keyGenerationHandle struct {
-	identities        []identity
-	keyLifetimeSecs   uint32
-	overrideAlgorithm int
-	profile           KeyGenerationProfile
-	clock             Clock
+	identities           []identity
+	keyLifetimeSecs      uint32
+	overrideAlgorithm    int
+	authenticationSubkey bool
+	certificationOnly    bool
+	subkeys              []subkeySpec
+	preferences          algorithmPreferences
+	seed                 []byte
+	profile              KeyGenerationProfile
+	clock                Clock
 }
 
 // --- Default key generation handle to build from
@@ -44,6 +83,12 @@ func (kgh *keyGenerationHandle) GenerateKeyWithSecurity(security int8) (key *Key
 	updateConfig(config, kgh.overrideAlgorithm)
 	config.Time = NewConstantClock(kgh.clock().Unix())
 	config.KeyLifetimeSecs = kgh.keyLifetimeSecs
+	if kgh.seed != nil {
+		if err = kgh.validateSeed(config); err != nil {
+			return nil, err
+		}
+		config.Rand = seededRandom(kgh.seed, "primary key")
+	}
 	key = &Key{}
 
 	if len(kgh.identities) == 0 {
@@ -85,9 +130,204 @@ func (kgh *keyGenerationHandle) GenerateKeyWithSecurity(security int8) (key *Key
 	if key.entity.PrivateKey == nil {
 		return nil, errors.New("gopenpgp: error in generating private key")
 	}
+
+	subkeys := kgh.subkeys
+	if kgh.hasSubkeyUsage(subkeyUsageEncrypt) {
+		// The configured encryption subkeys replace the default one
+		key.entity.Subkeys = key.entity.Subkeys[:0]
+	} else if kgh.seed != nil {
+		// The default encryption subkey is derived from the seed as first subkey
+		key.entity.Subkeys = key.entity.Subkeys[:0]
+		subkeys = append([]subkeySpec{{usage: subkeyUsageEncrypt}}, subkeys...)
+	}
+	for i, spec := range subkeys {
+		if err = addSubkey(key.entity, kgh.subkeyConfig(config, i), spec); err != nil {
+			return nil, err
+		}
+	}
+
+	if kgh.authenticationSubkey {
+		if err = addAuthenticationSubkey(key.entity, kgh.subkeyConfig(config, len(subkeys))); err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in adding authentication subkey: %w", err)
+		}
+	}
+
+	if kgh.certificationOnly || kgh.preferences.isSet() {
+		if err = kgh.updateSelfSignatures(key.entity, config); err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in updating self-signatures: %w", err)
+		}
+	}
 	return key, nil
 }
 
+// validateSeed checks that the seed is long enough and that only Curve25519
+// keys are generated from it.
+func (kgh *keyGenerationHandle) validateSeed(config *packet.Config) error {
+	if len(kgh.seed) < minSeedLength {
+		return fmt.Errorf("gopenpgp: key generation seed must be at least %d bytes", minSeedLength)
+	}
+	curve25519 := config.PublicKeyAlgorithm() == packet.PubKeyAlgoEd25519 ||
+		config.PublicKeyAlgorithm() == packet.PubKeyAlgoEdDSA && config.CurveName() == packet.Curve25519
+	for _, spec := range kgh.subkeys {
+		if spec.algorithm != 0 && spec.algorithm != KeyGenerationCurve25519Legacy && spec.algorithm != KeyGenerationCurve25519 {
+			curve25519 = false
+		}
+	}
+	if !curve25519 {
+		return errors.New("gopenpgp: only curve25519 keys can be generated from a seed")
+	}
+	return nil
+}
+
+// subkeyConfig returns the configuration to generate the subkey at the given
+// position, which derives its key material from the seed, if any.
+func (kgh *keyGenerationHandle) subkeyConfig(config *packet.Config, position int) *packet.Config {
+	if kgh.seed == nil {
+		return config
+	}
+	subkeyConfig := *config
+	subkeyConfig.Rand = seededRandom(kgh.seed, fmt.Sprintf("subkey %d", position))
+	return &subkeyConfig
+}
+
+// seededRandom returns a deterministic stream of random bytes for the key
+// component with the given label, derived from the seed with HKDF-SHA512.
+// The key material is read first, followed by signature salts.
+func seededRandom(seed []byte, label string) io.Reader {
+	return hkdf.New(sha512.New, seed, []byte(seedSalt), []byte(label))
+}
+
+func (kgh *keyGenerationHandle) hasSubkeyUsage(usage int) bool {
+	for _, spec := range kgh.subkeys {
+		if spec.usage == usage {
+			return true
+		}
+	}
+	return false
+}
+
+// addSubkey adds a subkey with the given usage, algorithm and lifetime to
+// the entity.
+func addSubkey(entity *openpgp.Entity, config *packet.Config, spec subkeySpec) error {
+	if spec.algorithm == KeyGenerationCurve25519Legacy && entity.PrimaryKey.Version == 6 {
+		return errors.New("gopenpgp: legacy curve25519 subkeys cannot be added to v6 keys")
+	}
+	subkeyConfig := *config
+	updateConfig(&subkeyConfig, spec.algorithm)
+	subkeyConfig.KeyLifetimeSecs = spec.keyLifetimeSecs
+
+	var err error
+	switch spec.usage {
+	case subkeyUsageSign:
+		err = entity.AddSigningSubkey(&subkeyConfig)
+	case subkeyUsageEncrypt:
+		err = entity.AddEncryptionSubkey(&subkeyConfig)
+	case subkeyUsageAuthenticate:
+		err = addAuthenticationSubkey(entity, &subkeyConfig)
+	}
+	if err != nil {
+		return fmt.Errorf("gopenpgp: error in adding subkey: %w", err)
+	}
+	return nil
+}
+
+// addAuthenticationSubkey adds a subkey to the entity that is only flagged
+// for authentication, e.g. for the use as SSH key.
+func addAuthenticationSubkey(entity *openpgp.Entity, config *packet.Config) error {
+	if err := entity.AddSigningSubkey(config); err != nil {
+		return err
+	}
+	subkey := &entity.Subkeys[len(entity.Subkeys)-1]
+	binding := subkey.Bindings[0].Packet
+	binding.FlagSign = false
+	binding.FlagAuthenticate = true
+	// The primary key binding signature is only required for signing subkeys
+	binding.EmbeddedSignature = nil
+	return binding.SignKey(subkey.PublicKey, entity.PrivateKey, config)
+}
+
+// updateSelfSignatures applies the primary key flags and algorithm
+// preferences to the self-signatures that carry the key properties, i.e. the
+// direct-key signature of v6 keys and the user id self-signatures of v4 keys,
+// and signs them again.
+func (kgh *keyGenerationHandle) updateSelfSignatures(entity *openpgp.Entity, config *packet.Config) error {
+	if err := kgh.preferences.validate(); err != nil {
+		return err
+	}
+	update := func(sig *packet.Signature) bool {
+		if !sig.FlagsValid {
+			return false
+		}
+		if kgh.certificationOnly {
+			sig.FlagSign = false
+		}
+		kgh.preferences.apply(sig)
+		return true
+	}
+
+	for i, directSignature := range entity.DirectSignatures {
+		sig := directSignature.Packet
+		if !update(sig) {
+			continue
+		}
+		if err := sig.SignDirectKeyBinding(entity.PrimaryKey, entity.PrivateKey, config); err != nil {
+			return err
+		}
+		entity.DirectSignatures[i] = packet.NewVerifiableSig(sig)
+	}
+	for _, ident := range entity.Identities {
+		for i, selfCertification := range ident.SelfCertifications {
+			sig := selfCertification.Packet
+			if !update(sig) {
+				continue
+			}
+			if err := sig.SignUserId(ident.UserId.Id, entity.PrimaryKey, entity.PrivateKey, config); err != nil {
+				return err
+			}
+			ident.SelfCertifications[i] = packet.NewVerifiableSig(sig)
+		}
+	}
+	return nil
+}
+
+func (prefs algorithmPreferences) isSet() bool {
+	return prefs.ciphers != nil || prefs.hashes != nil || prefs.compression != nil || prefs.cipherSuites != nil
+}
+
+// validate checks that the preferred hash functions are supported and that
+// the cipher suites are pairs.
+func (prefs algorithmPreferences) validate() error {
+	for _, id := range prefs.hashes {
+		if _, ok := openpgp.HashIdToHash(id); !ok {
+			return errors.New("gopenpgp: unsupported preferred hash function")
+		}
+	}
+	if len(prefs.cipherSuites)%2 != 0 {
+		return errors.New("gopenpgp: preferred cipher suites must be pairs of cipher and AEAD mode")
+	}
+	return nil
+}
+
+// apply writes the preferences that are set into the self-signature.
+func (prefs algorithmPreferences) apply(sig *packet.Signature) {
+	if prefs.ciphers != nil {
+		sig.PreferredSymmetric = prefs.ciphers
+	}
+	if prefs.hashes != nil {
+		sig.PreferredHash = prefs.hashes
+	}
+	if prefs.compression != nil {
+		sig.PreferredCompression = prefs.compression
+	}
+	if prefs.cipherSuites != nil {
+		sig.PreferredCipherSuites = make([][2]uint8, 0, len(prefs.cipherSuites)/2)
+		for i := 0; i+1 < len(prefs.cipherSuites); i += 2 {
+			sig.PreferredCipherSuites = append(sig.PreferredCipherSuites, [2]uint8{prefs.cipherSuites[i], prefs.cipherSuites[i+1]})
+		}
+		sig.SEIPDv2 = len(prefs.cipherSuites) > 0
+	}
+}
+
 func (id identity) valid() error {
 	if len(id.email) == 0 && len(id.name) == 0 {
 		return errors.New("gopenpgp: neither name nor email set in user id")
@@ -108,5 +348,15 @@ func updateConfig(config *packet.Config, algorithm int) {
 		config.Algorithm = packet.PubKeyAlgoEd25519
 	case KeyGenerationCurve448:
 		config.Algorithm = packet.PubKeyAlgoEd448
+	case KeyGenerationMLDSA65Ed25519:
+		config.V6Keys = true
+		config.Algorithm = packet.PubKeyAlgoMldsa65Ed25519
+	case KeyGenerationMLDSA87Ed448:
+		config.V6Keys = true
+		config.Algorithm = packet.PubKeyAlgoMldsa87Ed448
+	case KeyGenerationMLKEM768X25519:
+		config.Algorithm = packet.PubKeyAlgoMlkem768X25519
+	case KeyGenerationMLKEM1024X448:
+		config.Algorithm = packet.PubKeyAlgoMlkem1024X448
 	}
 }
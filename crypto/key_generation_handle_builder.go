@@ -37,12 +37,114 @@ func (kgb *KeyGenerationBuilder) AddUserId(name, email string) *KeyGenerationBui
 //
 // Allowed inputs (integer enum for go-mobile compatibility):
 // crypto.KeyGenerationRSA4096, crypto.KeyGenerationC25519, crypto.KeyGenerationC25519Refresh
-// crypto.KeyGenerationC448, crypto.KeyGenerationC448Refresh.
+// crypto.KeyGenerationC448, crypto.KeyGenerationC448Refresh,
+// crypto.KeyGenerationMLDSA65Ed25519, crypto.KeyGenerationMLDSA87Ed448.
 func (kgb *KeyGenerationBuilder) OverrideProfileAlgorithm(algorithm int) *KeyGenerationBuilder {
 	kgb.handle.overrideAlgorithm = algorithm
 	return kgb
 }
 
+// Seed derives the key material of any generated key deterministically from
+// the seed of at least 16 bytes, e.g. from mnemonic.ToSeed, instead of random
+// key material. Each subkey is derived from the seed and its position.
+// Generating a key with the same seed, options and GenerationTime yields a
+// byte-identical key, thus a key can be regenerated from its mnemonic.
+// Only Curve25519 keys are supported, i.e. crypto.KeyGenerationCurve25519Legacy
+// and crypto.KeyGenerationCurve25519.
+func (kgb *KeyGenerationBuilder) Seed(seed []byte) *KeyGenerationBuilder {
+	kgb.handle.seed = append([]byte{}, seed...)
+	return kgb
+}
+
+// AuthenticationSubkey adds a subkey flagged for authentication to any
+// generated key, which can be used as SSH key.
+// The subkey uses the same algorithm as the signing key.
+func (kgb *KeyGenerationBuilder) AuthenticationSubkey() *KeyGenerationBuilder {
+	kgb.handle.authenticationSubkey = true
+	return kgb
+}
+
+// CertificationOnlyPrimaryKey restricts the primary key of any generated key
+// to certifications, i.e. the primary key is not flagged for signing data.
+// Generated keys can only sign data with signing subkeys, see AddSigningSubkey.
+func (kgb *KeyGenerationBuilder) CertificationOnlyPrimaryKey() *KeyGenerationBuilder {
+	kgb.handle.certificationOnly = true
+	return kgb
+}
+
+// AddSigningSubkey adds a signing subkey to any generated key.
+// The algorithm takes the same values as OverrideProfileAlgorithm, zero uses
+// the algorithm of the primary key. The lifetime is given in seconds, zero
+// means infinite lifetime.
+func (kgb *KeyGenerationBuilder) AddSigningSubkey(algorithm int, seconds int32) *KeyGenerationBuilder {
+	return kgb.addSubkey(subkeyUsageSign, algorithm, seconds)
+}
+
+// AddEncryptionSubkey adds an encryption subkey to any generated key, e.g.
+// an X448 subkey with crypto.KeyGenerationCurve448 for an Ed25519 primary key.
+// If any encryption subkey is added, generated keys do not contain the
+// default encryption subkey of the profile.
+// The algorithm takes the same values as OverrideProfileAlgorithm, zero uses
+// the algorithm of the primary key. The lifetime is given in seconds, zero
+// means infinite lifetime.
+func (kgb *KeyGenerationBuilder) AddEncryptionSubkey(algorithm int, seconds int32) *KeyGenerationBuilder {
+	return kgb.addSubkey(subkeyUsageEncrypt, algorithm, seconds)
+}
+
+// AddAuthenticationSubkey adds an authentication subkey to any generated key.
+// The algorithm takes the same values as OverrideProfileAlgorithm, zero uses
+// the algorithm of the primary key. The lifetime is given in seconds, zero
+// means infinite lifetime.
+func (kgb *KeyGenerationBuilder) AddAuthenticationSubkey(algorithm int, seconds int32) *KeyGenerationBuilder {
+	return kgb.addSubkey(subkeyUsageAuthenticate, algorithm, seconds)
+}
+
+func (kgb *KeyGenerationBuilder) addSubkey(usage, algorithm int, seconds int32) *KeyGenerationBuilder {
+	kgb.handle.subkeys = append(kgb.handle.subkeys, subkeySpec{
+		usage:           usage,
+		algorithm:       algorithm,
+		keyLifetimeSecs: uint32(seconds),
+	})
+	return kgb
+}
+
+// PreferredCiphers sets the preferred symmetric ciphers as OpenPGP
+// identifiers, in order of preference, that are written into the
+// self-signatures of any generated key instead of the preferences of the
+// profile, e.g. constants.CipherAES256.
+func (kgb *KeyGenerationBuilder) PreferredCiphers(ciphers []byte) *KeyGenerationBuilder {
+	kgb.handle.preferences.ciphers = append([]uint8{}, ciphers...)
+	return kgb
+}
+
+// PreferredHashes sets the preferred hash functions as OpenPGP identifiers,
+// in order of preference, that are written into the self-signatures of any
+// generated key instead of the preferences of the profile, e.g. 8 for SHA-256.
+func (kgb *KeyGenerationBuilder) PreferredHashes(hashes []byte) *KeyGenerationBuilder {
+	kgb.handle.preferences.hashes = append([]uint8{}, hashes...)
+	return kgb
+}
+
+// PreferredCompression sets the preferred compression algorithms as OpenPGP
+// identifiers, in order of preference, that are written into the
+// self-signatures of any generated key instead of the preferences of the
+// profile, e.g. 0 for no compression.
+func (kgb *KeyGenerationBuilder) PreferredCompression(algorithms []byte) *KeyGenerationBuilder {
+	kgb.handle.preferences.compression = append([]uint8{}, algorithms...)
+	return kgb
+}
+
+// PreferredCipherSuites sets the preferred AEAD cipher suites, in order of
+// preference, that are written into the self-signatures of any generated key
+// instead of the preferences of the profile.
+// Each cipher suite is given by two bytes, the OpenPGP identifiers of the
+// cipher and the AEAD mode, e.g. []byte{9, 2} for AES-256 with OCB.
+// Generated keys advertise support for SEIPDv2 if the list is not empty.
+func (kgb *KeyGenerationBuilder) PreferredCipherSuites(cipherSuites []byte) *KeyGenerationBuilder {
+	kgb.handle.preferences.cipherSuites = append([]uint8{}, cipherSuites...)
+	return kgb
+}
+
 // New creates a new key generation handle from the internal configuration
 // that allows to generate pgp keys.
 func (kgb *KeyGenerationBuilder) New() PGPKeyGeneration {
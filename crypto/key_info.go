@@ -0,0 +1,321 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+)
+
+// KeyInfo describes a key at a given time, e.g. to display it in a UI.
+// It can be serialized to JSON, see Key.GetKeyInfoJson.
+type KeyInfo struct {
+	Version     int    `json:"version"`
+	Fingerprint string `json:"fingerprint"`
+	KeyID       string `json:"keyId"`
+	Private     bool   `json:"private"`
+	// Time is the unix time the information was computed at.
+	Time       int64               `json:"time"`
+	PrimaryKey *KeyComponentInfo   `json:"primaryKey"`
+	Subkeys    []*KeyComponentInfo `json:"subkeys"`
+	UserIDs    []*UserIDInfo       `json:"userIds"`
+	// Preferences are the algorithm preferences of the key, taken from the
+	// direct-key signature of v6 keys or the primary user ID of v4 keys.
+	Preferences *PreferencesInfo `json:"preferences,omitempty"`
+}
+
+// KeyComponentInfo describes the primary key or a subkey.
+type KeyComponentInfo struct {
+	KeyID       string `json:"keyId"`
+	Fingerprint string `json:"fingerprint"`
+	Primary     bool   `json:"primary"`
+	Version     int    `json:"version"`
+	// Algorithm is the public key algorithm, see packet.PublicKeyAlgorithm.
+	Algorithm     int    `json:"algorithm"`
+	AlgorithmName string `json:"algorithmName"`
+	BitLength     int    `json:"bitLength,omitempty"`
+	Curve         string `json:"curve,omitempty"`
+	CreationTime  int64  `json:"creationTime"`
+	// ExpirationTime is the unix time the key expires at, or 0 if it does
+	// not expire.
+	ExpirationTime int64 `json:"expirationTime"`
+	Expired        bool  `json:"expired"`
+	// Valid is true if the key has a valid self-signature and is neither
+	// expired nor revoked.
+	Valid           bool `json:"valid"`
+	CanCertify      bool `json:"canCertify"`
+	CanSign         bool `json:"canSign"`
+	CanEncrypt      bool `json:"canEncrypt"`
+	CanAuthenticate bool `json:"canAuthenticate"`
+	Revoked         bool `json:"revoked"`
+	// RevocationReason is the reason code of the revocation, see
+	// packet.ReasonForRevocation.
+	RevocationReason     int    `json:"revocationReason,omitempty"`
+	RevocationReasonText string `json:"revocationReasonText,omitempty"`
+	// SecretStatus is one of the SecretKey constants.
+	SecretStatus int8 `json:"secretStatus"`
+	// Protection describes the protection of locked secret key material.
+	Protection *KeyProtection `json:"protection,omitempty"`
+}
+
+// UserIDInfo describes a user ID and its self-certification.
+type UserIDInfo struct {
+	UserID  string `json:"userId"`
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Comment string `json:"comment,omitempty"`
+	Primary bool   `json:"primary"`
+	// Valid is true if the user ID has a valid self-certification and is
+	// not revoked.
+	Valid bool `json:"valid"`
+	// CreationTime is the unix time of the self-certification.
+	CreationTime int64 `json:"creationTime,omitempty"`
+	// ExpirationTime is the unix time the self-certification expires at, or
+	// 0 if it does not expire.
+	ExpirationTime       int64            `json:"expirationTime,omitempty"`
+	Revoked              bool             `json:"revoked"`
+	RevocationReason     int              `json:"revocationReason,omitempty"`
+	RevocationReasonText string           `json:"revocationReasonText,omitempty"`
+	Preferences          *PreferencesInfo `json:"preferences,omitempty"`
+}
+
+// PreferencesInfo lists the algorithm preferences of a self-signature, in
+// order of preference.
+type PreferencesInfo struct {
+	// Ciphers are the preferred symmetric ciphers, see packet.CipherFunction.
+	Ciphers []int `json:"ciphers"`
+	// Hashes are the preferred hash algorithms as OpenPGP identifiers.
+	Hashes []int `json:"hashes"`
+	// Compression are the preferred compression algorithms, see
+	// packet.CompressionAlgo.
+	Compression []int `json:"compression"`
+	// CipherSuites are the preferred AEAD cipher suites as pairs of cipher
+	// and AEAD mode.
+	CipherSuites [][2]int `json:"cipherSuites"`
+	SEIPDv1      bool     `json:"seipdv1"`
+	SEIPDv2      bool     `json:"seipdv2"`
+}
+
+var publicKeyAlgorithmNames = map[packet.PublicKeyAlgorithm]string{
+	packet.PubKeyAlgoRSA:            "RSA",
+	packet.PubKeyAlgoRSAEncryptOnly: "RSA",
+	packet.PubKeyAlgoRSASignOnly:    "RSA",
+	packet.PubKeyAlgoElGamal:        "ElGamal",
+	packet.PubKeyAlgoDSA:            "DSA",
+	packet.PubKeyAlgoECDH:           "ECDH",
+	packet.PubKeyAlgoECDSA:          "ECDSA",
+	packet.PubKeyAlgoEdDSA:          "EdDSA",
+	packet.PubKeyAlgoX25519:         "X25519",
+	packet.PubKeyAlgoX448:           "X448",
+	packet.PubKeyAlgoEd25519:        "Ed25519",
+	packet.PubKeyAlgoEd448:          "Ed448",
+	packet.PubKeyAlgoMldsa65Ed25519: "ML-DSA-65+Ed25519",
+	packet.PubKeyAlgoMldsa87Ed448:   "ML-DSA-87+Ed448",
+	packet.PubKeyAlgoMlkem768X25519: "ML-KEM-768+X25519",
+	packet.PubKeyAlgoMlkem1024X448:  "ML-KEM-1024+X448",
+}
+
+// GetKeyInfo describes the primary key, the subkeys and the user IDs of the
+// key at the given unix time.
+func (key *Key) GetKeyInfo(unixTime int64) (*KeyInfo, error) {
+	date := time.Unix(unixTime, 0)
+	config := &packet.Config{}
+	var protections []KeyProtection
+	if key.IsPrivate() {
+		var err error
+		if protections, err = key.GetKeyProtection(); err != nil {
+			return nil, err
+		}
+	}
+	protection := func(index int) *KeyProtection {
+		if index < len(protections) && protections[index].Status == SecretKeyLocked {
+			return &protections[index]
+		}
+		return nil
+	}
+
+	entity := key.entity
+	info := &KeyInfo{
+		Version:     entity.PrimaryKey.Version,
+		Fingerprint: key.GetFingerprint(),
+		KeyID:       key.GetHexKeyID(),
+		Private:     key.IsPrivate(),
+		Time:        unixTime,
+	}
+
+	primary := newKeyComponentInfo(entity.PrimaryKey, entity.PrivateKey)
+	primary.Primary = true
+	primary.Protection = protection(0)
+	if selfSig, err := entity.PrimarySelfSignature(date, config); err == nil {
+		primary.setSelfSignature(entity.PrimaryKey, selfSig, date)
+		info.Preferences = newPreferencesInfo(selfSig)
+	}
+	primary.Revoked = entity.Revoked(date)
+	if primary.Revoked {
+		primary.RevocationReason, primary.RevocationReasonText = revocationReason(entity.Revocations)
+	}
+	_, err := entity.VerifyPrimaryKey(date, config)
+	primary.Valid = err == nil
+	info.PrimaryKey = primary
+
+	info.Subkeys = make([]*KeyComponentInfo, len(entity.Subkeys))
+	for i := range entity.Subkeys {
+		sub := &entity.Subkeys[i]
+		subkey := newKeyComponentInfo(sub.PublicKey, sub.PrivateKey)
+		subkey.Protection = protection(i + 1)
+		bindingSig, err := sub.LatestValidBindingSignature(date, config)
+		if err == nil {
+			subkey.setSelfSignature(sub.PublicKey, bindingSig, date)
+		}
+		subkey.Revoked = sub.Revoked(bindingSig, date)
+		if subkey.Revoked {
+			subkey.RevocationReason, subkey.RevocationReasonText = revocationReason(sub.Revocations)
+		}
+		_, err = sub.Verify(date, config)
+		subkey.Valid = err == nil && primary.Valid
+		info.Subkeys[i] = subkey
+	}
+
+	_, primaryIdentity := entity.PrimaryIdentity(date, config)
+	for _, identity := range entity.Identities {
+		info.UserIDs = append(info.UserIDs, newUserIDInfo(identity, identity == primaryIdentity, date, config))
+	}
+	sortUserIDInfos(info.UserIDs)
+	return info, nil
+}
+
+// GetKeyInfoJson returns the KeyInfo of the key at the given unix time,
+// serialized as JSON for go-mobile clients.
+func (key *Key) GetKeyInfoJson(unixTime int64) ([]byte, error) {
+	info, err := key.GetKeyInfo(unixTime)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(info)
+}
+
+func newKeyComponentInfo(publicKey *packet.PublicKey, privateKey *packet.PrivateKey) *KeyComponentInfo {
+	info := &KeyComponentInfo{
+		KeyID:         keyIDToHex(publicKey.KeyId),
+		Fingerprint:   hex.EncodeToString(publicKey.Fingerprint),
+		Version:       publicKey.Version,
+		Algorithm:     int(publicKey.PubKeyAlgo),
+		AlgorithmName: publicKeyAlgorithmNames[publicKey.PubKeyAlgo],
+		CreationTime:  publicKey.CreationTime.Unix(),
+		SecretStatus:  secretKeyStatus(privateKey),
+	}
+	if info.AlgorithmName == "" {
+		info.AlgorithmName = "unknown"
+	}
+	if bitLength, err := publicKey.BitLength(); err == nil {
+		info.BitLength = int(bitLength)
+	}
+	if curve, err := publicKey.Curve(); err == nil {
+		info.Curve = string(curve)
+	}
+	return info
+}
+
+// setSelfSignature sets the expiration and the key flags from the
+// self-signature or binding signature of the key.
+func (info *KeyComponentInfo) setSelfSignature(publicKey *packet.PublicKey, sig *packet.Signature, date time.Time) {
+	if sig.KeyLifetimeSecs != nil && *sig.KeyLifetimeSecs != 0 {
+		info.ExpirationTime = info.CreationTime + int64(*sig.KeyLifetimeSecs)
+	}
+	info.Expired = publicKey.KeyExpired(sig, date) || sig.SigExpired(date)
+	if sig.FlagsValid {
+		info.CanCertify = sig.FlagCertify
+		info.CanSign = sig.FlagSign
+		info.CanEncrypt = sig.FlagEncryptCommunications || sig.FlagEncryptStorage
+		info.CanAuthenticate = sig.FlagAuthenticate
+	}
+}
+
+// revocationReason returns the reason of the first verified revocation.
+func revocationReason(revocations []*packet.VerifiableSignature) (int, string) {
+	for _, revocation := range revocations {
+		if revocation.Valid == nil || !*revocation.Valid {
+			continue
+		}
+		if revocation.Packet.RevocationReason == nil {
+			return int(packet.NoReason), revocation.Packet.RevocationReasonText
+		}
+		return int(*revocation.Packet.RevocationReason), revocation.Packet.RevocationReasonText
+	}
+	return 0, ""
+}
+
+func newUserIDInfo(identity *openpgp.Identity, primary bool, date time.Time, config *packet.Config) *UserIDInfo {
+	info := &UserIDInfo{
+		UserID:  identity.Name,
+		Primary: primary,
+	}
+	if identity.UserId != nil {
+		info.Name = identity.UserId.Name
+		info.Email = identity.UserId.Email
+		info.Comment = identity.UserId.Comment
+	}
+	selfSig, err := identity.LatestValidSelfCertification(date, config)
+	if err == nil {
+		info.CreationTime = selfSig.CreationTime.Unix()
+		if lifetime := selfSig.SigLifetimeSecs; lifetime != nil && *lifetime != 0 {
+			info.ExpirationTime = info.CreationTime + int64(*lifetime)
+		}
+		info.Preferences = newPreferencesInfo(selfSig)
+	}
+	info.Revoked = identity.Revoked(selfSig, date, config)
+	if info.Revoked {
+		info.RevocationReason, info.RevocationReasonText = revocationReason(identity.Revocations)
+	}
+	_, err = identity.Verify(date, config)
+	info.Valid = err == nil
+	return info
+}
+
+// newPreferencesInfo returns the preferences of the self-signature, or nil
+// if it states none.
+func newPreferencesInfo(sig *packet.Signature) *PreferencesInfo {
+	if len(sig.PreferredSymmetric) == 0 && len(sig.PreferredHash) == 0 &&
+		len(sig.PreferredCompression) == 0 && len(sig.PreferredCipherSuites) == 0 &&
+		!sig.SEIPDv1 && !sig.SEIPDv2 {
+		return nil
+	}
+	preferences := &PreferencesInfo{
+		Ciphers:      toInts(sig.PreferredSymmetric),
+		Hashes:       toInts(sig.PreferredHash),
+		Compression:  toInts(sig.PreferredCompression),
+		CipherSuites: make([][2]int, len(sig.PreferredCipherSuites)),
+		SEIPDv1:      sig.SEIPDv1,
+		SEIPDv2:      sig.SEIPDv2,
+	}
+	for i, suite := range sig.PreferredCipherSuites {
+		preferences.CipherSuites[i] = [2]int{int(suite[0]), int(suite[1])}
+	}
+	return preferences
+}
+
+func toInts(values []uint8) []int {
+	ints := make([]int, len(values))
+	for i, value := range values {
+		ints[i] = int(value)
+	}
+	return ints
+}
+
+// sortUserIDInfos sorts the user IDs with the primary user ID first, as the
+// identities of an entity are kept in a map.
+func sortUserIDInfos(userIDs []*UserIDInfo) {
+	slices.SortStableFunc(userIDs, func(a, b *UserIDInfo) int {
+		switch {
+		case a.Primary != b.Primary && a.Primary:
+			return -1
+		case a.Primary != b.Primary:
+			return 1
+		}
+		return strings.Compare(a.UserID, b.UserID)
+	})
+}
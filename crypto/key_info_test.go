@@ -0,0 +1,163 @@
+package crypto
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+func TestKeyInfo(t *testing.T) {
+	for name, pgp := range map[string]*PGPHandle{"v4": testPGP, "v6": PGPWithProfile(profile.RFC9580())} {
+		key, err := pgp.KeyGeneration().
+			AddUserId(keyTestName, keyTestDomain).
+			GenerationTime(testTime).
+			OverrideProfileAlgorithm(KeyGenerationCurve25519).
+			CertificationOnlyPrimaryKey().
+			AddSigningSubkey(0, 86400).
+			AddEncryptionSubkey(KeyGenerationCurve448, 0).
+			New().
+			GenerateKey()
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		key, err = pgp.LockKey(key, keyTestPassphrase)
+		if err != nil {
+			t.Fatal("Expected no error while locking key, got:", err)
+		}
+
+		info, err := key.GetKeyInfo(testTime + 3600)
+		if err != nil {
+			t.Fatal("Expected no error while getting key info, got:", err)
+		}
+		assert.Exactly(t, key.GetVersion(), info.Version, name)
+		assert.Exactly(t, key.GetFingerprint(), info.Fingerprint, name)
+		assert.True(t, info.Private, name)
+		assert.NotNil(t, info.Preferences, name)
+
+		primary := info.PrimaryKey
+		assert.True(t, primary.Primary, name)
+		assert.True(t, primary.Valid, name)
+		assert.True(t, primary.CanCertify, name)
+		assert.False(t, primary.CanSign, name)
+		assert.Exactly(t, int64(testTime), primary.CreationTime, name)
+		assert.Exactly(t, SecretKeyLocked, primary.SecretStatus, name)
+		if assert.NotNil(t, primary.Protection, name) {
+			assert.NotZero(t, primary.Protection.Cipher, name)
+		}
+
+		if assert.Len(t, info.Subkeys, 2, name) {
+			signing, encryption := info.Subkeys[0], info.Subkeys[1]
+			assert.True(t, signing.CanSign, name)
+			assert.Exactly(t, int64(testTime+86400), signing.ExpirationTime, name)
+			assert.True(t, signing.Valid, name)
+			assert.True(t, encryption.CanEncrypt, name)
+			assert.Exactly(t, "Curve448", encryption.Curve, name)
+			assert.Exactly(t, int64(0), encryption.ExpirationTime, name)
+		}
+
+		if assert.Len(t, info.UserIDs, 1, name) {
+			userID := info.UserIDs[0]
+			assert.Exactly(t, keyTestName, userID.Name, name)
+			assert.Exactly(t, keyTestDomain, userID.Email, name)
+			assert.True(t, userID.Primary, name)
+			assert.True(t, userID.Valid, name)
+		}
+
+		info, err = key.GetKeyInfo(testTime + 2*86400)
+		if err != nil {
+			t.Fatal("Expected no error while getting key info, got:", err)
+		}
+		assert.True(t, info.Subkeys[0].Expired, name)
+		assert.False(t, info.Subkeys[0].Valid, name)
+		assert.True(t, info.Subkeys[1].Valid, name)
+
+		serialized, err := key.GetKeyInfoJson(testTime)
+		if err != nil {
+			t.Fatal("Expected no error while serializing key info, got:", err)
+		}
+		var decoded KeyInfo
+		if err := json.Unmarshal(serialized, &decoded); err != nil {
+			t.Fatal("Expected no error while parsing key info, got:", err)
+		}
+		assert.Exactly(t, info.PrimaryKey.Fingerprint, decoded.PrimaryKey.Fingerprint, name)
+	}
+}
+
+func TestKeyInfoRevoked(t *testing.T) {
+	revokedKey, err := NewKeyFromArmored(readTestFile("key_revoked", false))
+	if err != nil {
+		t.Fatal("Cannot unarmor key:", err)
+	}
+	info, err := revokedKey.GetKeyInfo(testRevokedKeyCapabilitiesTime)
+	if err != nil {
+		t.Fatal("Expected no error while getting key info, got:", err)
+	}
+	assert.False(t, info.Private)
+	assert.True(t, info.PrimaryKey.Revoked)
+	assert.False(t, info.PrimaryKey.Valid)
+	assert.False(t, info.PrimaryKey.Expired)
+	assert.Exactly(t, SecretKeyMissing, info.PrimaryKey.SecretStatus)
+
+	key, err := keyTestEC.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	subkey := &key.entity.Subkeys[0]
+	if err := subkey.Revoke(packet.KeySuperseded, "rotated", nil); err != nil {
+		t.Fatal("Expected no error while revoking subkey, got:", err)
+	}
+	info, err = key.GetKeyInfo(subkey.Revocations[0].Packet.CreationTime.Unix() + 1)
+	if err != nil {
+		t.Fatal("Expected no error while getting key info, got:", err)
+	}
+	assert.False(t, info.PrimaryKey.Revoked)
+	assert.True(t, info.Subkeys[0].Revoked)
+	assert.False(t, info.Subkeys[0].Valid)
+	assert.Exactly(t, int(packet.KeySuperseded), info.Subkeys[0].RevocationReason)
+	assert.Exactly(t, "rotated", info.Subkeys[0].RevocationReasonText)
+}
+
+func TestKeyInfoSelfSignatureAtTime(t *testing.T) {
+	for name, pgp := range map[string]*PGPHandle{"v4": testPGP, "v6": PGPWithProfile(profile.RFC9580())} {
+		key, err := pgp.KeyGeneration().
+			AddUserId(keyTestName, keyTestDomain).
+			GenerationTime(testTime).
+			New().
+			GenerateKey()
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		// The new self-signatures are created two hours later
+		updater := *pgp
+		updater.defaultTime = NewConstantClock(testTime + 7200)
+		updated, err := updater.SetKeyPreferences(key, &KeyPreferences{Ciphers: []byte{byte(packet.CipherAES128)}})
+		if err != nil {
+			t.Fatal("Expected no error while setting preferences, got:", err)
+		}
+
+		info, err := updated.GetKeyInfo(testTime + 3600)
+		if err != nil {
+			t.Fatal("Expected no error while getting key info, got:", err)
+		}
+		previous, err := key.GetKeyInfo(testTime + 3600)
+		if err != nil {
+			t.Fatal("Expected no error while getting key info, got:", err)
+		}
+		assert.Exactly(t, previous.Preferences, info.Preferences, name)
+		assert.Exactly(t, previous.UserIDs[0].Preferences, info.UserIDs[0].Preferences, name)
+
+		info, err = updated.GetKeyInfo(testTime + 10800)
+		if err != nil {
+			t.Fatal("Expected no error while getting key info, got:", err)
+		}
+		if key.GetVersion() == 6 {
+			assert.Exactly(t, []int{int(packet.CipherAES128)}, info.Preferences.Ciphers, name)
+		} else {
+			assert.Exactly(t, []int{int(packet.CipherAES128)}, info.UserIDs[0].Preferences.Ciphers, name)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+)
+
+// KeyPreferences are the algorithm preferences of a key as OpenPGP
+// identifiers, in order of preference. Nil lists keep the current preferences
+// of the key.
+type KeyPreferences struct {
+	// Ciphers are the preferred symmetric ciphers, e.g. constants.CipherAES256.
+	Ciphers []byte
+	// Hashes are the preferred hash functions, e.g. 8 for SHA-256.
+	Hashes []byte
+	// Compression are the preferred compression algorithms, e.g. 0 for no
+	// compression.
+	Compression []byte
+	// CipherSuites are the preferred AEAD cipher suites, each given by two
+	// bytes, the cipher and the AEAD mode, e.g. []byte{9, 2} for AES-256 with
+	// OCB. The key advertises support for SEIPDv2 if the list is not empty.
+	CipherSuites []byte
+}
+
+func (preferences *KeyPreferences) algorithmPreferences() algorithmPreferences {
+	return algorithmPreferences{
+		ciphers:      slices.Clone(preferences.Ciphers),
+		hashes:       slices.Clone(preferences.Hashes),
+		compression:  slices.Clone(preferences.Compression),
+		cipherSuites: slices.Clone(preferences.CipherSuites),
+	}
+}
+
+// SetKeyPreferences returns a copy of the unlocked private key with the given
+// algorithm preferences.
+// The preferences are written into new self-signatures that replace the
+// latest self-signatures carrying the key properties, i.e. the direct-key
+// signature of v6 keys and the self-signatures of the user IDs of v4 keys.
+// Revoked user IDs are not changed.
+func (p *PGPHandle) SetKeyPreferences(key *Key, preferences *KeyPreferences) (*Key, error) {
+	if !key.IsPrivate() || key.entity.PrivateKey.Dummy() {
+		return nil, errors.New("gopenpgp: setting preferences requires the primary private key")
+	}
+	if key.entity.PrivateKey.Encrypted {
+		return nil, errors.New("gopenpgp: key is not unlocked")
+	}
+	prefs := preferences.algorithmPreferences()
+	if !prefs.isSet() {
+		return nil, errors.New("gopenpgp: no preferences to set")
+	}
+	if err := prefs.validate(); err != nil {
+		return nil, err
+	}
+
+	editedKey, err := key.Copy()
+	if err != nil {
+		return nil, err
+	}
+	config := p.profile.SignConfig()
+	config.Time = NewConstantClock(p.defaultTime().Unix())
+	update := func(sig *packet.Signature) {
+		prefs.apply(sig)
+	}
+	if err := renewSelfSignatures(editedKey.entity, config, update); err != nil {
+		return nil, err
+	}
+	return editedKey, nil
+}
+
+// renewSelfSignatures replaces the latest self-signatures that carry the key
+// properties, i.e. the direct-key signature of v6 keys and the self-signatures
+// of the user IDs of v4 keys, with new self-signatures changed by update.
+// Revoked user IDs are not changed.
+func renewSelfSignatures(entity *openpgp.Entity, config *packet.Config, update func(sig *packet.Signature)) error {
+	renewed := false
+	if directSig, err := entity.LatestValidDirectSignature(time.Time{}, config); err == nil && directSig.FlagsValid {
+		sig := newSelfSignature(directSig, config)
+		update(sig)
+		if err := sig.SignDirectKeyBinding(entity.PrimaryKey, entity.PrivateKey, config); err != nil {
+			return fmt.Errorf("gopenpgp: error in signing self-signature: %w", err)
+		}
+		entity.DirectSignatures = append(entity.DirectSignatures, packet.NewVerifiableSig(sig))
+		renewed = true
+	}
+	for _, ident := range entity.Identities {
+		selfCertification, err := ident.LatestValidSelfCertification(time.Time{}, config)
+		if err != nil || !selfCertification.FlagsValid || ident.Revoked(selfCertification, time.Time{}, config) {
+			// A newer self-signature would take precedence over a revocation
+			continue
+		}
+		sig := newSelfSignature(selfCertification, config)
+		update(sig)
+		if err := sig.SignUserId(ident.Name, entity.PrimaryKey, entity.PrivateKey, config); err != nil {
+			return fmt.Errorf("gopenpgp: error in signing self-signature: %w", err)
+		}
+		ident.SelfCertifications = append(ident.SelfCertifications, packet.NewVerifiableSig(sig))
+		renewed = true
+	}
+	if !renewed {
+		return errors.New("gopenpgp: key has no self-signature to update")
+	}
+	return nil
+}
+
+// newSelfSignature returns an unsigned copy of the self-signature with the
+// key properties, which is created at the time of the config.
+func newSelfSignature(sig *packet.Signature, config *packet.Config) *packet.Signature {
+	newSig := &packet.Signature{
+		Version:                   sig.Version,
+		SigType:                   sig.SigType,
+		PubKeyAlgo:                sig.PubKeyAlgo,
+		Hash:                      config.Hash(),
+		CreationTime:              config.Now(),
+		IssuerKeyId:               sig.IssuerKeyId,
+		IssuerFingerprint:         sig.IssuerFingerprint,
+		SigLifetimeSecs:           sig.SigLifetimeSecs,
+		KeyLifetimeSecs:           sig.KeyLifetimeSecs,
+		PreferredSymmetric:        sig.PreferredSymmetric,
+		PreferredHash:             sig.PreferredHash,
+		PreferredCompression:      sig.PreferredCompression,
+		PreferredCipherSuites:     sig.PreferredCipherSuites,
+		IsPrimaryId:               sig.IsPrimaryId,
+		KeyserverPrefsValid:       sig.KeyserverPrefsValid,
+		KeyserverPrefNoModify:     sig.KeyserverPrefNoModify,
+		PreferredKeyserver:        sig.PreferredKeyserver,
+		PolicyURI:                 sig.PolicyURI,
+		FlagsValid:                sig.FlagsValid,
+		FlagCertify:               sig.FlagCertify,
+		FlagSign:                  sig.FlagSign,
+		FlagEncryptCommunications: sig.FlagEncryptCommunications,
+		FlagEncryptStorage:        sig.FlagEncryptStorage,
+		FlagSplitKey:              sig.FlagSplitKey,
+		FlagAuthenticate:          sig.FlagAuthenticate,
+		FlagForward:               sig.FlagForward,
+		FlagGroupKey:              sig.FlagGroupKey,
+		SEIPDv1:                   sig.SEIPDv1,
+		SEIPDv2:                   sig.SEIPDv2,
+	}
+	for _, notation := range sig.Notations {
+		if notation.Name != packet.SaltNotationName {
+			newSig.Notations = append(newSig.Notations, notation)
+		}
+	}
+	return newSig
+}
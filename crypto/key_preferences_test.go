@@ -0,0 +1,76 @@
+package crypto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+func TestSetKeyPreferences(t *testing.T) {
+	for name, pgp := range map[string]*PGPHandle{"v4": testPGP, "v6": PGPWithProfile(profile.RFC9580())} {
+		key, err := pgp.KeyGeneration().AddUserId(keyTestName, keyTestDomain).GenerationTime(testTime).New().GenerateKey()
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		editedKey, err := pgp.SetKeyPreferences(key, &KeyPreferences{
+			Ciphers:      []byte{byte(packet.CipherAES128)},
+			Hashes:       []byte{10},
+			CipherSuites: []byte{byte(packet.CipherAES128), byte(packet.AEADModeOCB)},
+		})
+		if err != nil {
+			t.Fatal("Expected no error while setting preferences, got:", err)
+		}
+		now := time.Now().Unix()
+		info, err := editedKey.GetKeyInfo(now)
+		if err != nil {
+			t.Fatal("Expected no error while getting key info, got:", err)
+		}
+		assert.True(t, info.PrimaryKey.Valid, name)
+		if assert.NotNil(t, info.Preferences, name) {
+			assert.Exactly(t, []int{int(packet.CipherAES128)}, info.Preferences.Ciphers, name)
+			assert.Exactly(t, []int{10}, info.Preferences.Hashes, name)
+			assert.Exactly(t, [][2]int{{int(packet.CipherAES128), int(packet.AEADModeOCB)}}, info.Preferences.CipherSuites, name)
+			assert.True(t, info.Preferences.SEIPDv2, name)
+		}
+		originalInfo, err := key.GetKeyInfo(now)
+		if err != nil {
+			t.Fatal("Expected no error while getting key info, got:", err)
+		}
+		assert.Exactly(t, originalInfo.Preferences.Compression, info.Preferences.Compression, name)
+		assert.Exactly(t, originalInfo.PrimaryKey.CanSign, info.PrimaryKey.CanSign, name)
+
+		publicKey, err := editedKey.ToPublic()
+		if err != nil {
+			t.Fatal("Expected no error while getting public key, got:", err)
+		}
+		assert.True(t, publicKey.CanEncrypt(now), name)
+	}
+}
+
+func TestSetKeyPreferencesErrors(t *testing.T) {
+	preferences := &KeyPreferences{Ciphers: []byte{byte(packet.CipherAES256)}}
+	publicKey, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	_, err = testPGP.SetKeyPreferences(publicKey, preferences)
+	assert.Error(t, err)
+
+	lockedKey, err := testPGP.LockKey(keyTestEC, keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	_, err = testPGP.SetKeyPreferences(lockedKey, preferences)
+	assert.Error(t, err)
+
+	_, err = testPGP.SetKeyPreferences(keyTestEC, &KeyPreferences{})
+	assert.Error(t, err)
+	_, err = testPGP.SetKeyPreferences(keyTestEC, &KeyPreferences{Hashes: []byte{4}})
+	assert.Error(t, err)
+	_, err = testPGP.SetKeyPreferences(keyTestEC, &KeyPreferences{CipherSuites: []byte{byte(packet.CipherAES256)}})
+	assert.Error(t, err)
+}
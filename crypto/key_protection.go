@@ -0,0 +1,190 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+)
+
+// KeyProtection describes how the secret key material of the primary key or
+// a subkey is protected.
+// The protection details are only set for locked keys.
+type KeyProtection struct {
+	KeyID   uint64 `json:"keyId"`
+	Primary bool   `json:"primary"`
+	// Status is one of the SecretKey constants.
+	Status int8 `json:"status"`
+	// S2KUsage is 253 for AEAD protection and 254 or 255 for CFB protection.
+	S2KUsage uint8 `json:"s2kUsage"`
+	// Cipher is the symmetric cipher, see packet.CipherFunction.
+	Cipher uint8 `json:"cipher"`
+	// AEADMode is the AEAD mode of AEAD protection, see packet.AEADMode.
+	AEADMode uint8 `json:"aeadMode"`
+	// S2KMode is the string-to-key function, see s2k.Mode.
+	S2KMode uint8 `json:"s2kMode"`
+	// S2KHash is the hash of simple, salted and iterated S2K functions.
+	S2KHash uint8 `json:"s2kHash"`
+}
+
+// IsLegacy returns true if the key is not protected with Argon2 and AEAD,
+// as recommended by RFC 9580.
+func (protection *KeyProtection) IsLegacy() bool {
+	return protection.Status == SecretKeyLocked &&
+		(protection.S2KUsage != uint8(packet.S2KAEAD) || protection.S2KMode != uint8(s2k.Argon2S2K))
+}
+
+// GetKeyProtection reports the protection of the secret key material of the
+// primary key and the subkeys, in key order.
+func (key *Key) GetKeyProtection() ([]KeyProtection, error) {
+	if !key.IsPrivate() {
+		return nil, errors.New("gopenpgp: a public key has no key protection")
+	}
+	var protections []KeyProtection
+	for _, status := range key.GetSecretKeyStatus() {
+		protection := KeyProtection{KeyID: status.KeyID, Primary: status.Primary, Status: status.Status}
+		if status.Status == SecretKeyLocked {
+			if err := protection.parse(key.privateKeyByID(status.KeyID, status.Primary)); err != nil {
+				return nil, err
+			}
+		}
+		protections = append(protections, protection)
+	}
+	return protections, nil
+}
+
+func (key *Key) privateKeyByID(keyID uint64, primary bool) *packet.PrivateKey {
+	if primary {
+		return key.entity.PrivateKey
+	}
+	for _, sub := range key.entity.Subkeys {
+		if sub.PublicKey.KeyId == keyID {
+			return sub.PrivateKey
+		}
+	}
+	return nil
+}
+
+// parse reads the protection from the serialized secret key packet, as
+// go-crypto does not export it.
+func (protection *KeyProtection) parse(privateKey *packet.PrivateKey) error {
+	var publicKey, serialized bytes.Buffer
+	if err := privateKey.PublicKey.SerializeForHash(&publicKey); err != nil {
+		return fmt.Errorf("gopenpgp: error in serializing key: %w", err)
+	}
+	if err := privateKey.Serialize(&serialized); err != nil {
+		return fmt.Errorf("gopenpgp: error in serializing key: %w", err)
+	}
+	// Skip the packet header and the public key, which is prefixed with
+	// 0x99 and a 2-byte length or 0x9b and a 4-byte length when hashed
+	data := serialized.Bytes()[1:]
+	switch {
+	case data[0] < 192:
+		data = data[1:]
+	case data[0] < 224:
+		data = data[2:]
+	default:
+		data = data[5:]
+	}
+	prefixLength := 3
+	if privateKey.Version == 6 {
+		prefixLength = 5
+	}
+	secret := data[publicKey.Len()-prefixLength:]
+
+	protection.S2KUsage = secret[0]
+	secret = secret[1:]
+	if privateKey.Version == 6 {
+		// Length of the following protection fields
+		secret = secret[1:]
+	}
+	switch packet.S2KType(protection.S2KUsage) {
+	case packet.S2KNON:
+		return nil
+	case packet.S2KAEAD, packet.S2KSHA1, packet.S2KCHECKSUM:
+	default:
+		// Legacy protection with the cipher as S2K usage and simple S2K with MD5
+		protection.Cipher = protection.S2KUsage
+		return nil
+	}
+	protection.Cipher, secret = secret[0], secret[1:]
+	if packet.S2KType(protection.S2KUsage) == packet.S2KAEAD {
+		protection.AEADMode, secret = secret[0], secret[1:]
+	}
+	if privateKey.Version == 6 {
+		// Length of the S2K specifier
+		secret = secret[1:]
+	}
+	protection.S2KMode = secret[0]
+	switch s2k.Mode(protection.S2KMode) {
+	case s2k.SimpleS2K, s2k.SaltedS2K, s2k.IteratedSaltedS2K:
+		protection.S2KHash = secret[1]
+	}
+	return nil
+}
+
+// ReprotectKeys returns copies of the private keys, which are unlocked with
+// the old passphrases and locked with the new passphrase and the key
+// encryption settings of the profile, e.g. Argon2 and AEAD with
+// profile.RFC9580.
+// Each secret key packet is unlocked with the first old passphrase that
+// matches, thus the primary key and subkeys may use different passphrases.
+// Unlocked keys are locked as well.
+// If a key cannot be reprotected, an error is returned and no copy is kept.
+func (p *PGPHandle) ReprotectKeys(keys []*Key, oldPassphrases [][]byte, newPassphrase []byte) ([]*Key, error) {
+	if len(newPassphrase) == 0 {
+		return nil, errors.New("gopenpgp: reprotecting keys requires a new passphrase")
+	}
+	reprotectedKeys := make([]*Key, 0, len(keys))
+	rollback := func() {
+		for _, reprotectedKey := range reprotectedKeys {
+			reprotectedKey.ClearPrivateParams()
+		}
+	}
+	for _, key := range keys {
+		reprotectedKey, err := key.reprotect(oldPassphrases, newPassphrase, p.profile)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		reprotectedKeys = append(reprotectedKeys, reprotectedKey)
+	}
+	return reprotectedKeys, nil
+}
+
+func (key *Key) reprotect(oldPassphrases [][]byte, newPassphrase []byte, profile KeyEncryptionProfile) (*Key, error) {
+	if !key.IsPrivate() {
+		return nil, errors.New("gopenpgp: a public key cannot be reprotected")
+	}
+	reprotectedKey, err := key.Copy()
+	if err != nil {
+		return nil, err
+	}
+	privateKeys := []*packet.PrivateKey{reprotectedKey.entity.PrivateKey}
+	for _, sub := range reprotectedKey.entity.Subkeys {
+		privateKeys = append(privateKeys, sub.PrivateKey)
+	}
+	for _, privateKey := range privateKeys {
+		if privateKey == nil || privateKey.Dummy() || !privateKey.Encrypted {
+			continue
+		}
+		for _, passphrase := range oldPassphrases {
+			if privateKey.Decrypt(passphrase) == nil {
+				break
+			}
+		}
+		if privateKey.Encrypted {
+			reprotectedKey.ClearPrivateParams()
+			return nil, fmt.Errorf("gopenpgp: no passphrase unlocks key %016x", privateKey.KeyId)
+		}
+	}
+
+	err = reprotectedKey.entity.EncryptPrivateKeys(newPassphrase, profile.KeyEncryptionConfig())
+	if err != nil {
+		reprotectedKey.ClearPrivateParams()
+		return nil, fmt.Errorf("gopenpgp: error in locking key: %w", err)
+	}
+	return reprotectedKey, nil
+}
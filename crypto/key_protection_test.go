@@ -0,0 +1,111 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+// lockKeyPerPacket locks the primary key and the subkeys of a copy of the
+// key with different passphrases with RFC 4880 key protection.
+func lockKeyPerPacket(t *testing.T, key *Key, passphrases ...[]byte) *Key {
+	lockedKey, err := key.Copy()
+	if err != nil {
+		t.Fatal("Expected no error while copying key, got:", err)
+	}
+	config := profile.RFC4880().KeyEncryptionConfig()
+	privateKeys := []*packet.PrivateKey{lockedKey.entity.PrivateKey}
+	for _, sub := range lockedKey.entity.Subkeys {
+		privateKeys = append(privateKeys, sub.PrivateKey)
+	}
+	for i, privateKey := range privateKeys {
+		if err := privateKey.EncryptWithConfig(passphrases[i%len(passphrases)], config); err != nil {
+			t.Fatal("Expected no error while locking key, got:", err)
+		}
+	}
+	return lockedKey
+}
+
+func TestReprotectKeys(t *testing.T) {
+	lockedEC := lockKeyPerPacket(t, keyTestEC, []byte("primary"), []byte("subkey"))
+	lockedRSA := lockKeyPerPacket(t, keyTestRSA, []byte("primary"))
+
+	protections, err := lockedEC.GetKeyProtection()
+	if err != nil {
+		t.Fatal("Expected no error while reading key protection, got:", err)
+	}
+	if assert.Len(t, protections, 2) {
+		assert.True(t, protections[0].Primary)
+		assert.Exactly(t, SecretKeyLocked, protections[0].Status)
+		assert.Exactly(t, uint8(packet.S2KSHA1), protections[0].S2KUsage)
+		assert.Exactly(t, uint8(s2k.IteratedSaltedS2K), protections[0].S2KMode)
+		assert.Exactly(t, uint8(packet.CipherAES128), protections[0].Cipher)
+		assert.True(t, protections[1].IsLegacy())
+	}
+
+	rfc9580 := PGPWithProfile(profile.RFC9580())
+	reprotected, err := rfc9580.ReprotectKeys(
+		[]*Key{lockedEC, lockedRSA},
+		[][]byte{[]byte("subkey"), []byte("primary")},
+		[]byte("new passphrase"),
+	)
+	if err != nil {
+		t.Fatal("Expected no error while reprotecting keys, got:", err)
+	}
+	for _, key := range reprotected {
+		armored, err := key.Armor()
+		if err != nil {
+			t.Fatal("Expected no error while armoring key, got:", err)
+		}
+		key, err = NewKeyFromArmored(armored)
+		if err != nil {
+			t.Fatal("Expected no error while reading key, got:", err)
+		}
+		protections, err := key.GetKeyProtection()
+		if err != nil {
+			t.Fatal("Expected no error while reading key protection, got:", err)
+		}
+		for _, protection := range protections {
+			assert.Exactly(t, uint8(packet.S2KAEAD), protection.S2KUsage)
+			assert.Exactly(t, uint8(s2k.Argon2S2K), protection.S2KMode)
+			assert.False(t, protection.IsLegacy())
+		}
+		if _, err := key.Unlock([]byte("new passphrase")); err != nil {
+			t.Fatal("Expected no error while unlocking reprotected key, got:", err)
+		}
+	}
+
+	// The inputs are not changed
+	protections, err = lockedEC.GetKeyProtection()
+	if err != nil {
+		t.Fatal("Expected no error while reading key protection, got:", err)
+	}
+	assert.Exactly(t, uint8(packet.S2KSHA1), protections[1].S2KUsage)
+}
+
+func TestReprotectKeysRollback(t *testing.T) {
+	lockedEC := lockKeyPerPacket(t, keyTestEC, []byte("primary"), []byte("subkey"))
+	lockedRSA := lockKeyPerPacket(t, keyTestRSA, []byte("primary"))
+
+	// The subkey of the second key cannot be unlocked
+	reprotected, err := testPGP.ReprotectKeys([]*Key{lockedRSA, lockedEC}, [][]byte{[]byte("primary")}, []byte("new"))
+	assert.Error(t, err)
+	assert.Nil(t, reprotected)
+	_, err = testPGP.ReprotectKeys([]*Key{lockedRSA}, [][]byte{[]byte("primary")}, nil)
+	assert.Error(t, err)
+
+	unlocked, err := lockedRSA.Unlock([]byte("primary"))
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	protections, err := unlocked.GetKeyProtection()
+	if err != nil {
+		t.Fatal("Expected no error while reading key protection, got:", err)
+	}
+	assert.Exactly(t, SecretKeyUnlocked, protections[0].Status)
+	assert.False(t, protections[0].IsLegacy())
+}
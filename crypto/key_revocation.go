@@ -0,0 +1,335 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"time"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// Revocation key signature subpacket, see RFC 4880, section 5.2.3.15.
+// It is not supported by go-crypto and is thus handled here.
+const (
+	revocationKeySubpacket = 12
+	// revocationKeyClass is the class of a revocation key, 0x80 must be set.
+	revocationKeyClass = 0x80
+)
+
+// AddDesignatedRevoker returns a copy of the unlocked private key, which
+// designates the primary key of the revoker as revoker of the key.
+// The revoker is added with a new direct-key self-signature and can then
+// revoke the key with RevokeAsDesignatedRevoker.
+// Designated revokers are deprecated by RFC 9580 and only supported for v4
+// keys, as used by GnuPG.
+func (p *PGPHandle) AddDesignatedRevoker(key, revoker *Key) (*Key, error) {
+	if !key.IsPrivate() || key.entity.PrivateKey.Dummy() {
+		return nil, errors.New("gopenpgp: adding a designated revoker requires the primary private key")
+	}
+	if key.entity.PrivateKey.Encrypted {
+		return nil, errors.New("gopenpgp: key is not unlocked")
+	}
+	primaryKey, revokerKey := key.entity.PrimaryKey, revoker.entity.PrimaryKey
+	if primaryKey.Version != 4 || revokerKey.Version != 4 {
+		return nil, errors.New("gopenpgp: designated revokers are only supported for v4 keys")
+	}
+	if bytes.Equal(primaryKey.Fingerprint, revokerKey.Fingerprint) {
+		return nil, errors.New("gopenpgp: a key cannot be its own designated revoker")
+	}
+
+	editedKey, err := key.Copy()
+	if err != nil {
+		return nil, err
+	}
+	entity := editedKey.entity
+	config := p.profile.SignConfig()
+	config.Time = NewConstantClock(p.defaultTime().Unix())
+	sig := &packet.Signature{
+		Version:           entity.PrimaryKey.Version,
+		SigType:           packet.SigTypeDirectSignature,
+		PubKeyAlgo:        entity.PrimaryKey.PubKeyAlgo,
+		Hash:              config.Hash(),
+		CreationTime:      config.Now(),
+		IssuerKeyId:       &entity.PrimaryKey.KeyId,
+		IssuerFingerprint: entity.PrimaryKey.Fingerprint,
+	}
+	prepareHash, err := sig.PrepareSign(config)
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in signing designated revoker: %w", err)
+	}
+	if err := entity.PrimaryKey.SerializeForHash(prepareHash); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in signing designated revoker: %w", err)
+	}
+	subpacketHash := &hashedSubpacketHash{
+		Hash:          prepareHash,
+		subpacketType: revocationKeySubpacket,
+		subpacket:     append([]byte{revocationKeyClass, byte(revokerKey.PubKeyAlgo)}, revokerKey.Fingerprint...),
+	}
+	if err := sig.Sign(subpacketHash, entity.PrivateKey, config); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in signing designated revoker: %w", err)
+	}
+	directSig, err := subpacketHash.signature(sig)
+	if err != nil {
+		return nil, err
+	}
+	if err := entity.PrimaryKey.VerifyDirectKeySignature(directSig); err != nil {
+		return nil, fmt.Errorf("gopenpgp: invalid designated revoker signature: %w", err)
+	}
+	entity.DirectSignatures = append(entity.DirectSignatures, packet.NewVerifiableSig(directSig))
+	return editedKey, nil
+}
+
+// RevokeAsDesignatedRevoker returns a copy of the key with a key revocation
+// signature, which is made by the unlocked primary key of the revoker.
+// The revoker must be a designated revoker of the key, see
+// AddDesignatedRevoker. The key may be a public key.
+func (p *PGPHandle) RevokeAsDesignatedRevoker(
+	key, revoker *Key,
+	reason packet.ReasonForRevocation,
+	reasonText string,
+) (*Key, error) {
+	if !revoker.IsPrivate() || revoker.entity.PrivateKey.Dummy() {
+		return nil, errors.New("gopenpgp: a designated revocation requires the primary private key of the revoker")
+	}
+	if revoker.entity.PrivateKey.Encrypted {
+		return nil, errors.New("gopenpgp: revoker key is not unlocked")
+	}
+	if !key.isDesignatedRevoker(revoker.entity.PrimaryKey) {
+		return nil, errors.New("gopenpgp: revoker is not a designated revoker of the key")
+	}
+
+	revokedKey, err := key.Copy()
+	if err != nil {
+		return nil, err
+	}
+	revokerKey := revoker.entity.PrimaryKey
+	config := p.profile.SignConfig()
+	config.Time = NewConstantClock(p.defaultTime().Unix())
+	sig := &packet.Signature{
+		Version:              revokerKey.Version,
+		SigType:              packet.SigTypeKeyRevocation,
+		PubKeyAlgo:           revokerKey.PubKeyAlgo,
+		Hash:                 config.Hash(),
+		CreationTime:         config.Now(),
+		IssuerKeyId:          &revokerKey.KeyId,
+		IssuerFingerprint:    revokerKey.Fingerprint,
+		RevocationReason:     &reason,
+		RevocationReasonText: reasonText,
+	}
+	if err := sig.RevokeKey(revokedKey.entity.PrimaryKey, revoker.entity.PrivateKey, config); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in signing designated revocation: %w", err)
+	}
+	revokedKey.entity.Revocations = append(revokedKey.entity.Revocations, packet.NewVerifiableSig(sig))
+	return revokedKey, nil
+}
+
+// GetDesignatedRevokers returns the hex fingerprints of the designated
+// revokers of the key, as listed in its valid direct-key self-signatures.
+func (key *Key) GetDesignatedRevokers() []string {
+	var fingerprints []string
+	for _, fingerprint := range key.designatedRevokers() {
+		fingerprints = append(fingerprints, hex.EncodeToString(fingerprint))
+	}
+	return fingerprints
+}
+
+func (key *Key) designatedRevokers() (fingerprints [][]byte) {
+	for _, directSig := range key.entity.DirectSignatures {
+		if key.entity.PrimaryKey.VerifyDirectKeySignature(directSig.Packet) != nil {
+			continue
+		}
+		for _, subpacket := range hashedSubpackets(directSig.Packet, revocationKeySubpacket) {
+			// class, algorithm and a v4 fingerprint
+			if len(subpacket) != 22 || subpacket[0]&revocationKeyClass == 0 {
+				continue
+			}
+			fingerprints = append(fingerprints, subpacket[2:])
+		}
+	}
+	return fingerprints
+}
+
+func (key *Key) isDesignatedRevoker(revokerKey *packet.PublicKey) bool {
+	for _, fingerprint := range key.designatedRevokers() {
+		if bytes.Equal(fingerprint, revokerKey.Fingerprint) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRevokedByDesignatedRevoker checks whether one of the given revoker keys
+// made a valid revocation signature of the key as designated revoker.
+func (key *Key) isRevokedByDesignatedRevoker(now time.Time, revokers []*Key) bool {
+	for _, revocation := range key.entity.Revocations {
+		sig := revocation.Packet
+		for _, revoker := range revokers {
+			revokerKey := revoker.entity.PrimaryKey
+			if !sig.CheckKeyIdOrFingerprint(revokerKey) || !key.isDesignatedRevoker(revokerKey) {
+				continue
+			}
+			preparedHash, err := sig.PrepareVerify()
+			if err != nil {
+				continue
+			}
+			if key.entity.PrimaryKey.SerializeForHash(preparedHash) != nil ||
+				revokerKey.VerifySignature(preparedHash, sig) != nil {
+				continue
+			}
+			// As for self-revocations, a compromised key is revoked at any time
+			if sig.RevocationReason == nil ||
+				*sig.RevocationReason == packet.Unknown ||
+				*sig.RevocationReason == packet.NoReason ||
+				*sig.RevocationReason == packet.KeyCompromised ||
+				!sig.SigExpired(now) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hashedSubpackets returns the bodies of the hashed subpackets of the given
+// type, which are parsed from the hash suffix of the signature.
+func hashedSubpackets(sig *packet.Signature, subpacketType byte) (subpackets [][]byte) {
+	hashed, _, _ := splitHashSuffix(sig.HashSuffix)
+	for len(hashed) > 0 {
+		var length int
+		switch {
+		case hashed[0] < 192:
+			length, hashed = int(hashed[0]), hashed[1:]
+		case hashed[0] < 255 && len(hashed) >= 2:
+			length, hashed = (int(hashed[0])-192)<<8+int(hashed[1])+192, hashed[2:]
+		case len(hashed) >= 5:
+			length, hashed = int(binary.BigEndian.Uint32(hashed[1:5])), hashed[5:]
+		default:
+			return subpackets
+		}
+		if length == 0 || length > len(hashed) {
+			return subpackets
+		}
+		if hashed[0]&0x7f == subpacketType {
+			subpackets = append(subpackets, hashed[1:length])
+		}
+		hashed = hashed[length:]
+	}
+	return subpackets
+}
+
+// splitHashSuffix splits a v4 or v6 signature hash suffix into the hashed
+// subpackets and the preceding fields and returns the size of the length.
+func splitHashSuffix(suffix []byte) (hashed, fields []byte, lengthSize int) {
+	lengthSize = 2
+	if len(suffix) > 0 && suffix[0] == 6 {
+		lengthSize = 4
+	}
+	if len(suffix) < 4+lengthSize {
+		return nil, nil, lengthSize
+	}
+	length := 0
+	for _, b := range suffix[4 : 4+lengthSize] {
+		length = length<<8 | int(b)
+	}
+	if len(suffix) < 4+lengthSize+length {
+		return nil, nil, lengthSize
+	}
+	return suffix[4+lengthSize : 4+lengthSize+length], suffix[:4], lengthSize
+}
+
+// hashedSubpacketHash adds a hashed subpacket to a signature that is signed
+// by go-crypto, which cannot serialize revocation key subpackets. It buffers
+// the hash suffix that go-crypto writes after the signed data, in one or
+// more calls, and rewrites it with the subpacket when the hash is summed.
+// This relies on how go-crypto hashes the suffix, which is pinned by
+// TestDesignatedRevokerSignatureEncoding, and the signature is verified
+// after signing.
+// The subpacket body must be shorter than 191 bytes.
+type hashedSubpacketHash struct {
+	hash.Hash
+	subpacketType byte
+	subpacket     []byte
+	// written is the hash suffix written by go-crypto.
+	written []byte
+	// suffix is the rewritten hash suffix, set once the hash is summed.
+	suffix []byte
+}
+
+// signature returns the signature with the added subpacket.
+func (h *hashedSubpacketHash) signature(sig *packet.Signature) (*packet.Signature, error) {
+	if h.suffix == nil {
+		return nil, errors.New("gopenpgp: invalid signature hash suffix")
+	}
+	// go-crypto serializes the hashed subpackets of the hash suffix, but
+	// computes the packet length from its own subpackets
+	sig.HashSuffix = h.suffix
+	var serialized bytes.Buffer
+	if err := sig.Serialize(&serialized); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in serializing signature: %w", err)
+	}
+	body := serialized.Bytes()[1:]
+	switch {
+	case body[0] < 192:
+		body = body[1:]
+	case body[0] < 224:
+		body = body[2:]
+	default:
+		body = body[5:]
+	}
+	packetData := []byte{0xc0 | 2, 0xff}
+	packetData = binary.BigEndian.AppendUint32(packetData, uint32(len(body)))
+	parsed, err := packet.Read(bytes.NewReader(append(packetData, body...)))
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in reading signature: %w", err)
+	}
+	parsedSig, ok := parsed.(*packet.Signature)
+	if !ok {
+		return nil, errors.New("gopenpgp: error in reading signature")
+	}
+	return parsedSig, nil
+}
+
+func (h *hashedSubpacketHash) Write(p []byte) (int, error) {
+	if h.suffix != nil {
+		return 0, errors.New("gopenpgp: unexpected write after the hash suffix")
+	}
+	h.written = append(h.written, p...)
+	return len(p), nil
+}
+
+// Sum hashes the rewritten suffix and returns the digest. If the written
+// suffix is invalid, the suffix is not set and signature fails.
+func (h *hashedSubpacketHash) Sum(b []byte) []byte {
+	if h.suffix == nil {
+		if suffix := h.rewriteSuffix(); suffix != nil {
+			_, _ = h.Hash.Write(suffix)
+			h.suffix = suffix
+		}
+	}
+	return h.Hash.Sum(b)
+}
+
+// rewriteSuffix returns the written hash suffix with the added subpacket,
+// or nil if the written suffix is invalid.
+func (h *hashedSubpacketHash) rewriteSuffix() []byte {
+	hashed, fields, lengthSize := splitHashSuffix(h.written)
+	// The hashed subpackets are followed by the trailer of version, 0xff,
+	// and a four-octet length
+	if fields == nil || len(h.written) != len(fields)+lengthSize+len(hashed)+6 {
+		return nil
+	}
+	hashed = append(append([]byte{}, hashed...), byte(len(h.subpacket)+1), h.subpacketType)
+	hashed = append(hashed, h.subpacket...)
+
+	suffix := append([]byte{}, fields...)
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(hashed)))
+	suffix = append(suffix, length[4-lengthSize:]...)
+	suffix = append(suffix, hashed...)
+	binary.BigEndian.PutUint32(length, uint32(len(fields)+lengthSize+len(hashed)))
+	suffix = append(suffix, fields[0], 0xff)
+	return append(suffix, length...)
+}
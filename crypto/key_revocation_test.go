@@ -0,0 +1,201 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+func TestDesignatedRevoker(t *testing.T) {
+	revoker, err := testPGP.KeyGeneration().AddUserId("security", "security@example.com").New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating revoker key, got:", err)
+	}
+	key, err := testPGP.AddDesignatedRevoker(keyTestEC, revoker)
+	if err != nil {
+		t.Fatal("Expected no error while adding designated revoker, got:", err)
+	}
+	armored, err := key.GetArmoredPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while exporting public key, got:", err)
+	}
+	publicKey, err := NewKeyFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while reading public key, got:", err)
+	}
+	assert.Exactly(t, []string{revoker.GetFingerprint()}, publicKey.GetDesignatedRevokers())
+	assert.Empty(t, keyTestEC.GetDesignatedRevokers())
+	assert.True(t, publicKey.CanEncrypt(testTime))
+
+	revokedKey, err := testPGP.RevokeAsDesignatedRevoker(publicKey, revoker, packet.KeyCompromised, "lost laptop")
+	if err != nil {
+		t.Fatal("Expected no error while revoking key, got:", err)
+	}
+	armored, err = revokedKey.Armor()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	revokedKey, err = NewKeyFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while reading revoked key, got:", err)
+	}
+	assert.False(t, revokedKey.IsRevoked(testTime))
+	assert.False(t, revokedKey.IsRevokedBy(testTime, nil))
+	otherKeyRing, err := NewKeyRing(keyTestRSA)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.False(t, revokedKey.IsRevokedBy(testTime, otherKeyRing))
+	if err := otherKeyRing.AddKey(revoker); err != nil {
+		t.Fatal("Expected no error while adding key, got:", err)
+	}
+	assert.True(t, revokedKey.IsRevokedBy(testTime, otherKeyRing))
+
+	// The keyring has to contain the revoker, which cannot encrypt itself
+	keyRing, err := NewKeyRing(revokedKey)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	assert.True(t, keyRing.CanEncrypt(testTime))
+	revokerPublic, err := revoker.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	revokerPublic.entity.Subkeys = nil
+	if err := keyRing.AddKey(revokerPublic); err != nil {
+		t.Fatal("Expected no error while adding revoker to keyring, got:", err)
+	}
+	assert.False(t, keyRing.CanEncrypt(testTime))
+}
+
+func TestDesignatedRevokerSignatureEncoding(t *testing.T) {
+	revoker, err := testPGP.KeyGeneration().AddUserId("security", "security@example.com").New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating revoker key, got:", err)
+	}
+	key, err := testPGP.AddDesignatedRevoker(keyTestEC, revoker)
+	if err != nil {
+		t.Fatal("Expected no error while adding designated revoker, got:", err)
+	}
+	directSigs := key.entity.DirectSignatures
+	directSig := directSigs[len(directSigs)-1].Packet
+	var serialized bytes.Buffer
+	if err := directSig.Serialize(&serialized); err != nil {
+		t.Fatal("Expected no error while serializing signature, got:", err)
+	}
+
+	// Parse the v4 signature packet independently of go-crypto
+	data := serialized.Bytes()
+	assert.Exactly(t, byte(0xc2), data[0])
+	var body []byte
+	switch {
+	case data[1] < 192:
+		body = data[2:]
+	case data[1] < 224:
+		body = data[3:]
+	default:
+		body = data[6:]
+	}
+	assert.Exactly(t, []byte{4, byte(packet.SigTypeDirectSignature)}, body[:2])
+	hashedLength := int(binary.BigEndian.Uint16(body[4:6]))
+	hashedEnd := 6 + hashedLength
+	subpackets := map[byte][]byte{}
+	for hashed := body[6:hashedEnd]; len(hashed) > 0; {
+		length := int(hashed[0])
+		if length >= 192 || length > len(hashed)-1 {
+			t.Fatal("Expected short hashed subpackets, got length:", length)
+		}
+		subpackets[hashed[1]&0x7f] = hashed[2 : 1+length]
+		hashed = hashed[1+length:]
+	}
+	revokerKey := revoker.entity.PrimaryKey
+	assert.Exactly(t, append([]byte{revocationKeyClass, byte(revokerKey.PubKeyAlgo)}, revokerKey.Fingerprint...), subpackets[revocationKeySubpacket])
+	assert.Contains(t, subpackets, byte(2))  // signature creation time
+	assert.Contains(t, subpackets, byte(33)) // issuer fingerprint
+	unhashedLength := int(binary.BigEndian.Uint16(body[hashedEnd : hashedEnd+2]))
+	hashTag := body[hashedEnd+2+unhashedLength : hashedEnd+4+unhashedLength]
+
+	// The signature must be computed over the serialized hashed area,
+	// see RFC 4880, section 5.2.4
+	digest := directSig.Hash.New()
+	if err := key.entity.PrimaryKey.SerializeForHash(digest); err != nil {
+		t.Fatal("Expected no error while hashing key, got:", err)
+	}
+	digest.Write(body[:hashedEnd])
+	digest.Write([]byte{4, 0xff})
+	digest.Write(binary.BigEndian.AppendUint32(nil, uint32(hashedEnd)))
+	assert.Exactly(t, hashTag, digest.Sum(nil)[:2])
+
+	parsed, err := packet.Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("Expected no error while reading signature, got:", err)
+	}
+	parsedSig, ok := parsed.(*packet.Signature)
+	if !ok {
+		t.Fatal("Expected a signature packet")
+	}
+	assert.NoError(t, key.entity.PrimaryKey.VerifyDirectKeySignature(parsedSig))
+}
+
+func TestDesignatedRevokerErrors(t *testing.T) {
+	revoker, err := testPGP.KeyGeneration().AddUserId("security", "security@example.com").New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating revoker key, got:", err)
+	}
+	publicKey, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	_, err = testPGP.AddDesignatedRevoker(publicKey, revoker)
+	assert.Error(t, err)
+	_, err = testPGP.AddDesignatedRevoker(keyTestEC, keyTestEC)
+	assert.Error(t, err)
+	_, err = testPGP.RevokeAsDesignatedRevoker(publicKey, revoker, packet.KeyCompromised, "")
+	assert.Error(t, err)
+
+	v6Key, err := PGPWithProfile(profile.RFC9580()).KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	_, err = testPGP.AddDesignatedRevoker(v6Key, revoker)
+	assert.Error(t, err)
+}
+
+func TestHashedSubpacketHashSplitWrites(t *testing.T) {
+	// v4 hash suffix with a signature creation time subpacket
+	hashed := []byte{5, 2, 0x5c, 0xd9, 0x73, 0x03}
+	suffix := []byte{4, byte(packet.SigTypeDirectSignature), byte(packet.PubKeyAlgoEdDSA), 8, 0, byte(len(hashed))}
+	suffix = append(suffix, hashed...)
+	suffix = binary.BigEndian.AppendUint32(append(suffix, 4, 0xff), uint32(6+len(hashed)))
+
+	newHash := func() *hashedSubpacketHash {
+		return &hashedSubpacketHash{
+			Hash:          crypto.SHA256.New(),
+			subpacketType: revocationKeySubpacket,
+			subpacket:     []byte{revocationKeyClass, 1, 2, 3},
+		}
+	}
+	whole := newHash()
+	_, _ = whole.Write(suffix)
+	digest := whole.Sum(nil)
+	assert.NotNil(t, whole.suffix)
+
+	split := newHash()
+	for i := range suffix {
+		_, _ = split.Write(suffix[i : i+1])
+	}
+	assert.Exactly(t, digest, split.Sum(nil))
+	assert.Exactly(t, whole.suffix, split.suffix)
+
+	truncated := newHash()
+	_, _ = truncated.Write(suffix[:len(suffix)-1])
+	truncated.Sum(nil)
+	_, err := truncated.signature(&packet.Signature{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,177 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// Header and fields of key transition statements.
+const (
+	keyTransitionHeader = "OpenPGP key transition statement"
+	keyTransitionOldKey = "Old-Key: "
+	keyTransitionNewKey = "New-Key: "
+)
+
+// KeyRotation is the result of rotating a key with PGPHandle.RotateKey.
+type KeyRotation struct {
+	// OldKey is the old private key, with the new expiration if one was set.
+	OldKey *Key
+	// NewKey is the new private key, whose user IDs are certified by the old
+	// key.
+	NewKey *Key
+	// Statement is the key transition statement, a cleartext message signed
+	// by both keys, see PGPHandle.VerifyKeyRotation.
+	Statement []byte
+}
+
+// RotateKey generates the successor of the unlocked private key with the key
+// generation handle, e.g. created with the KeyGenerationBuilder.
+// The user IDs of the new key are certified by the old key, and a key
+// transition statement naming both keys is signed by both keys.
+// If oldKeyExpiration is not 0, the old key expires at this unix time, which
+// must be in the future.
+func (p *PGPHandle) RotateKey(oldKey *Key, keyGeneration PGPKeyGeneration, oldKeyExpiration int64) (*KeyRotation, error) {
+	if !oldKey.IsPrivate() || oldKey.entity.PrivateKey.Dummy() {
+		return nil, errors.New("gopenpgp: rotating a key requires the primary private key")
+	}
+	if oldKey.entity.PrivateKey.Encrypted {
+		return nil, errors.New("gopenpgp: key is not unlocked")
+	}
+	now := p.defaultTime()
+	if oldKeyExpiration != 0 && oldKeyExpiration <= now.Unix() {
+		return nil, errors.New("gopenpgp: the expiration time of the old key must be in the future")
+	}
+	newKey, err := keyGeneration.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	rotatedKey, err := oldKey.Copy()
+	if err != nil {
+		return nil, err
+	}
+
+	config := p.profile.SignConfig()
+	config.Time = NewConstantClock(now.Unix())
+	oldEntity, newEntity := rotatedKey.entity, newKey.entity
+	for _, ident := range newEntity.Identities {
+		sig := &packet.Signature{
+			Version:           oldEntity.PrimaryKey.Version,
+			SigType:           packet.SigTypeGenericCert,
+			PubKeyAlgo:        oldEntity.PrimaryKey.PubKeyAlgo,
+			Hash:              config.Hash(),
+			CreationTime:      config.Now(),
+			IssuerKeyId:       &oldEntity.PrimaryKey.KeyId,
+			IssuerFingerprint: oldEntity.PrimaryKey.Fingerprint,
+		}
+		if err := sig.SignUserId(ident.Name, newEntity.PrimaryKey, oldEntity.PrivateKey, config); err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in certifying user id: %w", err)
+		}
+		ident.OtherCertifications = append(ident.OtherCertifications, packet.NewVerifiableSig(sig))
+	}
+
+	signingKeys, err := NewKeyRing(oldKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := signingKeys.AddKey(newKey); err != nil {
+		return nil, err
+	}
+	signer, err := p.Sign().SigningKeys(signingKeys).New()
+	if err != nil {
+		return nil, err
+	}
+	statement, err := signer.SignCleartext(keyTransitionStatement(oldKey, newKey))
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in signing key transition statement: %w", err)
+	}
+
+	if oldKeyExpiration != 0 {
+		lifetime := uint32(oldKeyExpiration - oldEntity.PrimaryKey.CreationTime.Unix())
+		update := func(sig *packet.Signature) {
+			sig.KeyLifetimeSecs = &lifetime
+		}
+		if err := renewSelfSignatures(oldEntity, config, update); err != nil {
+			return nil, err
+		}
+	}
+	return &KeyRotation{
+		OldKey:    rotatedKey,
+		NewKey:    newKey,
+		Statement: statement,
+	}, nil
+}
+
+// VerifyKeyRotation verifies that the new key is the successor of the old
+// key: the key transition statement must name both keys and be signed by
+// both keys, and all user IDs of the new key must be certified by the old
+// key. The keys may be public keys.
+func (p *PGPHandle) VerifyKeyRotation(statement []byte, oldKey, newKey *Key) error {
+	verificationKeys, err := NewKeyRing(oldKey)
+	if err != nil {
+		return err
+	}
+	if err := verificationKeys.AddKey(newKey); err != nil {
+		return err
+	}
+	verifier, err := p.Verify().VerificationKeys(verificationKeys).New()
+	if err != nil {
+		return err
+	}
+	result, err := verifier.VerifyCleartext(statement)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(result.Cleartext(), keyTransitionStatement(oldKey, newKey)) {
+		return errors.New("gopenpgp: the key transition statement does not name the keys")
+	}
+	for _, key := range []*Key{oldKey, newKey} {
+		if !signedByKey(&result.VerifyResult, key) {
+			return fmt.Errorf("gopenpgp: the key transition statement is not signed by key %s", key.GetFingerprint())
+		}
+	}
+
+	oldPrimaryKey, newPrimaryKey := oldKey.entity.PrimaryKey, newKey.entity.PrimaryKey
+	for _, ident := range newKey.entity.Identities {
+		certified := false
+		for _, certification := range ident.OtherCertifications {
+			sig := certification.Packet
+			if sig.IssuerFingerprint != nil && !bytes.Equal(sig.IssuerFingerprint, oldPrimaryKey.Fingerprint) ||
+				sig.IssuerKeyId != nil && *sig.IssuerKeyId != oldPrimaryKey.KeyId {
+				continue
+			}
+			if oldPrimaryKey.VerifyUserIdSignature(ident.Name, newPrimaryKey, sig) == nil {
+				certified = true
+				break
+			}
+		}
+		if !certified {
+			return fmt.Errorf("gopenpgp: user id %q of the new key is not certified by the old key", ident.Name)
+		}
+	}
+	return nil
+}
+
+// keyTransitionStatement returns the text of the key transition statement.
+func keyTransitionStatement(oldKey, newKey *Key) []byte {
+	var statement strings.Builder
+	statement.WriteString(keyTransitionHeader + "\n\n")
+	statement.WriteString(keyTransitionOldKey + strings.ToUpper(hex.EncodeToString(oldKey.GetFingerprintBytes())) + "\n")
+	statement.WriteString(keyTransitionNewKey + strings.ToUpper(hex.EncodeToString(newKey.GetFingerprintBytes())) + "\n")
+	return []byte(statement.String())
+}
+
+// signedByKey returns true if the result has a valid signature by the key.
+func signedByKey(result *VerifyResult, key *Key) bool {
+	for _, signature := range result.Signatures {
+		if signature.SignatureError == nil && signature.SignedBy != nil &&
+			bytes.Equal(signature.SignedBy.GetFingerprintBytes(), key.GetFingerprintBytes()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+func TestRotateKey(t *testing.T) {
+	for name, pgp := range map[string]*PGPHandle{"v4": testPGP, "v6": PGPWithProfile(profile.RFC9580())} {
+		oldKey, err := pgp.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		expiration := time.Now().Unix() + 86400
+		rotation, err := pgp.RotateKey(oldKey, pgp.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New(), expiration)
+		if err != nil {
+			t.Fatal("Expected no error while rotating key, got:", err)
+		}
+		assert.NotEqual(t, oldKey.GetFingerprint(), rotation.NewKey.GetFingerprint(), name)
+		assert.Exactly(t, oldKey.GetFingerprint(), rotation.OldKey.GetFingerprint(), name)
+
+		info, err := rotation.OldKey.GetKeyInfo(time.Now().Unix())
+		if err != nil {
+			t.Fatal("Expected no error while getting key info, got:", err)
+		}
+		assert.Exactly(t, expiration, info.PrimaryKey.ExpirationTime, name)
+		assert.False(t, oldKey.IsExpired(expiration+1), name)
+		assert.True(t, rotation.OldKey.IsExpired(expiration+1), name)
+
+		oldPublicKey, err := rotation.OldKey.ToPublic()
+		if err != nil {
+			t.Fatal("Expected no error while getting public key, got:", err)
+		}
+		newPublicKey, err := rotation.NewKey.ToPublic()
+		if err != nil {
+			t.Fatal("Expected no error while getting public key, got:", err)
+		}
+		if err := pgp.VerifyKeyRotation(rotation.Statement, oldPublicKey, newPublicKey); err != nil {
+			t.Fatal("Expected no error while verifying key rotation, got:", err)
+		}
+		// The keys are swapped
+		assert.Error(t, pgp.VerifyKeyRotation(rotation.Statement, newPublicKey, oldPublicKey), name)
+
+		// The new key is not certified by the old key
+		otherKey, err := pgp.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		assert.Error(t, pgp.VerifyKeyRotation(rotation.Statement, oldPublicKey, otherKey), name)
+	}
+}
+
+func TestRotateKeyErrors(t *testing.T) {
+	key, err := testPGP.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyGeneration := testPGP.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New()
+	publicKey, err := key.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while getting public key, got:", err)
+	}
+	_, err = testPGP.RotateKey(publicKey, keyGeneration, 0)
+	assert.Error(t, err)
+	lockedKey, err := testPGP.LockKey(key, []byte("password"))
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	_, err = testPGP.RotateKey(lockedKey, keyGeneration, 0)
+	assert.Error(t, err)
+	_, err = testPGP.RotateKey(key, keyGeneration, testTime-3600)
+	assert.Error(t, err)
+
+	rotation, err := testPGP.RotateKey(key, keyGeneration, 0)
+	if err != nil {
+		t.Fatal("Expected no error while rotating key, got:", err)
+	}
+	assert.False(t, rotation.OldKey.IsExpired(testTime+100*365*86400))
+	tampered := []byte(strings.Replace(string(rotation.Statement), "New-Key", "New-key", 1))
+	assert.Error(t, testPGP.VerifyKeyRotation(tampered, rotation.OldKey, rotation.NewKey))
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/ProtonMail/gopenpgp/v3/constants"
 	"github.com/ProtonMail/gopenpgp/v3/profile"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/mnemonic"
 )
 
 const keyTestName = "Max Mustermann"
@@ -427,3 +429,346 @@ func TestKeyCompression(t *testing.T) {
 		selfSig.PreferredCompression,
 	)
 }
+
+func TestGenerateKeyWithAuthenticationSubkey(t *testing.T) {
+	key, err := testPGP.KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		AuthenticationSubkey().
+		New().
+		GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	serialized, err := key.Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	key, err = NewKey(serialized)
+	if err != nil {
+		t.Fatal("Expected no error while parsing key, got:", err)
+	}
+
+	subkeys := key.entity.Subkeys
+	if !assert.Len(t, subkeys, 2) {
+		return
+	}
+	binding, err := subkeys[1].Verify(time.Unix(testTime, 0), nil)
+	if err != nil {
+		t.Fatal("Expected no error while verifying authentication subkey, got:", err)
+	}
+	assert.True(t, binding.FlagAuthenticate)
+	assert.False(t, binding.FlagSign)
+	assert.False(t, binding.FlagEncryptCommunications)
+
+	assert.True(t, key.CanVerify(testTime))
+	assert.True(t, key.CanEncrypt(testTime))
+}
+
+func TestGenerateKeyWithSubkeyLayout(t *testing.T) {
+	for name, pgp := range map[string]*PGPHandle{"v4": testPGP, "v6": PGPWithProfile(profile.RFC9580())} {
+		key, err := pgp.KeyGeneration().
+			AddUserId(keyTestName, keyTestDomain).
+			GenerationTime(testTime).
+			OverrideProfileAlgorithm(KeyGenerationCurve25519).
+			CertificationOnlyPrimaryKey().
+			AddSigningSubkey(0, 86400).
+			AddEncryptionSubkey(KeyGenerationCurve448, 0).
+			AddAuthenticationSubkey(0, 0).
+			New().
+			GenerateKey()
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		serialized, err := key.Serialize()
+		if err != nil {
+			t.Fatal("Expected no error while serializing key, got:", err)
+		}
+		key, err = NewKey(serialized)
+		if err != nil {
+			t.Fatal("Expected no error while parsing key, got:", err)
+		}
+
+		now := time.Unix(testTime, 0)
+		primarySelfSignature, err := key.entity.VerifyPrimaryKey(now, nil)
+		if err != nil {
+			t.Fatal("Expected no error while verifying primary key, got:", err)
+		}
+		assert.True(t, primarySelfSignature.FlagCertify, name)
+		assert.False(t, primarySelfSignature.FlagSign, name)
+
+		subkeys := key.entity.Subkeys
+		if !assert.Len(t, subkeys, 3, name) {
+			continue
+		}
+		algorithms := []packet.PublicKeyAlgorithm{packet.PubKeyAlgoEd25519, packet.PubKeyAlgoX448, packet.PubKeyAlgoEd25519}
+		for i, subkey := range subkeys {
+			assert.Exactly(t, algorithms[i], subkey.PublicKey.PubKeyAlgo, name)
+			assert.Exactly(t, key.entity.PrimaryKey.Version, subkey.PublicKey.Version, name)
+		}
+		binding, err := subkeys[0].Verify(now, nil)
+		if err != nil {
+			t.Fatal("Expected no error while verifying signing subkey, got:", err)
+		}
+		assert.True(t, binding.FlagSign, name)
+		assert.Exactly(t, uint32(86400), *binding.KeyLifetimeSecs, name)
+		binding, err = subkeys[2].Verify(now, nil)
+		if err != nil {
+			t.Fatal("Expected no error while verifying authentication subkey, got:", err)
+		}
+		assert.True(t, binding.FlagAuthenticate, name)
+
+		encHandle, err := pgp.Encryption().Recipient(key).SigningKey(key).EncryptionTime(testTime).SignTime(testTime).New()
+		if err != nil {
+			t.Fatal("Expected no error while creating encryption handle, got:", err)
+		}
+		message, err := encHandle.Encrypt([]byte(testMessage))
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		encryptionKeyIDs, ok := message.EncryptionKeyIDs()
+		assert.True(t, ok, name)
+		assert.Exactly(t, []uint64{subkeys[1].PublicKey.KeyId}, encryptionKeyIDs, name)
+		decHandle, err := pgp.Decryption().DecryptionKey(key).VerificationKey(key).VerifyTime(testTime).New()
+		if err != nil {
+			t.Fatal("Expected no error while creating decryption handle, got:", err)
+		}
+		result, err := decHandle.Decrypt(message.Bytes(), Bytes)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.NoError(t, result.SignatureError(), name)
+		assert.Exactly(t, subkeys[0].PublicKey.KeyId, result.SignedByKeyId(), name)
+
+		// The signing subkey expires after a day
+		signer, err := pgp.Sign().SigningKey(key).SignTime(testTime + 2*86400).New()
+		if err != nil {
+			t.Fatal("Expected no error while creating signer, got:", err)
+		}
+		_, err = signer.Sign([]byte(testMessage), Bytes)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestGenerateKeyWithAlgorithmPreferences(t *testing.T) {
+	for name, pgp := range map[string]*PGPHandle{"v4": testPGP, "v6": PGPWithProfile(profile.RFC9580())} {
+		key, err := pgp.KeyGeneration().
+			AddUserId(keyTestName, keyTestDomain).
+			GenerationTime(testTime).
+			PreferredCiphers([]byte{byte(constants.CipherAES256), byte(constants.CipherAES128)}).
+			PreferredHashes([]byte{10, 8}).
+			PreferredCompression([]byte{byte(packet.CompressionNone)}).
+			PreferredCipherSuites([]byte{byte(constants.CipherAES256), byte(packet.AEADModeOCB)}).
+			New().
+			GenerateKey()
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		serialized, err := key.Serialize()
+		if err != nil {
+			t.Fatal("Expected no error while serializing key, got:", err)
+		}
+		key, err = NewKey(serialized)
+		if err != nil {
+			t.Fatal("Expected no error while parsing key, got:", err)
+		}
+		primarySelfSignature, err := key.entity.VerifyPrimaryKey(time.Unix(testTime, 0), nil)
+		if err != nil {
+			t.Fatal("Expected no error while verifying primary key, got:", err)
+		}
+		assert.True(t, primarySelfSignature.FlagSign, name)
+		assert.Exactly(t, []uint8{uint8(packet.CipherAES256), uint8(packet.CipherAES128)}, primarySelfSignature.PreferredSymmetric, name)
+		assert.Exactly(t, []uint8{10, 8}, primarySelfSignature.PreferredHash, name)
+		assert.Exactly(t, []uint8{uint8(packet.CompressionNone)}, primarySelfSignature.PreferredCompression, name)
+		assert.Exactly(t, [][2]uint8{{uint8(packet.CipherAES256), uint8(packet.AEADModeOCB)}}, primarySelfSignature.PreferredCipherSuites, name)
+		assert.True(t, primarySelfSignature.SEIPDv2, name)
+	}
+}
+
+func TestGenerateKeyWithInvalidSubkeyLayout(t *testing.T) {
+	_, err := PGPWithProfile(profile.RFC9580()).KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		AddEncryptionSubkey(KeyGenerationCurve25519Legacy, 0).
+		New().
+		GenerateKey()
+	assert.Error(t, err)
+
+	_, err = testPGP.KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		PreferredHashes([]byte{4}).
+		New().
+		GenerateKey()
+	assert.Error(t, err)
+
+	_, err = testPGP.KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		PreferredCipherSuites([]byte{byte(constants.CipherAES256)}).
+		New().
+		GenerateKey()
+	assert.Error(t, err)
+}
+
+func TestGenerateKeyFromSeed(t *testing.T) {
+	seed, err := mnemonic.ToSeed("legal winner thank year wave sausage worth useful legal winner thank yellow", "")
+	if err != nil {
+		t.Fatal("Expected no error while deriving seed, got:", err)
+	}
+	for name, pgp := range map[string]*PGPHandle{"v4": testPGP, "v6": PGPWithProfile(profile.RFC9580())} {
+		generate := func(seed []byte, authenticationSubkey bool) *Key {
+			builder := pgp.KeyGeneration().
+				AddUserId(keyTestName, keyTestDomain).
+				GenerationTime(testTime).
+				Seed(seed).
+				AddSigningSubkey(0, 0)
+			if authenticationSubkey {
+				builder.AuthenticationSubkey()
+			}
+			key, err := builder.New().GenerateKey()
+			if err != nil {
+				t.Fatal("Expected no error while generating key, got:", err)
+			}
+			return key
+		}
+		key := generate(seed, false)
+		serialized, err := key.Serialize()
+		if err != nil {
+			t.Fatal("Expected no error while serializing key, got:", err)
+		}
+		regenerated, err := generate(seed, false).Serialize()
+		if err != nil {
+			t.Fatal("Expected no error while serializing key, got:", err)
+		}
+		assert.Exactly(t, serialized, regenerated, name)
+		assert.True(t, key.CanEncrypt(testTime), name)
+		assert.True(t, key.CanVerify(testTime), name)
+
+		// Each subkey is derived independently of the others
+		extended := generate(seed, true)
+		if assert.Len(t, extended.entity.Subkeys, 3, name) && assert.Len(t, key.entity.Subkeys, 2, name) {
+			assert.Exactly(t, key.GetFingerprint(), extended.GetFingerprint(), name)
+			for i, subkey := range key.entity.Subkeys {
+				assert.Exactly(t, subkey.PublicKey.Fingerprint, extended.entity.Subkeys[i].PublicKey.Fingerprint, name)
+			}
+		}
+
+		otherSeed := append([]byte{}, seed...)
+		otherSeed[0] ^= 1
+		assert.NotEqual(t, key.GetFingerprint(), generate(otherSeed, false).GetFingerprint(), name)
+	}
+}
+
+func TestGenerateKeyFromInvalidSeed(t *testing.T) {
+	_, err := testPGP.KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		Seed(make([]byte, 8)).
+		New().
+		GenerateKey()
+	assert.Error(t, err)
+
+	_, err = testPGP.KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		Seed(make([]byte, 32)).
+		OverrideProfileAlgorithm(KeyGenerationRSA4096).
+		New().
+		GenerateKey()
+	assert.Error(t, err)
+
+	_, err = testPGP.KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		Seed(make([]byte, 32)).
+		AddEncryptionSubkey(KeyGenerationCurve448, 0).
+		New().
+		GenerateKey()
+	assert.Error(t, err)
+}
+
+func TestSecretSubkeysOnly(t *testing.T) {
+	key, err := testPGP.KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		CertificationOnlyPrimaryKey().
+		AddSigningSubkey(0, 0).
+		AddEncryptionSubkey(0, 0).
+		New().
+		GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	assert.True(t, key.HasPrimarySecret())
+
+	subkeysKey, err := key.ToSecretSubkeys()
+	if err != nil {
+		t.Fatal("Expected no error while stripping primary key, got:", err)
+	}
+	armored, err := subkeysKey.Armor()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	subkeysKey, err = NewKeyFromArmored(armored)
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	assert.False(t, subkeysKey.HasPrimarySecret())
+	assert.Exactly(t, key.GetFingerprint(), subkeysKey.GetFingerprint())
+	assert.Exactly(t, []SecretKeyStatus{
+		{KeyID: key.GetKeyID(), Primary: true, Status: SecretKeyStripped},
+		{KeyID: key.entity.Subkeys[0].PublicKey.KeyId, Status: SecretKeyUnlocked},
+		{KeyID: key.entity.Subkeys[1].PublicKey.KeyId, Status: SecretKeyUnlocked},
+	}, subkeysKey.GetSecretKeyStatus())
+
+	// Lock and unlock the subkeys only
+	locked, err := testPGP.LockKey(subkeysKey, keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	isLocked, err := locked.IsLocked()
+	if err != nil {
+		t.Fatal("Expected no error while checking key, got:", err)
+	}
+	assert.True(t, isLocked)
+	assert.Exactly(t, SecretKeyLocked, locked.GetSecretKeyStatus()[1].Status)
+	unlocked, err := locked.Unlock(keyTestPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while unlocking key, got:", err)
+	}
+	assert.Exactly(t, SecretKeyStripped, unlocked.GetSecretKeyStatus()[0].Status)
+
+	encHandle, err := testPGP.Encryption().Recipient(key).SigningKey(unlocked).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	message, err := encHandle.Encrypt([]byte(testMessage))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, err := testPGP.Decryption().DecryptionKey(unlocked).VerificationKey(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryption handle, got:", err)
+	}
+	result, err := decHandle.Decrypt(message.Bytes(), Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, testMessage, string(result.Bytes()))
+	assert.NoError(t, result.SignatureError())
+}
+
+func TestSecretSubkeysOnlySignWithPrimary(t *testing.T) {
+	// The default layout signs with the primary key
+	subkeysKey, err := keyTestEC.ToSecretSubkeys()
+	if err != nil {
+		t.Fatal("Expected no error while stripping primary key, got:", err)
+	}
+	signer, err := testPGP.Sign().SigningKey(subkeysKey).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating signer, got:", err)
+	}
+	_, err = signer.Sign([]byte(testMessage), Bytes)
+	assert.Error(t, err)
+
+	publicKey, err := keyTestEC.ToPublic()
+	if err != nil {
+		t.Fatal("Expected no error while extracting public key, got:", err)
+	}
+	_, err = publicKey.ToSecretSubkeys()
+	assert.Error(t, err)
+	assert.Exactly(t, SecretKeyMissing, publicKey.GetSecretKeyStatus()[0].Status)
+}
@@ -94,6 +94,17 @@ func (keyRing *KeyRing) GetKey(n int) (*Key, error) {
 	return &Key{keyRing.entities[n]}, nil
 }
 
+// GetKeyByFingerprint returns the key in this KeyRing with the given primary
+// key fingerprint, e.g. parsed with ParseFingerprintURI.
+func (keyRing *KeyRing) GetKeyByFingerprint(fingerprint []byte) (*Key, error) {
+	for _, entity := range keyRing.getEntities() {
+		if bytes.Equal(entity.PrimaryKey.Fingerprint, fingerprint) {
+			return &Key{entity}, nil
+		}
+	}
+	return nil, errors.New("gopenpgp: no key with the fingerprint in keyring")
+}
+
 func (keyRing *KeyRing) signingEntities() ([]*openpgp.Entity, error) {
 	var signEntity []*openpgp.Entity
 	for _, e := range keyRing.entities {
@@ -198,10 +209,11 @@ func (keyRing *KeyRing) CanVerify(unixTime int64) bool {
 }
 
 // CanEncrypt returns true if any of the keys in the keyring can be used for encryption.
+// Keys that are revoked by a designated revoker in the keyring are ignored.
 func (keyRing *KeyRing) CanEncrypt(unixTime int64) bool {
 	keys := keyRing.GetKeys()
 	for _, key := range keys {
-		if key.CanEncrypt(unixTime) {
+		if key.CanEncrypt(unixTime) && !key.isRevokedByDesignatedRevoker(time.Unix(unixTime, 0), keys) {
 			return true
 		}
 	}
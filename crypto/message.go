@@ -308,6 +308,16 @@ func (msg *PGPMessage) GetNumberOfKeyPackets() (int, error) {
 	return keyPacketCount, nil
 }
 
+// PaddingLength returns the total length of the padding packets of the
+// message outside of the encrypted data packet, including their headers.
+// Padding packets inside the encrypted data packet are reported by
+// VerifyDataReader.PaddingLength.
+func (msg *PGPMessage) PaddingLength() int64 {
+	padding := newPaddingReader(io.MultiReader(bytes.NewReader(msg.KeyPacket), bytes.NewReader(msg.DataPacket)))
+	_, _ = io.Copy(io.Discard, padding)
+	return padding.length
+}
+
 // splitMessage splits the message into key and data packet(s).
 func (msg *PGPMessage) splitMessage() (*PGPMessage, error) {
 	data := msg.DataPacket
@@ -324,7 +334,7 @@ Loop:
 			return nil, err
 		}
 		switch p.(type) {
-		case *packet.SymmetricKeyEncrypted, *packet.EncryptedKey:
+		case *packet.SymmetricKeyEncrypted, *packet.EncryptedKey, packet.Padding:
 			splitPoint = bytesReader.Size() - int64(bytesReader.Len())
 		case *packet.SymmetricallyEncrypted, *packet.AEADEncrypted:
 			break Loop
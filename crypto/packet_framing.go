@@ -0,0 +1,130 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// newFormatLength encodes the length of a new format packet body.
+func newFormatLength(length int) []byte {
+	switch {
+	case length < 192:
+		return []byte{byte(length)}
+	case length < 8384:
+		length -= 192
+		return []byte{byte(length>>8) + 192, byte(length)}
+	}
+	return binary.BigEndian.AppendUint32([]byte{255}, uint32(length))
+}
+
+// partialLength encodes the length of a partial body, a power of two.
+func partialLength(length int) byte {
+	exponent := 0
+	for 1<<exponent < length {
+		exponent++
+	}
+	return byte(224 + exponent)
+}
+
+// readNewFormatLength reads the length of a new format packet body.
+func readNewFormatLength(r io.Reader) (length int64, partial bool, err error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:1]); err != nil {
+		return 0, false, err
+	}
+	switch first := buf[0]; {
+	case first < 192:
+		return int64(first), false, nil
+	case first < 224:
+		if _, err := io.ReadFull(r, buf[:1]); err != nil {
+			return 0, false, err
+		}
+		return (int64(first)-192)<<8 + int64(buf[0]) + 192, false, nil
+	case first < 255:
+		return 1 << (first & 0x1f), true, nil
+	}
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, false, err
+	}
+	return int64(binary.BigEndian.Uint32(buf[:])), false, nil
+}
+
+// readPacketHeader reads the header of a packet with a definite length or a
+// partial body length.
+func readPacketHeader(r io.Reader) (tag uint8, length int64, partial bool, err error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:1]); err != nil {
+		return 0, 0, false, err
+	}
+	tagByte := buf[0]
+	switch {
+	case tagByte&0x80 == 0:
+		return 0, 0, false, errors.New("gopenpgp: invalid packet header")
+	case tagByte&0x40 != 0:
+		length, partial, err = readNewFormatLength(r)
+		return tagByte & 0x3f, length, partial, err
+	}
+	// Old format packet
+	lengthBytes := 1 << (tagByte & 0x03)
+	if lengthBytes > 4 {
+		return 0, 0, false, errors.New("gopenpgp: packets of indeterminate length are not supported")
+	}
+	if _, err := io.ReadFull(r, buf[:lengthBytes]); err != nil {
+		return 0, 0, false, err
+	}
+	for _, b := range buf[:lengthBytes] {
+		length = length<<8 | int64(b)
+	}
+	return (tagByte & 0x3f) >> 2, length, false, nil
+}
+
+// countingReader counts the bytes read from the reader.
+type countingReader struct {
+	reader io.Reader
+	offset int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.offset += int64(n)
+	return n, err
+}
+
+// partialBodyReader reads the body of a packet, which may consist of
+// partial bodies.
+type partialBodyReader struct {
+	reader *countingReader
+	// remaining is the length of the rest of the current partial body.
+	remaining int64
+	// partial is true if another partial body follows the current one.
+	partial bool
+}
+
+func (r *partialBodyReader) Read(b []byte) (int, error) {
+	for r.remaining == 0 {
+		if !r.partial {
+			return 0, io.EOF
+		}
+		var err error
+		if r.remaining, r.partial, err = readNewFormatLength(r.reader); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+	}
+	n, err := r.reader.Read(b[:min(int64(len(b)), r.remaining)])
+	r.remaining -= int64(n)
+	if r.remaining > 0 && errors.Is(err, io.EOF) {
+		return n, io.ErrUnexpectedEOF
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, err
+	}
+	return n, nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,247 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"io"
+	"math/big"
+	"math/bits"
+	"slices"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// packetTagPadding is the tag of padding packets.
+const packetTagPadding = 21
+
+// minPaddingPacketLength is the length of an empty padding packet.
+const minPaddingPacketLength = 2
+
+// PaddingStrategy determines the length to which the packets inside the
+// encrypted data packet of a message are padded with padding packets,
+// to hide the length of the message.
+type PaddingStrategy interface {
+	// PaddedLength returns the padded length for the given length of the
+	// packets. It must not be smaller than length.
+	PaddedLength(length int64) int64
+}
+
+type bucketPadding struct {
+	buckets []int64
+}
+
+// NewBucketPadding returns a padding strategy that pads messages to the
+// smallest of the given bucket sizes that fits the message. Messages longer
+// than the largest bucket are padded to a multiple of it.
+func NewBucketPadding(buckets ...int64) PaddingStrategy {
+	sorted := slices.DeleteFunc(slices.Clone(buckets), func(bucket int64) bool {
+		return bucket <= 0
+	})
+	slices.Sort(sorted)
+	return &bucketPadding{buckets: sorted}
+}
+
+func (padding *bucketPadding) PaddedLength(length int64) int64 {
+	if len(padding.buckets) == 0 {
+		return length
+	}
+	for _, bucket := range padding.buckets {
+		if length <= bucket {
+			return bucket
+		}
+	}
+	largest := padding.buckets[len(padding.buckets)-1]
+	return (length + largest - 1) / largest * largest
+}
+
+type padmePadding struct{}
+
+// NewPadmePadding returns a padding strategy that pads messages with the
+// Padmé scheme, which leaks O(log log L) bits of the length L with at most
+// 12% overhead.
+func NewPadmePadding() PaddingStrategy {
+	return padmePadding{}
+}
+
+func (padmePadding) PaddedLength(length int64) int64 {
+	if length < 2 {
+		return length
+	}
+	exponent := bits.Len64(uint64(length)) - 1
+	lastBits := exponent - bits.Len64(uint64(exponent))
+	if lastBits <= 0 {
+		return length
+	}
+	mask := int64(1)<<lastBits - 1
+	return (length + mask) &^ mask
+}
+
+type randomPadding struct {
+	max int64
+}
+
+// NewRandomPadding returns a padding strategy that adds a uniformly random
+// amount of padding of up to maxLength bytes to messages.
+func NewRandomPadding(maxLength int64) PaddingStrategy {
+	return &randomPadding{max: max(maxLength, 0)}
+}
+
+func (padding *randomPadding) PaddedLength(length int64) int64 {
+	n, err := rand.Int(rand.Reader, big.NewInt(padding.max+1))
+	if err != nil {
+		// Padding more never reveals more about the length
+		return length + padding.max
+	}
+	return length + n.Int64()
+}
+
+// paddingWriteCloser pads the packets written into the encrypted data packet
+// when it is closed.
+type paddingWriteCloser struct {
+	writer   io.WriteCloser
+	strategy PaddingStrategy
+	rand     io.Reader
+	length   int64
+}
+
+func newPaddingWriteCloser(writer io.WriteCloser, strategy PaddingStrategy, rand io.Reader) *paddingWriteCloser {
+	return &paddingWriteCloser{
+		writer:   writer,
+		strategy: strategy,
+		rand:     rand,
+	}
+}
+
+func (w *paddingWriteCloser) Write(b []byte) (int, error) {
+	n, err := w.writer.Write(b)
+	w.length += int64(n)
+	return n, err
+}
+
+func (w *paddingWriteCloser) Close() error {
+	minLength := w.length + minPaddingPacketLength
+	if err := writePadding(w.writer, max(w.strategy.PaddedLength(minLength), minLength)-w.length, w.rand); err != nil {
+		return err
+	}
+	return w.writer.Close()
+}
+
+// writePadding writes padding packets of exactly the given total length,
+// which must be at least minPaddingPacketLength.
+func writePadding(w io.Writer, length int64, rand io.Reader) error {
+	for {
+		for _, headerLength := range []int64{2, 3, 6} {
+			bodyLength := length - headerLength
+			if bodyLength >= 0 && paddingHeaderLength(bodyLength) == headerLength {
+				return packet.Padding(bodyLength).SerializePadding(w, rand)
+			}
+		}
+		// Some lengths cannot be encoded in a single packet, e.g. a body of
+		// 192 bytes needs a 3 byte header, so an empty packet is added.
+		if err := packet.Padding(0).SerializePadding(w, rand); err != nil {
+			return err
+		}
+		length -= minPaddingPacketLength
+	}
+}
+
+// paddingHeaderLength returns the length of the header of a padding packet
+// with the given body length.
+func paddingHeaderLength(bodyLength int64) int64 {
+	switch {
+	case bodyLength < 192:
+		return 2
+	case bodyLength < 8384:
+		return 3
+	default:
+		return 6
+	}
+}
+
+// paddingReader passes an OpenPGP packet stream through and sums up the
+// length of its padding packets, including their headers.
+// Streams that are not OpenPGP packets, or end in a packet of indeterminate
+// length, are passed through without tracking them.
+type paddingReader struct {
+	reader io.Reader
+	// length is the total length of the padding packets read so far.
+	length int64
+
+	// pending are the header bytes read from the reader that are not
+	// returned yet.
+	pending []byte
+	// err is the error of the reader while reading a header, which is
+	// returned after the pending bytes.
+	err       error
+	tag       uint8
+	remaining int64
+	partial   bool
+	untracked bool
+}
+
+func newPaddingReader(reader io.Reader) *paddingReader {
+	return &paddingReader{reader: reader}
+}
+
+func (r *paddingReader) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	for len(r.pending) == 0 {
+		switch {
+		case r.err != nil:
+			return 0, r.err
+		case r.untracked:
+			return r.reader.Read(b)
+		case r.remaining > 0:
+			n, err := r.reader.Read(b[:min(int64(len(b)), r.remaining)])
+			r.remaining -= int64(n)
+			if r.tag == packetTagPadding {
+				r.length += int64(n)
+			}
+			return n, err
+		}
+		r.readHeader()
+	}
+	n := copy(b, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// readHeader reads the header of the next packet, or the length of the next
+// partial body, into the pending bytes.
+func (r *paddingReader) readHeader() {
+	header := &recordingReader{reader: r.reader}
+	var err error
+	if r.partial {
+		r.remaining, r.partial, err = readNewFormatLength(header)
+	} else {
+		r.tag, r.remaining, r.partial, err = readPacketHeader(header)
+	}
+	r.pending = header.data
+	r.err = header.err
+	if err != nil {
+		// Not an OpenPGP packet or of indeterminate length, unless the
+		// reader failed
+		r.untracked = r.err == nil
+		return
+	}
+	if r.tag == packetTagPadding {
+		r.length += int64(len(header.data))
+	}
+}
+
+// recordingReader records the data and the error read from the reader.
+type recordingReader struct {
+	reader io.Reader
+	data   []byte
+	err    error
+}
+
+func (r *recordingReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.data = append(r.data, b[:n]...)
+	if err != nil {
+		r.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,347 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaddingStrategies(t *testing.T) {
+	buckets := NewBucketPadding(1024, 256)
+	assert.Exactly(t, int64(256), buckets.PaddedLength(10))
+	assert.Exactly(t, int64(256), buckets.PaddedLength(256))
+	assert.Exactly(t, int64(1024), buckets.PaddedLength(300))
+	assert.Exactly(t, int64(3072), buckets.PaddedLength(2050))
+
+	padme := NewPadmePadding()
+	assert.Exactly(t, int64(10), padme.PaddedLength(9))
+	assert.Exactly(t, int64(1024), padme.PaddedLength(1000))
+	assert.Exactly(t, int64(1024), padme.PaddedLength(1024))
+	assert.Exactly(t, int64(1088), padme.PaddedLength(1025))
+
+	random := NewRandomPadding(16)
+	for i := 0; i < 100; i++ {
+		length := random.PaddedLength(100)
+		assert.GreaterOrEqual(t, length, int64(100))
+		assert.LessOrEqual(t, length, int64(116))
+	}
+}
+
+func TestWritePadding(t *testing.T) {
+	for length := int64(minPaddingPacketLength); length < 8400; length++ {
+		var buffer bytes.Buffer
+		if err := writePadding(&buffer, length, bytes.NewReader(make([]byte, length))); err != nil {
+			t.Fatal("Expected no error while writing padding, got:", err)
+		}
+		if int64(buffer.Len()) != length {
+			t.Fatalf("Expected %d bytes of padding, got: %d", length, buffer.Len())
+		}
+		padding := newPaddingReader(&buffer)
+		if _, err := io.Copy(io.Discard, padding); err != nil {
+			t.Fatal("Expected no error while reading padding, got:", err)
+		}
+		if padding.length != length {
+			t.Fatalf("Expected %d bytes of padding to be read, got: %d", length, padding.length)
+		}
+	}
+}
+
+func TestPaddingReader(t *testing.T) {
+	var stream []byte
+	// Literal data packet with a definite length
+	stream = append(stream, 0xc0|packetTagLiteralData, 3, 'b', 0, 0)
+	// Padding packet with a partial body of 512 bytes and a final body of 200 bytes
+	stream = append(stream, 0xc0|packetTagPadding, partialLength(512))
+	stream = append(stream, make([]byte, 512)...)
+	stream = append(stream, newFormatLength(200)...)
+	stream = append(stream, make([]byte, 200)...)
+	// Empty old format marker packet
+	stream = append(stream, 0x80|10<<2, 0)
+	paddingLength := int64(2 + 512 + 2 + 200)
+
+	for name, reader := range map[string]func(io.Reader) io.Reader{
+		"whole":    func(r io.Reader) io.Reader { return r },
+		"one byte": iotest.OneByteReader,
+		"data EOF": iotest.DataErrReader,
+	} {
+		padding := newPaddingReader(reader(bytes.NewReader(stream)))
+		data, err := io.ReadAll(padding)
+		if err != nil {
+			t.Fatal("Expected no error while reading, got:", err)
+		}
+		assert.Exactly(t, stream, data, name)
+		assert.Exactly(t, paddingLength, padding.length, name)
+	}
+
+	// Other data and truncated headers are passed through
+	for _, data := range [][]byte{
+		[]byte("not a packet"),
+		stream[:1],
+		{0xc0 | packetTagPadding, 0xff, 0, 0},
+		append(slices.Clone(stream[:len(stream)-2]), 0x80|10<<2|3, 'x'),
+	} {
+		padding := newPaddingReader(iotest.OneByteReader(bytes.NewReader(data)))
+		read, err := io.ReadAll(padding)
+		if err != nil {
+			t.Fatal("Expected no error while reading, got:", err)
+		}
+		assert.Exactly(t, data, read)
+	}
+	readErr := errors.New("read error")
+	_, err := io.ReadAll(newPaddingReader(iotest.ErrReader(readErr)))
+	assert.ErrorIs(t, err, readErr)
+}
+
+func TestEncryptDecryptPadding(t *testing.T) {
+	for _, material := range testMaterialForProfiles {
+		t.Run(material.profileName, func(t *testing.T) {
+			encryptor, err := material.pgp.Encryption().
+				Recipients(material.keyRingTestPublic).
+				SigningKeys(material.keyRingTestPrivate).
+				Padding(NewBucketPadding(4096)).
+				New()
+			if err != nil {
+				t.Fatal("Expected no error while creating encryptor, got:", err)
+			}
+			decryptor, err := material.pgp.Decryption().
+				DecryptionKeys(material.keyRingTestPrivate).
+				VerificationKeys(material.keyRingTestPublic).
+				New()
+			if err != nil {
+				t.Fatal("Expected no error while creating decryptor, got:", err)
+			}
+			var dataPacketLength int
+			for _, message := range []string{"a", strings.Repeat("hello ", 200)} {
+				encrypted, err := encryptor.Encrypt([]byte(message))
+				if err != nil {
+					t.Fatal("Expected no error while encrypting, got:", err)
+				}
+				if dataPacketLength == 0 {
+					dataPacketLength = len(encrypted.DataPacket)
+				}
+				// Only the partial length headers of the data packet differ
+				assert.InDelta(t, dataPacketLength, len(encrypted.DataPacket), 8)
+
+				result, err := decryptor.Decrypt(encrypted.Bytes(), Bytes)
+				if err != nil {
+					t.Fatal("Expected no error while decrypting, got:", err)
+				}
+				assert.Exactly(t, message, result.String())
+				if err := result.SignatureError(); err != nil {
+					t.Fatal("Expected no signature error, got:", err)
+				}
+
+				sessionKey, err := decryptor.DecryptSessionKey(encrypted.KeyPacket)
+				if err != nil {
+					t.Fatal("Expected no error while decrypting session key, got:", err)
+				}
+				sessionKeyDecryptor, err := material.pgp.Decryption().
+					SessionKey(sessionKey).
+					VerificationKeys(material.keyRingTestPublic).
+					New()
+				if err != nil {
+					t.Fatal("Expected no error while creating decryptor, got:", err)
+				}
+				result, err = sessionKeyDecryptor.Decrypt(encrypted.DataPacket, Bytes)
+				if err != nil {
+					t.Fatal("Expected no error while decrypting with session key, got:", err)
+				}
+				assert.Exactly(t, message, result.String())
+				assert.Greater(t, result.PaddingLength(), int64(minPaddingPacketLength))
+
+				// The signatures list the recipients as intended recipients
+				var recipients []string
+				for _, key := range material.keyRingTestPublic.GetKeys() {
+					recipients = append(recipients, key.GetFingerprint())
+				}
+				signatures := readEncryptedSignatures(t, sessionKey, encrypted.DataPacket)
+				if assert.NotEmpty(t, signatures) {
+					for _, signature := range signatures {
+						var fingerprints []string
+						for _, recipient := range signature.IntendedRecipients {
+							fingerprints = append(fingerprints, hex.EncodeToString(recipient.Fingerprint))
+						}
+						assert.Exactly(t, recipients, fingerprints)
+					}
+				}
+			}
+		})
+	}
+}
+
+// readEncryptedSignatures returns the signature packets inside the data
+// packet encrypted with the session key.
+func readEncryptedSignatures(t *testing.T, sessionKey *SessionKey, dataPacket []byte) (signatures []*packet.Signature) {
+	decrypted, _, err := decryptStreamWithSessionKey(sessionKey, bytes.NewReader(dataPacket))
+	if err != nil {
+		t.Fatal("Expected no error while decrypting data packet, got:", err)
+	}
+	packets := packet.NewReader(decrypted)
+	for {
+		p, err := packets.Next()
+		if errors.Is(err, io.EOF) {
+			return signatures
+		}
+		if err != nil {
+			t.Fatal("Expected no error while reading packets, got:", err)
+		}
+		switch p := p.(type) {
+		case *packet.Compressed:
+			packets.Push(p.Body)
+		case *packet.LiteralData:
+			_, _ = io.Copy(io.Discard, p.Body)
+		case *packet.Signature:
+			signatures = append(signatures, p)
+		}
+	}
+}
+
+func TestPaddingSignaturesMatchGoCrypto(t *testing.T) {
+	// Padded messages are signed and compressed by gopenpgp, which must
+	// negotiate the same algorithms as go-crypto without padding
+	for _, material := range testMaterialForProfiles {
+		t.Run(material.profileName, func(t *testing.T) {
+			for _, textSig := range []bool{false, true} {
+				var packets [2]*encryptedPackets
+				for i, padding := range []PaddingStrategy{nil, NewBucketPadding(4096)} {
+					builder := material.pgp.Encryption().
+						Recipients(material.keyRingTestPublic).
+						SigningKeys(material.keyRingTestPrivate).
+						Compress().
+						Padding(padding)
+					if textSig {
+						builder.Utf8()
+					}
+					encryptor, err := builder.New()
+					if err != nil {
+						t.Fatal("Expected no error while creating encryptor, got:", err)
+					}
+					encrypted, err := encryptor.Encrypt([]byte(testMessageString))
+					if err != nil {
+						t.Fatal("Expected no error while encrypting, got:", err)
+					}
+					decryptor, _ := material.pgp.Decryption().DecryptionKeys(material.keyRingTestPrivate).New()
+					sessionKey, err := decryptor.DecryptSessionKey(encrypted.KeyPacket)
+					if err != nil {
+						t.Fatal("Expected no error while decrypting session key, got:", err)
+					}
+					packets[i] = readEncryptedPackets(t, sessionKey, encrypted.DataPacket)
+				}
+				assert.NotEmpty(t, packets[0].signatures)
+				assert.NotEqual(t, -1, packets[0].compression)
+				assert.Exactly(t, packets[0].compression, packets[1].compression)
+				if assert.Len(t, packets[1].signatures, len(packets[0].signatures)) {
+					for i, signature := range packets[0].signatures {
+						padded := packets[1].signatures[i]
+						assert.Exactly(t, signature.Version, padded.Version)
+						assert.Exactly(t, signature.SigType, padded.SigType)
+						assert.Exactly(t, signature.Hash, padded.Hash)
+						assert.Exactly(t, signature.IntendedRecipients, padded.IntendedRecipients)
+					}
+				}
+			}
+		})
+	}
+}
+
+type encryptedPackets struct {
+	// compression is the algorithm of the compressed packet, or -1.
+	compression int
+	signatures  []*packet.Signature
+}
+
+// readEncryptedPackets returns the compression algorithm and the signature
+// packets inside the data packet encrypted with the session key.
+func readEncryptedPackets(t *testing.T, sessionKey *SessionKey, dataPacket []byte) *encryptedPackets {
+	decrypted, _, err := decryptStreamWithSessionKey(sessionKey, bytes.NewReader(dataPacket))
+	if err != nil {
+		t.Fatal("Expected no error while decrypting data packet, got:", err)
+	}
+	var plaintext bytes.Buffer
+	if _, err := io.Copy(&plaintext, decrypted); err != nil {
+		t.Fatal("Expected no error while decrypting data packet, got:", err)
+	}
+	packets := &encryptedPackets{compression: -1}
+	reader := bytes.NewReader(plaintext.Bytes())
+	if tag, _, _, err := readPacketHeader(reader); err == nil && tag == 8 {
+		algo, _ := reader.ReadByte()
+		packets.compression = int(algo)
+	}
+	packets.signatures = readEncryptedSignatures(t, sessionKey, dataPacket)
+	return packets
+}
+
+func TestEncryptDecryptPaddingPassword(t *testing.T) {
+	password := []byte("password")
+	encryptor, err := testPGP.Encryption().Password(password).Padding(NewPadmePadding()).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryptor, got:", err)
+	}
+	encrypted, err := encryptor.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decryptor, err := testPGP.Decryption().Password(password).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryptor, got:", err)
+	}
+	result, err := decryptor.Decrypt(encrypted.Bytes(), Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, testMessageString, result.String())
+}
+
+func TestPGPMessagePadding(t *testing.T) {
+	material := testMaterialForProfiles[0]
+	encryptor, err := material.pgp.Encryption().Recipients(material.keyRingTestPublic).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryptor, got:", err)
+	}
+	encrypted, err := encryptor.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	assert.Exactly(t, int64(0), encrypted.PaddingLength())
+
+	// Padding packets between the key packets and the data packet
+	var padding bytes.Buffer
+	if err := packet.Padding(30).SerializePadding(&padding, bytes.NewReader(make([]byte, 30))); err != nil {
+		t.Fatal("Expected no error while writing padding, got:", err)
+	}
+	padded := NewPGPMessage(slices.Concat(encrypted.KeyPacket, padding.Bytes(), encrypted.DataPacket))
+	assert.Exactly(t, int64(32), padded.PaddingLength())
+
+	decryptor, err := material.pgp.Decryption().DecryptionKeys(material.keyRingTestPrivate).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryptor, got:", err)
+	}
+	result, err := decryptor.Decrypt(padded.Bytes(), Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, testMessageString, result.String())
+	assert.Exactly(t, int64(32), result.PaddingLength())
+
+	sessionKey, err := decryptor.DecryptSessionKey(padded.KeyPacket)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	sessionKeyDecryptor, err := material.pgp.Decryption().SessionKey(sessionKey).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryptor, got:", err)
+	}
+	result, err = sessionKeyDecryptor.Decrypt(padded.DataPacket, Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with session key, got:", err)
+	}
+	assert.Exactly(t, testMessageString, result.String())
+}
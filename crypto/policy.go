@@ -0,0 +1,223 @@
+package crypto
+
+import (
+	"crypto"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+
+	"github.com/lovoo/gopenpgp/v3/policy"
+)
+
+// checkSignaturePolicy checks a verified data signature and the key that
+// created it against the policy.
+// The hash algorithm and the signing key are checked at the creation time of
+// the signature, the self-signatures of the key at their creation time.
+func checkSignaturePolicy(cryptoPolicy *policy.Policy, sig *packet.Signature, signedBy *openpgp.Key) error {
+	if sig == nil {
+		return nil
+	}
+	if err := cryptoPolicy.CheckHash(sig.Hash, policy.DataSignature, sig.CreationTime); err != nil {
+		return err
+	}
+	if signedBy == nil || signedBy.PublicKey == nil {
+		return nil
+	}
+	if err := checkPublicKeyPolicy(cryptoPolicy, signedBy.PublicKey, policy.DataSignature, sig.CreationTime); err != nil {
+		return err
+	}
+	return checkSelfSignaturesPolicy(cryptoPolicy, signedBy)
+}
+
+// checkSelfSignaturesPolicy checks the hashes of the primary self-signature
+// and the binding signature of the key at their creation time.
+func checkSelfSignaturesPolicy(cryptoPolicy *policy.Policy, key *openpgp.Key) error {
+	for _, selfSig := range []*packet.Signature{key.PrimarySelfSignature, key.SelfSignature} {
+		if selfSig == nil {
+			continue
+		}
+		if err := cryptoPolicy.CheckHash(selfSig.Hash, policy.SelfSignature, selfSig.CreationTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSigningPolicy checks the hashes and the signing keys of the
+// signatures that the signers create at the time of the config against the
+// policy. The hash of each signature is selected among the candidates as in
+// go-crypto, with the preferences of the signing key.
+func checkSigningPolicy(cryptoPolicy *policy.Policy, signers []*openpgp.Entity, candidates []crypto.Hash, config *packet.Config) error {
+	now := config.Now()
+	for _, entity := range signers {
+		signKey, ok := entity.SigningKeyById(now, config.SigningKey(), config)
+		if !ok || signKey.PrimarySelfSignature == nil {
+			// Signing fails without a valid signing key
+			continue
+		}
+		hash := selectSignatureHash(
+			intersectHashPreferences(slices.Clone(candidates), signKey.PrimarySelfSignature.PreferredHash),
+			config.Hash(),
+			signKey.PublicKey,
+		)
+		if err := checkSignerPolicy(cryptoPolicy, hash, signKey.PublicKey, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSignerPolicy checks the hash and the key of a signature that is
+// created at the time against the policy.
+func checkSignerPolicy(cryptoPolicy *policy.Policy, hash crypto.Hash, signer *packet.PublicKey, at time.Time) error {
+	if err := cryptoPolicy.CheckHash(hash, policy.DataSignature, at); err != nil {
+		return err
+	}
+	return checkPublicKeyPolicy(cryptoPolicy, signer, policy.DataSignature, at)
+}
+
+// checkPublicKeyPolicy checks the algorithm and the size of the public key
+// against the policy.
+func checkPublicKeyPolicy(cryptoPolicy *policy.Policy, publicKey *packet.PublicKey, context policy.Context, at time.Time) error {
+	var bits int
+	switch publicKey.PubKeyAlgo {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoRSASignOnly,
+		packet.PubKeyAlgoDSA, packet.PubKeyAlgoElGamal:
+		bitLength, err := publicKey.BitLength()
+		if err == nil {
+			bits = int(bitLength)
+		}
+	}
+	return cryptoPolicy.CheckPublicKey(publicKey.PubKeyAlgo, bits, context, at)
+}
+
+// checkPolicy checks the cipher and the decryption key of a decrypted
+// message against the policy of the handle, if any.
+func (dh *decryptionHandle) checkPolicy(md *openpgp.MessageDetails) error {
+	if dh.policy == nil {
+		return nil
+	}
+	now := dh.clock()
+	if md.DecryptedWithAlgorithm != 0 {
+		if err := dh.policy.CheckCipher(md.DecryptedWithAlgorithm, policy.Decryption, now); err != nil {
+			return err
+		}
+	}
+	if md.DecryptedWith.PublicKey != nil {
+		return checkPublicKeyPolicy(dh.policy, md.DecryptedWith.PublicKey, policy.Decryption, now)
+	}
+	return nil
+}
+
+// checkPolicy checks the ciphers that encrypting with the handle negotiates,
+// i.e., the cipher and, for SEIPDv2 data, the AEAD cipher, the encryption
+// keys of the recipients, and the signatures of the signing keys against the
+// policy of the handle, if any.
+func (eh *encryptionHandle) checkPolicy() error {
+	if eh.policy == nil {
+		return nil
+	}
+	negotiation, err := eh.NegotiateAlgorithms()
+	if err != nil {
+		return err
+	}
+	algos := []string{negotiation.Cipher}
+	if negotiation.SEIPDVersion == 2 {
+		algos = append(algos, negotiation.AEADCipher)
+	}
+	now := eh.clock()
+	for _, algo := range algos {
+		cipher, ok := symKeyAlgos[algo]
+		if !ok {
+			return errors.New("gopenpgp: unsupported cipher function")
+		}
+		if err := eh.policy.CheckCipher(cipher, policy.Encryption, now); err != nil {
+			return err
+		}
+	}
+	if err := eh.checkRecipientsPolicy(now); err != nil {
+		return err
+	}
+	return eh.checkSignersPolicy(now)
+}
+
+// checkSessionKeyPolicy checks the cipher of the session key and the
+// encryption keys of the recipients against the policy of the handle, if any.
+// If the session key has no algorithm, the cipher of the profile is checked.
+func (eh *encryptionHandle) checkSessionKeyPolicy(sessionKey *SessionKey) error {
+	if eh.policy == nil {
+		return nil
+	}
+	cipher := eh.profile.EncryptionConfig().Cipher()
+	if sessionKey != nil && sessionKey.hasAlgorithm() {
+		var err error
+		if cipher, err = sessionKey.GetCipherFunc(); err != nil {
+			return err
+		}
+	}
+	now := eh.clock()
+	if err := eh.policy.CheckCipher(cipher, policy.Encryption, now); err != nil {
+		return err
+	}
+	return eh.checkRecipientsPolicy(now)
+}
+
+// checkRecipientsPolicy checks the encryption keys of the recipients and
+// their self-signatures against the policy of the handle.
+func (eh *encryptionHandle) checkRecipientsPolicy(now time.Time) error {
+	config := eh.profile.EncryptionConfig()
+	config.Time = NewConstantClock(now.Unix())
+	for _, keyRing := range []*KeyRing{eh.Recipients, eh.HiddenRecipients} {
+		for _, entity := range keyRing.getEntities() {
+			if key, ok := entity.EncryptionKey(now, config); ok {
+				if err := checkPublicKeyPolicy(eh.policy, key.PublicKey, policy.Encryption, now); err != nil {
+					return err
+				}
+				if err := checkSelfSignaturesPolicy(eh.policy, &key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkSignersPolicy checks the signatures that the signing keys of the
+// handle create against the policy of the handle.
+// Embedded signatures in messages to recipients use the hashes that the
+// recipients prefer.
+func (eh *encryptionHandle) checkSignersPolicy(now time.Time) error {
+	if eh.SignKeyRing == nil {
+		return nil
+	}
+	signers, err := eh.SignKeyRing.signingEntities()
+	if err != nil {
+		return err
+	}
+	config := eh.profile.EncryptionConfig()
+	config.Time = NewConstantClock(now.Unix())
+	candidates := candidateSignatureHashes
+	recipients := slices.Concat(eh.Recipients.getEntities(), eh.HiddenRecipients.getEntities())
+	if len(recipients) > 0 && !eh.DetachedSignature && !eh.PlainDetachedSignature {
+		if candidates, err = recipientSignatureHashes(recipients, now, config); err != nil {
+			return err
+		}
+	}
+	return checkSigningPolicy(eh.policy, signers, candidates, config)
+}
+
+// checkPolicy checks the signatures that signing with the handle creates
+// against the policy of the handle, if any.
+func (sh *signatureHandle) checkPolicy(config *packet.Config) error {
+	if sh.policy == nil || sh.SignKeyRing == nil {
+		return nil
+	}
+	signers, err := sh.SignKeyRing.signingEntities()
+	if err != nil {
+		return err
+	}
+	return checkSigningPolicy(sh.policy, signers, candidateSignatureHashes, config)
+}
@@ -0,0 +1,306 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v3/constants"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/policy"
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+func TestPolicyDataSignature(t *testing.T) {
+	signer, _ := testPGP.Sign().SigningKeys(keyRingTestPrivate).New()
+	signature, err := signer.Sign([]byte(testMessageString), Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	encHandle, _ := testPGP.Encryption().Recipients(keyRingTestPublic).SigningKeys(keyRingTestPrivate).New()
+	message, err := encHandle.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+
+	// The signatures are created after the cutoff
+	cutoff := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	pgp := testPGP.WithPolicy(policy.New().RejectHash(crypto.SHA256, cutoff, policy.DataSignature))
+	verifier, _ := pgp.Verify().VerificationKeys(keyRingTestPublic).New()
+	verified, err := verifier.VerifyInline(signature, Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while verifying, got:", err)
+	}
+	assert.Exactly(t, testMessageString, verified.String())
+	var violation *policy.Violation
+	if !errors.As(verified.SignatureError(), &violation) {
+		t.Fatal("Expected a policy violation, got:", verified.SignatureError())
+	}
+	assert.Exactly(t, policy.DataSignature, violation.Context)
+	assert.Exactly(t, crypto.SHA256, violation.Hash)
+	assert.Exactly(t, constants.SIGNATURE_FAILED, verified.SignatureErrorExplicit().Status)
+
+	decHandle, _ := pgp.Decryption().DecryptionKeys(keyRingTestPrivate).VerificationKeys(keyRingTestPublic).New()
+	decrypted, err := decHandle.Decrypt(message.Bytes(), Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.True(t, errors.As(decrypted.SignatureError(), &violation))
+
+	// The self-signatures of the signing key are checked
+	selfSignature, err := keyRingTestPublic.entities[0].PrimarySelfSignature(time.Unix(testTime, 0), nil)
+	if err != nil {
+		t.Fatal("Expected no error while reading self-signature, got:", err)
+	}
+	pgp = testPGP.WithPolicy(policy.New().RejectHash(selfSignature.Hash, time.Time{}, policy.SelfSignature))
+	verifier, _ = pgp.Verify().VerificationKeys(keyRingTestPublic).New()
+	verified, err = verifier.VerifyInline(signature, Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while verifying, got:", err)
+	}
+	if !errors.As(verified.SignatureError(), &violation) {
+		t.Fatal("Expected a policy violation, got:", verified.SignatureError())
+	}
+	assert.Exactly(t, policy.SelfSignature, violation.Context)
+
+	// The signatures are accepted before the cutoff and by the default policy
+	cutoff = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, p := range []*policy.Policy{policy.New().RejectHash(crypto.SHA256, cutoff), policy.Default()} {
+		verifier, _ = testPGP.WithPolicy(p).Verify().VerificationKeys(keyRingTestPublic).New()
+		verified, err = verifier.VerifyInline(signature, Bytes)
+		if err != nil {
+			t.Fatal("Expected no error while verifying, got:", err)
+		}
+		assert.NoError(t, verified.SignatureError())
+	}
+}
+
+func TestPolicyCipher(t *testing.T) {
+	// The message is encrypted with 3DES and a password by GnuPG
+	message, err := NewPGPMessageFromArmored(readTestFile("message_3des", false))
+	if err != nil {
+		t.Fatal("Expected no error while reading message, got:", err)
+	}
+	password := []byte("password")
+
+	// 3DES is accepted for decryption, but rejected for encryption
+	pgp := testPGP.WithPolicy(policy.Default())
+	passwordDecHandle, _ := pgp.Decryption().Password(password).New()
+	sessionKey, err := passwordDecHandle.DecryptSessionKey(message.KeyPacket)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting session key, got:", err)
+	}
+	sessionKeyDecHandle, _ := pgp.Decryption().SessionKey(sessionKey).New()
+	for _, handle := range []PGPDecryption{passwordDecHandle, sessionKeyDecHandle} {
+		decrypted, err := handle.Decrypt(message.Bytes(), Bytes)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, "Hello, 3DES!", decrypted.String())
+	}
+
+	encHandle, _ := pgp.Encryption().SessionKey(sessionKey).New()
+	_, err = encHandle.Encrypt([]byte(testMessageString))
+	var violation *policy.Violation
+	if !errors.As(err, &violation) {
+		t.Fatal("Expected a policy violation, got:", err)
+	}
+	assert.Exactly(t, policy.Encryption, violation.Context)
+	assert.Exactly(t, packet.Cipher3DES, violation.Cipher)
+	encHandle, _ = pgp.Encryption().Recipients(keyRingTestPublic).New()
+	_, err = encHandle.EncryptSessionKey(sessionKey)
+	assert.True(t, errors.As(err, &violation))
+
+	// A policy can reject 3DES for decryption
+	pgp = testPGP.WithPolicy(policy.New().RejectCipher(packet.Cipher3DES, time.Time{}, policy.Decryption))
+	passwordDecHandle, _ = pgp.Decryption().Password(password).New()
+	sessionKeyDecHandle, _ = pgp.Decryption().SessionKey(sessionKey).New()
+	for _, handle := range []PGPDecryption{passwordDecHandle, sessionKeyDecHandle} {
+		_, err := handle.Decrypt(message.Bytes(), Bytes)
+		if !errors.As(err, &violation) {
+			t.Fatal("Expected a policy violation, got:", err)
+		}
+		assert.Exactly(t, policy.Decryption, violation.Context)
+	}
+}
+
+func TestPolicyNegotiatedCipher(t *testing.T) {
+	// The profiles encrypt with AES-256, but the recipient only prefers AES-128
+	rejectAES128 := policy.New().RejectCipher(packet.CipherAES128, time.Time{}, policy.Encryption)
+	testCases := []struct {
+		name        string
+		profile     *profile.Custom
+		preferences *KeyPreferences
+	}{
+		{
+			name: "SEIPDv1", profile: profile.RFC4880(),
+			preferences: &KeyPreferences{Ciphers: []byte{byte(packet.CipherAES128)}},
+		},
+		{
+			name: "SEIPDv2", profile: profile.RFC9580(),
+			preferences: &KeyPreferences{
+				Ciphers:      []byte{byte(packet.CipherAES128)},
+				CipherSuites: []byte{byte(packet.CipherAES128), byte(packet.AEADModeOCB)},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pgp := PGPWithProfile(testCase.profile)
+			key, err := pgp.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+			if err != nil {
+				t.Fatal("Expected no error while generating key, got:", err)
+			}
+			if key, err = pgp.SetKeyPreferences(key, testCase.preferences); err != nil {
+				t.Fatal("Expected no error while setting preferences, got:", err)
+			}
+			encHandle, _ := pgp.WithPolicy(rejectAES128).Encryption().Recipient(key).New()
+			_, err = encHandle.Encrypt([]byte(testMessageString))
+			var violation *policy.Violation
+			if !errors.As(err, &violation) {
+				t.Fatal("Expected a policy violation, got:", err)
+			}
+			assert.Exactly(t, policy.Encryption, violation.Context)
+			assert.Exactly(t, packet.CipherAES128, violation.Cipher)
+			if testCase.profile.AeadEncryption != nil {
+				_, err = encHandle.ResumableEncryptingWriter(&bytes.Buffer{}, []byte("test.bin"))
+				assert.True(t, errors.As(err, &violation))
+			}
+
+			// The negotiated cipher is accepted if the policy does not reject it
+			encHandle, _ = pgp.WithPolicy(policy.Default()).Encryption().Recipient(key).New()
+			if _, err = encHandle.Encrypt([]byte(testMessageString)); err != nil {
+				t.Fatal("Expected no error while encrypting, got:", err)
+			}
+		})
+	}
+}
+
+func TestPolicySigning(t *testing.T) {
+	for _, material := range testMaterialForProfiles {
+		t.Run(material.profileName, func(t *testing.T) {
+			// sign returns the hashes of the signatures or the error of each
+			// way to sign
+			sign := func(pgp *PGPHandle) (map[string][]crypto.Hash, map[string]error) {
+				hashes := make(map[string][]crypto.Hash)
+				errs := make(map[string]error)
+				for _, detached := range []bool{false, true} {
+					mode := fmt.Sprintf("detached=%t", detached)
+					builder := pgp.Sign().SigningKeys(material.keyRingTestPrivate)
+					if detached {
+						builder.Detached()
+					}
+					signer, _ := builder.New()
+					signature, err := signer.Sign([]byte(testMessageString), Bytes)
+					if err != nil {
+						errs[mode] = err
+						continue
+					}
+					hashes[mode] = readSignatureHashes(t, signature)
+				}
+
+				signer, _ := pgp.Sign().SigningKeys(material.keyRingTestPrivate).New()
+				if cleartext, err := signer.SignCleartext([]byte(testMessageString)); err != nil {
+					errs["cleartext"] = err
+				} else {
+					block, _ := clearsign.Decode(cleartext)
+					signature, err := io.ReadAll(block.ArmoredSignature.Body)
+					if err != nil {
+						t.Fatal("Expected no error while reading cleartext signature, got:", err)
+					}
+					hashes["cleartext"] = readSignatureHashes(t, signature)
+				}
+
+				encHandle, _ := pgp.Encryption().
+					Recipients(material.keyRingTestPublic).
+					SigningKeys(material.keyRingTestPrivate).
+					New()
+				if message, err := encHandle.Encrypt([]byte(testMessageString)); err != nil {
+					errs["encrypted"] = err
+				} else {
+					decHandle, _ := pgp.Decryption().DecryptionKeys(material.keyRingTestPrivate).New()
+					sessionKey, err := decHandle.DecryptSessionKey(message.KeyPacket)
+					if err != nil {
+						t.Fatal("Expected no error while decrypting session key, got:", err)
+					}
+					for _, signature := range readEncryptedSignatures(t, sessionKey, message.DataPacket) {
+						hashes["encrypted"] = append(hashes["encrypted"], signature.Hash)
+					}
+				}
+				return hashes, errs
+			}
+			hashes, errs := sign(material.pgp)
+			for mode, err := range errs {
+				t.Fatal("Expected no error while signing with", mode, "got:", err)
+			}
+
+			// The policy checks the hashes that go-crypto selects
+			for mode, signed := range hashes {
+				used := policy.New()
+				unused := policy.New()
+				for _, hash := range candidateSignatureHashes {
+					if slices.Contains(signed, hash) {
+						used.RejectHash(hash, time.Time{}, policy.DataSignature)
+					} else {
+						unused.RejectHash(hash, time.Time{}, policy.DataSignature)
+					}
+				}
+				_, errs = sign(material.pgp.WithPolicy(unused))
+				assert.NoError(t, errs[mode], mode)
+				_, errs = sign(material.pgp.WithPolicy(used))
+				var violation *policy.Violation
+				if !errors.As(errs[mode], &violation) {
+					t.Fatal("Expected a policy violation while signing with", mode, "got:", errs[mode])
+				}
+				assert.Exactly(t, policy.DataSignature, violation.Context)
+			}
+		})
+	}
+}
+
+func TestPolicyRecipientSelfSignature(t *testing.T) {
+	selfSignature, err := keyRingTestPublic.entities[0].PrimarySelfSignature(time.Unix(testTime, 0), nil)
+	if err != nil {
+		t.Fatal("Expected no error while reading self-signature, got:", err)
+	}
+	pgp := testPGP.WithPolicy(policy.New().RejectHash(selfSignature.Hash, time.Time{}, policy.SelfSignature))
+	encHandle, _ := pgp.Encryption().Recipients(keyRingTestPublic).New()
+	_, err = encHandle.Encrypt([]byte(testMessageString))
+	var violation *policy.Violation
+	if !errors.As(err, &violation) {
+		t.Fatal("Expected a policy violation, got:", err)
+	}
+	assert.Exactly(t, policy.SelfSignature, violation.Context)
+}
+
+// readSignatureHashes returns the hashes of the signature packets in the
+// unencrypted data.
+func readSignatureHashes(t *testing.T, data []byte) []crypto.Hash {
+	var hashes []crypto.Hash
+	packets := packet.NewReader(bytes.NewReader(data))
+	for {
+		p, err := packets.Next()
+		if errors.Is(err, io.EOF) {
+			return hashes
+		}
+		if err != nil {
+			t.Fatal("Expected no error while reading packets, got:", err)
+		}
+		if literal, ok := p.(*packet.LiteralData); ok {
+			if _, err := io.Copy(io.Discard, literal.Body); err != nil {
+				t.Fatal("Expected no error while reading literal data, got:", err)
+			}
+		}
+		if signature, ok := p.(*packet.Signature); ok {
+			hashes = append(hashes, signature.Hash)
+		}
+	}
+}
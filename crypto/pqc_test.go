@@ -0,0 +1,151 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v3/constants"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+// Test vectors of the OpenPGP PQC draft (draft-ietf-openpgp-pqc).
+var pqcTestVectors = []struct {
+	name         string
+	privateKey   string
+	publicKey    string
+	fingerprints []string
+	messages     []string
+}{
+	{
+		name:       "v6 ML-DSA-65 + Ed25519 with ML-KEM-768 + X25519",
+		privateKey: "pqc_v6_mldsa65_privateKey",
+		publicKey:  "pqc_v6_mldsa65_publicKey",
+		fingerprints: []string{
+			"a3e2e14b6a493ff930fb27321f125e9a6880338be9fb7da3ae065ea65793242f",
+			"7dae8fbce23022607167af72a002e774e0ca379a2d7ae072384e1e8fde3265e4",
+		},
+		messages: []string{"pqc_v6_mldsa65_message"},
+	},
+	{
+		name:       "v4 Ed25519 with ML-KEM-768 + X25519",
+		privateKey: "pqc_v4_mlkem768_privateKey",
+		publicKey:  "pqc_v4_mlkem768_publicKey",
+		fingerprints: []string{
+			"342e5db2de345215cb2c944f7102ffed3b9cf12d",
+			"e51dbfea51936988b5428fffa4f95f985ed61a51",
+		},
+		messages: []string{"pqc_v4_mlkem768_message_seipdv1", "pqc_v4_mlkem768_message_seipdv2"},
+	},
+}
+
+func TestPQCDraftVectors(t *testing.T) {
+	pgp := PGPWithProfile(profile.PostQuantum())
+	for _, vector := range pqcTestVectors {
+		privateKey, err := NewKeyFromArmored(readTestFile(vector.privateKey, false))
+		if err != nil {
+			t.Fatal("Expected no error while reading private key, got:", err)
+		}
+		publicKey, err := NewKeyFromArmored(readTestFile(vector.publicKey, false))
+		if err != nil {
+			t.Fatal("Expected no error while reading public key, got:", err)
+		}
+		fingerprints := []string{privateKey.GetFingerprint()}
+		for _, subkey := range privateKey.entity.Subkeys {
+			fingerprints = append(fingerprints, hex.EncodeToString(subkey.PublicKey.Fingerprint))
+		}
+		assert.Exactly(t, vector.fingerprints, fingerprints, vector.name)
+		assert.Exactly(t, privateKey.GetFingerprint(), publicKey.GetFingerprint(), vector.name)
+
+		decHandle, err := pgp.Decryption().DecryptionKey(privateKey).VerificationKey(publicKey).New()
+		if err != nil {
+			t.Fatal("Expected no error while creating decryption handle, got:", err)
+		}
+		for _, message := range vector.messages {
+			result, err := decHandle.Decrypt([]byte(readTestFile(message, false)), Armor)
+			if err != nil {
+				t.Fatal("Expected no error while decrypting "+message+", got:", err)
+			}
+			assert.Exactly(t, "Testing\n", string(result.Bytes()), message)
+			assert.NoError(t, result.SignatureError(), message)
+		}
+	}
+}
+
+func TestPQCGenerateKey(t *testing.T) {
+	pgp := PGPWithProfile(profile.PostQuantum())
+	for _, test := range []struct {
+		security   int8
+		signing    packet.PublicKeyAlgorithm
+		encryption packet.PublicKeyAlgorithm
+	}{
+		{constants.StandardSecurity, packet.PubKeyAlgoMldsa65Ed25519, packet.PubKeyAlgoMlkem768X25519},
+		{constants.HighSecurity, packet.PubKeyAlgoMldsa87Ed448, packet.PubKeyAlgoMlkem1024X448},
+	} {
+		key, err := pgp.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKeyWithSecurity(test.security)
+		if err != nil {
+			t.Fatal("Expected no error while generating post-quantum key, got:", err)
+		}
+		armored, err := key.Armor()
+		if err != nil {
+			t.Fatal("Expected no error while armoring key, got:", err)
+		}
+		key, err = NewKeyFromArmored(armored)
+		if err != nil {
+			t.Fatal("Expected no error while reading key, got:", err)
+		}
+		assert.Exactly(t, 6, key.GetVersion())
+		assert.Exactly(t, test.signing, key.entity.PrimaryKey.PubKeyAlgo)
+		if assert.Len(t, key.entity.Subkeys, 1) {
+			assert.Exactly(t, test.encryption, key.entity.Subkeys[0].PublicKey.PubKeyAlgo)
+		}
+		testPQCEncryptDecrypt(t, pgp, key)
+	}
+}
+
+func TestPQCEncryptionSubkey(t *testing.T) {
+	// ML-KEM-768 + X25519 subkeys can be used with v4 keys
+	key, err := testPGP.KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		AddEncryptionSubkey(KeyGenerationMLKEM768X25519, 0).
+		New().
+		GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	assert.Exactly(t, 4, key.GetVersion())
+	if assert.Len(t, key.entity.Subkeys, 1) {
+		assert.Exactly(t, packet.PublicKeyAlgorithm(packet.PubKeyAlgoMlkem768X25519), key.entity.Subkeys[0].PublicKey.PubKeyAlgo)
+	}
+	testPQCEncryptDecrypt(t, testPGP, key)
+
+	_, err = testPGP.KeyGeneration().
+		AddUserId(keyTestName, keyTestDomain).
+		AddEncryptionSubkey(KeyGenerationMLKEM1024X448, 0).
+		New().
+		GenerateKey()
+	assert.Error(t, err)
+}
+
+func testPQCEncryptDecrypt(t *testing.T, pgp *PGPHandle, key *Key) {
+	encHandle, err := pgp.Encryption().Recipient(key).SigningKey(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	message, err := encHandle.Encrypt([]byte(testMessage))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, err := pgp.Decryption().DecryptionKey(key).VerificationKey(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryption handle, got:", err)
+	}
+	result, err := decHandle.Decrypt(message.Bytes(), Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, testMessage, string(result.Bytes()))
+	assert.NoError(t, result.SignatureError())
+}
@@ -0,0 +1,135 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/eax"
+	"github.com/ProtonMail/go-crypto/ocb"
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Constants of the SEIPDv2 data of resumable encryption and decryption.
+const (
+	packetTagLiteralData = 11
+	packetTagSEIPD       = 18
+	seipdVersionAEAD     = 2
+	seipdSaltLength      = 32
+	aeadTagLength        = 16
+	// resumablePartLength is the length of the partial bodies of the
+	// packets written by resumable encryption.
+	resumablePartLength = 1 << 13
+)
+
+// seipdParameters are the parameters of SEIPDv2 data.
+type seipdParameters struct {
+	Cipher        uint8  `json:"cipher"`
+	Mode          uint8  `json:"mode"`
+	ChunkSizeByte uint8  `json:"chunkSizeByte"`
+	Salt          []byte `json:"salt"`
+}
+
+// header returns the fields that start the body of a SEIPDv2 packet.
+func (params *seipdParameters) header() []byte {
+	return append([]byte{seipdVersionAEAD, params.Cipher, params.Mode, params.ChunkSizeByte}, params.Salt...)
+}
+
+// chunkSize returns the size of the plaintext of all but the last chunk.
+func (params *seipdParameters) chunkSize() int {
+	return 1 << (params.ChunkSizeByte + 6)
+}
+
+// aeadChunkCipher seals and opens the chunks of SEIPDv2 data.
+type aeadChunkCipher struct {
+	aead           cipher.AEAD
+	noncePrefix    []byte
+	associatedData []byte
+}
+
+func newAEADChunkCipher(sessionKey []byte, params *seipdParameters) (*aeadChunkCipher, error) {
+	cipherFunc := packet.CipherFunction(params.Cipher)
+	switch cipherFunc {
+	case packet.CipherAES128, packet.CipherAES192, packet.CipherAES256:
+	default:
+		return nil, fmt.Errorf("gopenpgp: unsupported cipher for SEIPDv2: %d", params.Cipher)
+	}
+	if len(sessionKey) != cipherFunc.KeySize() {
+		return nil, errors.New("gopenpgp: wrong session key size")
+	}
+	if params.ChunkSizeByte > 16 {
+		return nil, fmt.Errorf("gopenpgp: invalid chunk size byte: %d", params.ChunkSizeByte)
+	}
+	if len(params.Salt) != seipdSaltLength {
+		return nil, errors.New("gopenpgp: invalid SEIPDv2 salt")
+	}
+	mode := packet.AEADMode(params.Mode)
+	if !mode.IsSupported() {
+		return nil, fmt.Errorf("gopenpgp: unsupported AEAD mode: %d", params.Mode)
+	}
+
+	associatedData := []byte{0xc0 | packetTagSEIPD, seipdVersionAEAD, params.Cipher, params.Mode, params.ChunkSizeByte}
+	derivedKey := make([]byte, len(sessionKey)+mode.IvLength()-8)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sessionKey, params.Salt, associatedData), derivedKey); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in deriving the message key: %w", err)
+	}
+	block, err := aes.NewCipher(derivedKey[:len(sessionKey)])
+	if err != nil {
+		return nil, err
+	}
+	var aead cipher.AEAD
+	switch mode {
+	case packet.AEADModeEAX:
+		aead, err = eax.NewEAX(block)
+	case packet.AEADModeOCB:
+		aead, err = ocb.NewOCB(block)
+	default:
+		aead, err = cipher.NewGCM(block)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &aeadChunkCipher{
+		aead:           aead,
+		noncePrefix:    derivedKey[len(sessionKey):],
+		associatedData: associatedData,
+	}, nil
+}
+
+func (c *aeadChunkCipher) nonce(index uint64) []byte {
+	return binary.BigEndian.AppendUint64(clone(c.noncePrefix), index)
+}
+
+// finalAssociatedData returns the associated data of the final tag, which
+// authenticates the total length of the plaintext.
+func (c *aeadChunkCipher) finalAssociatedData(length uint64) []byte {
+	return binary.BigEndian.AppendUint64(clone(c.associatedData), length)
+}
+
+func (c *aeadChunkCipher) sealChunk(index uint64, plaintext []byte) []byte {
+	return c.aead.Seal(nil, c.nonce(index), plaintext, c.associatedData)
+}
+
+func (c *aeadChunkCipher) openChunk(index uint64, ciphertext []byte) ([]byte, error) {
+	plaintext, err := c.aead.Open(nil, c.nonce(index), ciphertext, c.associatedData)
+	if err != nil {
+		return nil, errors.New("gopenpgp: chunk authentication failed")
+	}
+	return plaintext, nil
+}
+
+func (c *aeadChunkCipher) finalTag(index, length uint64) []byte {
+	return c.aead.Seal(nil, c.nonce(index), nil, c.finalAssociatedData(length))
+}
+
+func (c *aeadChunkCipher) verifyFinalTag(index, length uint64, tag []byte) error {
+	if _, err := c.aead.Open(nil, c.nonce(index), tag, c.finalAssociatedData(length)); err != nil {
+		return errors.New("gopenpgp: final authentication tag verification failed")
+	}
+	return nil
+}
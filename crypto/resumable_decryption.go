@@ -0,0 +1,420 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Stages of parsing the literal data packet in the decrypted data.
+const (
+	literalStageHeader = iota
+	literalStageBody
+	literalStageLength
+	literalStageEnd
+)
+
+// DecryptionCheckpoint is the state of a ResumableDecryptionReader at the end
+// of a decrypted chunk, from which decrypting the message can be resumed with
+// ResumeDecryptingReader.
+// It contains the session key of the message and must be kept secret.
+type DecryptionCheckpoint struct {
+	// PlaintextOffset is the number of plaintext bytes read before the
+	// checkpoint. After resuming, the plaintext is read from this offset on.
+	PlaintextOffset int64
+	// CiphertextOffset is the offset in the message of the chunk after the
+	// checkpoint. The message must be read from this offset on after
+	// resuming.
+	CiphertextOffset int64
+	state            *decryptionState
+}
+
+// decryptionState is the serialized state of a decryption checkpoint.
+type decryptionState struct {
+	SessionKey       []byte          `json:"sessionKey"`
+	Parameters       seipdParameters `json:"parameters"`
+	ChunkIndex       uint64          `json:"chunkIndex"`
+	CiphertextOffset int64           `json:"ciphertextOffset"`
+	PlaintextOffset  int64           `json:"plaintextOffset"`
+	// Remaining and Partial are the framing of the SEIPD packet, see
+	// partialBodyReader.
+	Remaining int64 `json:"remaining"`
+	Partial   bool  `json:"partial"`
+	// Literal is the framing of the literal data packet.
+	Literal literalParser `json:"literal"`
+}
+
+// NewDecryptionCheckpoint parses a checkpoint serialized with
+// DecryptionCheckpoint.Serialize.
+func NewDecryptionCheckpoint(data []byte) (*DecryptionCheckpoint, error) {
+	state := &decryptionState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in parsing checkpoint: %w", err)
+	}
+	if _, err := newAEADChunkCipher(state.SessionKey, &state.Parameters); err != nil {
+		return nil, err
+	}
+	return newDecryptionCheckpoint(state), nil
+}
+
+func newDecryptionCheckpoint(state *decryptionState) *DecryptionCheckpoint {
+	return &DecryptionCheckpoint{
+		PlaintextOffset:  state.PlaintextOffset,
+		CiphertextOffset: state.CiphertextOffset,
+		state:            state,
+	}
+}
+
+// Serialize returns the checkpoint in a format that can be stored, and
+// parsed again with NewDecryptionCheckpoint.
+func (checkpoint *DecryptionCheckpoint) Serialize() ([]byte, error) {
+	return json.Marshal(checkpoint.state)
+}
+
+// ResumableDecryptionReader decrypts a message with SEIPDv2 data, whose
+// state is saved in checkpoints at the end of each decrypted chunk, to resume
+// reading the message after an interruption.
+// Create one with PGPDecryption.ResumableDecryptingReader.
+type ResumableDecryptionReader struct {
+	input  *countingReader
+	body   *partialBodyReader
+	cipher *aeadChunkCipher
+	state  decryptionState
+	// carry are the bytes read after the current chunk to detect the last
+	// chunk.
+	carry     []byte
+	chunk     []byte
+	processed uint64
+	// buffer is the decrypted plaintext of the current chunk that has not
+	// been read yet.
+	buffer []byte
+	// checkpoint is the state at the end of the last chunk whose plaintext
+	// has been read, pending the state at the end of the current chunk.
+	checkpoint *decryptionState
+	pending    *decryptionState
+	done       bool
+}
+
+// ResumableDecryptingReader returns a reader that decrypts a binary message
+// with SEIPDv2 data, and saves its state in checkpoints at the end of each
+// chunk, see ResumableDecryptionReader.
+// The message must contain a literal data packet that is neither signed nor
+// compressed, as written by PGPEncryption.ResumableEncryptingWriter.
+// Each chunk is authenticated before its plaintext is returned, but
+// truncation of the message is only detected at its end.
+func (dh *decryptionHandle) ResumableDecryptingReader(input Reader) (*ResumableDecryptionReader, error) {
+	if err := dh.validate(); err != nil {
+		return nil, err
+	}
+	counter := &countingReader{reader: input}
+	var keyPackets bytes.Buffer
+	var tag uint8
+	var length int64
+	var partial bool
+	for {
+		var err error
+		header := &bytes.Buffer{}
+		tag, length, partial, err = readPacketHeader(io.TeeReader(counter, header))
+		if err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in reading message: %w", unexpectedEOF(err))
+		}
+		if tag == packetTagSEIPD {
+			break
+		}
+		if partial {
+			return nil, errors.New("gopenpgp: invalid key packet")
+		}
+		// Public-key and symmetric-key encrypted session key packets
+		switch tag {
+		case 1, 3:
+			keyPackets.Write(header.Bytes())
+			if _, err := io.CopyN(&keyPackets, counter, length); err != nil {
+				return nil, fmt.Errorf("gopenpgp: error in reading message: %w", unexpectedEOF(err))
+			}
+		case 10, packetTagPadding:
+			if _, err := io.CopyN(io.Discard, counter, length); err != nil {
+				return nil, fmt.Errorf("gopenpgp: error in reading message: %w", unexpectedEOF(err))
+			}
+		default:
+			return nil, errors.New("gopenpgp: resumable decryption requires a message with SEIPDv2 data")
+		}
+	}
+
+	body := &partialBodyReader{reader: counter, remaining: length, partial: partial}
+	header := make([]byte, 4+seipdSaltLength)
+	if _, err := io.ReadFull(body, header); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in reading message: %w", unexpectedEOF(err))
+	}
+	if header[0] != seipdVersionAEAD {
+		return nil, errors.New("gopenpgp: resumable decryption requires a message with SEIPDv2 data")
+	}
+	params := seipdParameters{
+		Cipher:        header[1],
+		Mode:          header[2],
+		ChunkSizeByte: header[3],
+		Salt:          header[4:],
+	}
+	sessionKey, err := dh.resumableSessionKey(keyPackets.Bytes(), &params)
+	if err != nil {
+		return nil, err
+	}
+	state := decryptionState{
+		SessionKey:       clone(sessionKey),
+		Parameters:       params,
+		CiphertextOffset: counter.offset,
+		Remaining:        body.remaining,
+		Partial:          body.partial,
+	}
+	return newResumableDecryptionReader(counter, body, state)
+}
+
+// resumableSessionKey returns the session key to decrypt the SEIPDv2 data,
+// either decrypted from the key packets or given by the handle.
+func (dh *decryptionHandle) resumableSessionKey(keyPackets []byte, params *seipdParameters) ([]byte, error) {
+	if len(keyPackets) > 0 && (dh.DecryptionKeyRing != nil || len(dh.Passwords) > 0) {
+		sessionKey, err := dh.DecryptSessionKey(keyPackets)
+		if err != nil {
+			return nil, err
+		}
+		return sessionKey.Key, nil
+	}
+	for _, sessionKey := range dh.SessionKeys {
+		if _, err := newAEADChunkCipher(sessionKey.Key, params); err == nil {
+			return sessionKey.Key, nil
+		}
+	}
+	return nil, errors.New("gopenpgp: no session key to decrypt the message")
+}
+
+// ResumeDecryptingReader returns a reader that resumes decrypting the message
+// of the checkpoint from the input, which must start at the ciphertext offset
+// of the checkpoint. The plaintext is read from the plaintext offset of the
+// checkpoint on.
+func ResumeDecryptingReader(input Reader, checkpoint *DecryptionCheckpoint) (*ResumableDecryptionReader, error) {
+	state := *checkpoint.state
+	state.SessionKey = clone(state.SessionKey)
+	state.Literal.Header = clone(state.Literal.Header)
+	state.Literal.Fields = clone(state.Literal.Fields)
+	counter := &countingReader{reader: input, offset: state.CiphertextOffset}
+	body := &partialBodyReader{reader: counter, remaining: state.Remaining, partial: state.Partial}
+	return newResumableDecryptionReader(counter, body, state)
+}
+
+func newResumableDecryptionReader(input *countingReader, body *partialBodyReader, state decryptionState) (*ResumableDecryptionReader, error) {
+	cipher, err := newAEADChunkCipher(state.SessionKey, &state.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := state
+	return &ResumableDecryptionReader{
+		input:      input,
+		body:       body,
+		cipher:     cipher,
+		state:      state,
+		chunk:      make([]byte, state.Parameters.chunkSize()+2*aeadTagLength),
+		processed:  state.ChunkIndex * uint64(state.Parameters.chunkSize()),
+		checkpoint: &checkpoint,
+	}, nil
+}
+
+// GetMetadata returns the metadata of the literal data packet, once it has
+// been read.
+func (r *ResumableDecryptionReader) GetMetadata() *LiteralMetadata {
+	return r.state.Literal.metadata()
+}
+
+// Read reads the decrypted plaintext.
+func (r *ResumableDecryptionReader) Read(b []byte) (int, error) {
+	for len(r.buffer) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.decryptChunk(); err != nil {
+			r.done = true
+			return 0, err
+		}
+	}
+	n := copy(b, r.buffer)
+	r.buffer = r.buffer[n:]
+	r.state.PlaintextOffset += int64(n)
+	r.commitCheckpoint()
+	return n, nil
+}
+
+// Checkpoint returns the state of the reader at the end of the last chunk
+// whose plaintext has been read completely.
+func (r *ResumableDecryptionReader) Checkpoint() *DecryptionCheckpoint {
+	state := *r.checkpoint
+	state.SessionKey = clone(state.SessionKey)
+	return newDecryptionCheckpoint(&state)
+}
+
+func (r *ResumableDecryptionReader) commitCheckpoint() {
+	if len(r.buffer) == 0 && r.pending != nil {
+		r.checkpoint, r.pending = r.pending, nil
+	}
+}
+
+// decryptChunk decrypts the next chunk into the buffer. A chunk is followed
+// by another chunk or the final tag, so a tag is read after each chunk.
+func (r *ResumableDecryptionReader) decryptChunk() error {
+	chunkLength := r.state.Parameters.chunkSize() + aeadTagLength
+	data := r.chunk[:copy(r.chunk, r.carry)]
+	n, err := io.ReadFull(r.body, r.chunk[len(data):chunkLength])
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("gopenpgp: error in reading message: %w", err)
+	}
+	data = r.chunk[:len(data)+n]
+	final := len(data) < chunkLength
+	framing := *r.body
+	offset := r.input.offset
+	if !final {
+		peeked, err := io.ReadFull(r.body, r.chunk[chunkLength:chunkLength+aeadTagLength])
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("gopenpgp: error in reading message: %w", err)
+		}
+		data = r.chunk[:chunkLength+peeked]
+		final = peeked < aeadTagLength
+		r.carry = append(r.carry[:0], data[chunkLength:]...)
+		data = data[:chunkLength]
+	}
+	if final {
+		if len(data) < aeadTagLength {
+			return errors.New("gopenpgp: the message is truncated")
+		}
+		data, r.carry = data[:len(data)-aeadTagLength], data[len(data)-aeadTagLength:]
+	}
+
+	var plaintext []byte
+	if len(data) > 0 {
+		if plaintext, err = r.cipher.openChunk(r.state.ChunkIndex, data); err != nil {
+			return err
+		}
+		r.state.ChunkIndex++
+		r.processed += uint64(len(plaintext))
+	}
+	if r.buffer, err = r.state.Literal.parse(plaintext); err != nil {
+		return err
+	}
+	if final {
+		if err := r.cipher.verifyFinalTag(r.state.ChunkIndex, r.processed, r.carry); err != nil {
+			return err
+		}
+		if r.state.Literal.Stage != literalStageEnd {
+			return errors.New("gopenpgp: the literal data packet is truncated")
+		}
+		r.done = true
+		return nil
+	}
+	checkpoint := r.state
+	checkpoint.PlaintextOffset += int64(len(r.buffer))
+	checkpoint.CiphertextOffset = offset
+	checkpoint.Remaining, checkpoint.Partial = framing.remaining, framing.partial
+	checkpoint.Literal.Header = clone(checkpoint.Literal.Header)
+	checkpoint.Literal.Fields = clone(checkpoint.Literal.Fields)
+	r.pending = &checkpoint
+	r.commitCheckpoint()
+	return nil
+}
+
+// literalParser parses the literal data packet in the decrypted data.
+type literalParser struct {
+	Stage int `json:"stage"`
+	// Header is the buffered packet header or partial body length.
+	Header []byte `json:"header,omitempty"`
+	// Fields are the literal fields at the start of the body.
+	Fields    []byte `json:"fields,omitempty"`
+	Remaining int64  `json:"remaining"`
+	Partial   bool   `json:"partial"`
+}
+
+// parse parses the decrypted data and returns the literal data in it.
+func (p *literalParser) parse(data []byte) (literal []byte, err error) {
+	for len(data) > 0 {
+		switch p.Stage {
+		case literalStageHeader, literalStageLength:
+			p.Header = append(p.Header, data[0])
+			data = data[1:]
+			if err := p.parseHeader(); err != nil {
+				return nil, err
+			}
+		case literalStageBody:
+			n := min(int64(len(data)), p.Remaining)
+			body := data[:n]
+			data = data[n:]
+			p.Remaining -= n
+			for len(body) > 0 && len(p.Fields) < p.fieldsLength() {
+				fields := min(len(body), p.fieldsLength()-len(p.Fields))
+				p.Fields = append(p.Fields, body[:fields]...)
+				body = body[fields:]
+			}
+			literal = append(literal, body...)
+			if p.Remaining == 0 {
+				p.endBody()
+			}
+		default:
+			return nil, errors.New("gopenpgp: unexpected data after the literal data packet")
+		}
+	}
+	return literal, nil
+}
+
+// parseHeader parses the buffered packet header or partial body length once
+// it is complete.
+func (p *literalParser) parseHeader() (err error) {
+	var tag uint8
+	if p.Stage == literalStageLength {
+		p.Remaining, p.Partial, err = readNewFormatLength(bytes.NewReader(p.Header))
+	} else {
+		tag, p.Remaining, p.Partial, err = readPacketHeader(bytes.NewReader(p.Header))
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		// The header is not complete yet
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if p.Stage == literalStageHeader && tag != packetTagLiteralData {
+		return errors.New("gopenpgp: resumable decryption requires a message with unsigned and uncompressed literal data")
+	}
+	p.Header = nil
+	p.Stage = literalStageBody
+	if p.Remaining == 0 {
+		p.endBody()
+	}
+	return nil
+}
+
+// endBody advances to the next partial body or the end of the packet.
+func (p *literalParser) endBody() {
+	p.Stage = literalStageEnd
+	if p.Partial {
+		p.Stage = literalStageLength
+	}
+}
+
+// fieldsLength returns the length of the literal fields, as far as known.
+func (p *literalParser) fieldsLength() int {
+	if len(p.Fields) < 2 {
+		return 2
+	}
+	return 6 + int(p.Fields[1])
+}
+
+// metadata returns the metadata in the literal fields, or nil if they have
+// not been parsed yet.
+func (p *literalParser) metadata() *LiteralMetadata {
+	if len(p.Fields) < 2 || len(p.Fields) < p.fieldsLength() {
+		return nil
+	}
+	nameLength := int(p.Fields[1])
+	return NewFileMetadata(
+		p.Fields[0] == 'u' || p.Fields[0] == 't',
+		string(p.Fields[2:2+nameLength]),
+		int64(binary.BigEndian.Uint32(p.Fields[2+nameLength:])),
+	)
+}
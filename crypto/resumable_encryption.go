@@ -0,0 +1,393 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ProtonMail/gopenpgp/v3/constants"
+)
+
+// EncryptionCheckpoint is the state of a ResumableEncryptionWriter at the end
+// of an encrypted chunk, from which writing the same message can be resumed
+// with ResumeEncryptingWriter.
+// It contains the session key of the message and must be kept secret.
+// It records a digest of the source ID of the plaintext, so that it only
+// resumes encrypting the same plaintext.
+type EncryptionCheckpoint struct {
+	// PlaintextOffset is the number of plaintext bytes before the checkpoint.
+	// After resuming, the plaintext must be written from this offset on.
+	PlaintextOffset int64
+	// CiphertextOffset is the number of message bytes written before the
+	// checkpoint. Before resuming, the output must be truncated to this
+	// offset, e.g. by resuming an upload at this offset.
+	CiphertextOffset int64
+	state            *encryptionState
+}
+
+// encryptionState is the serialized state of an encryption checkpoint.
+type encryptionState struct {
+	SessionKey       []byte          `json:"sessionKey"`
+	Parameters       seipdParameters `json:"parameters"`
+	LiteralFields    []byte          `json:"literalFields"`
+	ChunkIndex       uint64          `json:"chunkIndex"`
+	CiphertextOffset int64           `json:"ciphertextOffset"`
+	// SourceDigest is the SHA-256 digest of the source ID of the plaintext.
+	SourceDigest []byte `json:"sourceDigest"`
+	// Pending is the ciphertext of the next partial body of the SEIPD
+	// packet written so far.
+	Pending []byte `json:"pending"`
+}
+
+// NewEncryptionCheckpoint parses a checkpoint serialized with
+// EncryptionCheckpoint.Serialize.
+func NewEncryptionCheckpoint(data []byte) (*EncryptionCheckpoint, error) {
+	state := &encryptionState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in parsing checkpoint: %w", err)
+	}
+	if _, err := newAEADChunkCipher(state.SessionKey, &state.Parameters); err != nil {
+		return nil, err
+	}
+	if len(state.LiteralFields) < 6 || len(state.Pending) >= resumablePartLength || len(state.SourceDigest) != sha256.Size {
+		return nil, errors.New("gopenpgp: invalid checkpoint")
+	}
+	return newEncryptionCheckpoint(state), nil
+}
+
+func newEncryptionCheckpoint(state *encryptionState) *EncryptionCheckpoint {
+	position := literalPositionAt(state.ChunkIndex * uint64(state.Parameters.chunkSize()))
+	return &EncryptionCheckpoint{
+		PlaintextOffset:  max(position.bodyOffset-int64(len(state.LiteralFields)), 0),
+		CiphertextOffset: state.CiphertextOffset,
+		state:            state,
+	}
+}
+
+// Serialize returns the checkpoint in a format that can be stored, and
+// parsed again with NewEncryptionCheckpoint.
+func (checkpoint *EncryptionCheckpoint) Serialize() ([]byte, error) {
+	return json.Marshal(checkpoint.state)
+}
+
+// ResumableEncryptionWriter encrypts a message with SEIPDv2 data, whose
+// state is saved in checkpoints at the end of each encrypted chunk, to resume
+// writing the same message after an interruption.
+// Create one with PGPEncryption.ResumableEncryptingWriter.
+type ResumableEncryptionWriter struct {
+	output Writer
+	cipher *aeadChunkCipher
+	state  encryptionState
+	// literal is the buffered next partial body of the literal data packet.
+	literal []byte
+	// partRemaining is the length of the rest of a partial body of the
+	// literal data packet whose length was written before resuming.
+	partRemaining int
+	// chunk is the buffered plaintext of the next chunk.
+	chunk     []byte
+	processed uint64
+	// pending is the buffered next partial body of the SEIPD packet.
+	pending []byte
+	err     error
+	closed  bool
+}
+
+// ResumableEncryptingWriter returns a writer that encrypts the plaintext
+// written to it into a binary message with SEIPDv2 data, and saves its state
+// in checkpoints at the end of each chunk, see ResumableEncryptionWriter.
+// The handle must not sign, compress or pad the message and must encrypt
+// binary data, and the recipients must support SEIPDv2.
+// The chunk size of the profile bounds the plaintext written again after
+// resuming.
+// The non-empty sourceID identifies the plaintext, see ResumeEncryptingWriter.
+func (eh *encryptionHandle) ResumableEncryptingWriter(output Writer, sourceID []byte) (*ResumableEncryptionWriter, error) {
+	if err := eh.validate(); err != nil {
+		return nil, err
+	}
+	if len(sourceID) == 0 {
+		return nil, errors.New("gopenpgp: resumable encryption requires a source ID")
+	}
+	if eh.SignKeyRing != nil || eh.DetachedSignature || eh.PlainDetachedSignature || eh.ExternalSignature != nil ||
+		eh.Compression != constants.NoCompression || eh.Padding != nil || eh.IsUTF8 {
+		return nil, errors.New("gopenpgp: resumable encryption does not support signatures, compression, padding or text messages")
+	}
+	config := eh.profile.EncryptionConfig()
+	config.Time = NewConstantClock(eh.clock().Unix())
+	state := encryptionState{
+		// Binary literal data without file name and time
+		LiteralFields: []byte{'b', 0, 0, 0, 0, 0},
+		SourceDigest:  sourceDigest(sourceID),
+		Parameters: seipdParameters{
+			Mode:          uint8(config.AEAD().Mode()),
+			ChunkSizeByte: config.AEAD().ChunkSizeByte(),
+			Salt:          make([]byte, seipdSaltLength),
+		},
+	}
+	if _, err := config.Random().Read(state.Parameters.Salt); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in generating salt: %w", err)
+	}
+	var keyPackets []byte
+	encrypt := func() (err error) {
+		sessionKey := eh.SessionKey
+		if !sessionKey.v6 {
+			return errors.New("gopenpgp: resumable encryption requires SEIPDv2, which is not supported by the recipients or the profile")
+		}
+		if err := eh.checkSessionKeyPolicy(sessionKey); err != nil {
+			return err
+		}
+		cipher := config.Cipher()
+		if sessionKey.hasAlgorithm() {
+			if cipher, err = sessionKey.GetCipherFunc(); err != nil {
+				return fmt.Errorf("gopenpgp: unable to encrypt with session key: %w", err)
+			}
+		}
+		state.Parameters.Cipher = uint8(cipher)
+		state.SessionKey = clone(sessionKey.Key)
+		return nil
+	}
+	if eh.Recipients == nil && eh.HiddenRecipients == nil && eh.Password == nil {
+		if err := encrypt(); err != nil {
+			return nil, err
+		}
+	} else {
+		var keyPacketWriter bytes.Buffer
+		if _, err := eh.encryptSessionKeyAndStream(&keyPacketWriter, func() (io.WriteCloser, error) {
+			return nil, encrypt()
+		}); err != nil {
+			return nil, err
+		}
+		keyPackets = keyPacketWriter.Bytes()
+	}
+
+	w, err := newResumableEncryptionWriter(output, state)
+	if err != nil {
+		return nil, err
+	}
+	w.writeOutput(append(keyPackets, 0xc0|packetTagSEIPD))
+	w.writeCiphertext(state.Parameters.header())
+	w.startLiteral()
+	if w.err != nil {
+		return nil, w.err
+	}
+	return w, nil
+}
+
+// ResumeEncryptingWriter returns a writer that resumes writing the message of
+// the checkpoint to the output, which must have been truncated to the
+// ciphertext offset of the checkpoint. The plaintext must be written from the
+// plaintext offset of the checkpoint on.
+//
+// The plaintext must be byte-identical to the plaintext written before the
+// interruption: the chunks after the checkpoint are encrypted again with the
+// same nonces, so different plaintext breaks the confidentiality of the
+// message if ciphertext written after the checkpoint was exposed.
+// The sourceID must be the one the message was started with, and must
+// change whenever the plaintext changes, e.g. a digest of the file content,
+// or its path, size and modification time. Resuming with a different source
+// ID fails.
+func ResumeEncryptingWriter(output Writer, checkpoint *EncryptionCheckpoint, sourceID []byte) (*ResumableEncryptionWriter, error) {
+	if subtle.ConstantTimeCompare(sourceDigest(sourceID), checkpoint.state.SourceDigest) != 1 {
+		return nil, errors.New("gopenpgp: the source ID does not match the checkpoint")
+	}
+	state := *checkpoint.state
+	state.SessionKey = clone(state.SessionKey)
+	w, err := newResumableEncryptionWriter(output, state)
+	if err != nil {
+		return nil, err
+	}
+	w.pending = append(w.pending, state.Pending...)
+	w.processed = state.ChunkIndex * uint64(state.Parameters.chunkSize())
+	w.startLiteral()
+	if w.err != nil {
+		return nil, w.err
+	}
+	return w, nil
+}
+
+// sourceDigest returns the digest of the source ID stored in checkpoints.
+func sourceDigest(sourceID []byte) []byte {
+	digest := sha256.Sum256(sourceID)
+	return digest[:]
+}
+
+func newResumableEncryptionWriter(output Writer, state encryptionState) (*ResumableEncryptionWriter, error) {
+	cipher, err := newAEADChunkCipher(state.SessionKey, &state.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	state.Pending = nil
+	return &ResumableEncryptionWriter{
+		output:  output,
+		cipher:  cipher,
+		state:   state,
+		literal: make([]byte, 0, resumablePartLength),
+		chunk:   make([]byte, 0, state.Parameters.chunkSize()),
+		pending: make([]byte, 0, resumablePartLength),
+	}, nil
+}
+
+// Write encrypts the plaintext. Plaintext after the last chunk is buffered
+// until the chunk is complete.
+func (w *ResumableEncryptionWriter) Write(b []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("gopenpgp: write to closed writer")
+	}
+	w.writeLiteral(b)
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(b), nil
+}
+
+// Checkpoint returns the state of the writer at the end of the last
+// encrypted chunk. The plaintext written after the checkpoint must be written
+// again after resuming.
+func (w *ResumableEncryptionWriter) Checkpoint() (*EncryptionCheckpoint, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+	if w.closed {
+		return nil, errors.New("gopenpgp: the writer is closed")
+	}
+	state := w.state
+	state.SessionKey = clone(state.SessionKey)
+	state.Pending = clone(w.pending)
+	return newEncryptionCheckpoint(&state), nil
+}
+
+// Close encrypts the buffered plaintext and writes the end of the message.
+func (w *ResumableEncryptionWriter) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	if w.partRemaining > 0 {
+		w.err = errors.New("gopenpgp: the plaintext ends before the plaintext offset of the checkpoint")
+		return w.err
+	}
+	w.writeStream(newFormatLength(len(w.literal)))
+	w.writeStream(w.literal)
+	if len(w.chunk) > 0 {
+		w.sealChunk()
+	}
+	w.writeCiphertext(w.cipher.finalTag(w.state.ChunkIndex, w.processed))
+	w.writeOutput(append(newFormatLength(len(w.pending)), w.pending...))
+	return w.err
+}
+
+// literalPosition is a position in the literal data packet.
+type literalPosition struct {
+	// started is false before the header of the packet.
+	started bool
+	// bodyOffset is the offset in the packet body, which starts with the
+	// literal fields.
+	bodyOffset int64
+	// partRemaining is the length of the rest of the current partial body
+	// after its length.
+	partRemaining int
+}
+
+// literalPositionAt returns the position in the literal data packet at the
+// offset in the encrypted plaintext, before the last partial body.
+// The literal data packet starts with its tag, which is followed by partial
+// bodies of resumablePartLength bytes that each start with their length.
+func literalPositionAt(offset uint64) literalPosition {
+	if offset == 0 {
+		return literalPosition{}
+	}
+	part, partOffset := (offset-1)/(resumablePartLength+1), (offset-1)%(resumablePartLength+1)
+	position := literalPosition{started: true, bodyOffset: int64(part * resumablePartLength)}
+	if partOffset > 0 {
+		position.bodyOffset += int64(partOffset) - 1
+		position.partRemaining = resumablePartLength - int(partOffset) + 1
+	}
+	return position
+}
+
+// startLiteral writes the start of the literal data packet up to the
+// position of the plaintext offset.
+func (w *ResumableEncryptionWriter) startLiteral() {
+	fields := w.state.LiteralFields
+	position := literalPositionAt(w.processed)
+	if !position.started {
+		w.writeStream([]byte{0xc0 | packetTagLiteralData})
+	}
+	w.partRemaining = position.partRemaining
+	if position.bodyOffset < int64(len(fields)) {
+		w.writeLiteral(fields[position.bodyOffset:])
+	}
+}
+
+// writeLiteral writes the body of the literal data packet.
+func (w *ResumableEncryptionWriter) writeLiteral(b []byte) {
+	for len(b) > 0 && w.err == nil {
+		if w.partRemaining > 0 {
+			n := min(len(b), w.partRemaining)
+			w.writeStream(b[:n])
+			w.partRemaining -= n
+			b = b[n:]
+			continue
+		}
+		n := min(len(b), resumablePartLength-len(w.literal))
+		w.literal = append(w.literal, b[:n]...)
+		b = b[n:]
+		if len(w.literal) == resumablePartLength {
+			w.writeStream([]byte{partialLength(resumablePartLength)})
+			w.writeStream(w.literal)
+			w.literal = w.literal[:0]
+		}
+	}
+}
+
+// writeStream writes the plaintext of the chunks.
+func (w *ResumableEncryptionWriter) writeStream(b []byte) {
+	for len(b) > 0 && w.err == nil {
+		n := min(len(b), cap(w.chunk)-len(w.chunk))
+		w.chunk = append(w.chunk, b[:n]...)
+		b = b[n:]
+		if len(w.chunk) == cap(w.chunk) {
+			w.sealChunk()
+		}
+	}
+}
+
+func (w *ResumableEncryptionWriter) sealChunk() {
+	w.writeCiphertext(w.cipher.sealChunk(w.state.ChunkIndex, w.chunk))
+	w.state.ChunkIndex++
+	w.processed += uint64(len(w.chunk))
+	w.chunk = w.chunk[:0]
+}
+
+// writeCiphertext writes the body of the SEIPD packet.
+func (w *ResumableEncryptionWriter) writeCiphertext(b []byte) {
+	for len(b) > 0 && w.err == nil {
+		n := min(len(b), resumablePartLength-len(w.pending))
+		w.pending = append(w.pending, b[:n]...)
+		b = b[n:]
+		if len(w.pending) == resumablePartLength {
+			w.writeOutput(append([]byte{partialLength(resumablePartLength)}, w.pending...))
+			w.pending = w.pending[:0]
+		}
+	}
+}
+
+func (w *ResumableEncryptionWriter) writeOutput(b []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.output.Write(b)
+	w.state.CiphertextOffset += int64(n)
+	if err == nil && n < len(b) {
+		err = errors.New("gopenpgp: short write")
+	}
+	if err != nil {
+		w.err = fmt.Errorf("gopenpgp: error in writing message: %w", err)
+	}
+}
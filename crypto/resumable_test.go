@@ -0,0 +1,290 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+// resumableTestSource identifies the plaintext of resumable test messages.
+var resumableTestSource = []byte("test.bin")
+
+func resumableTestHandle(mode packet.AEADMode, chunkSize uint64) *PGPHandle {
+	testProfile := profile.RFC9580()
+	testProfile.AeadEncryption = &packet.AEADConfig{DefaultMode: mode, ChunkSize: chunkSize}
+	return PGPWithProfile(testProfile)
+}
+
+func resumableTestData(t *testing.T) []byte {
+	data := make([]byte, 100000)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal("Expected no error while generating data, got:", err)
+	}
+	return data
+}
+
+func TestResumableEncryption(t *testing.T) {
+	data := resumableTestData(t)
+	key, err := PGPWithProfile(profile.RFC9580()).KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	for _, test := range []struct {
+		mode      packet.AEADMode
+		chunkSize uint64
+		password  bool
+	}{
+		{packet.AEADModeOCB, 64, false},
+		{packet.AEADModeGCM, 1024, false},
+		{packet.AEADModeEAX, 1 << 14, true},
+	} {
+		pgp := resumableTestHandle(test.mode, test.chunkSize)
+		encryptionBuilder, decryptionBuilder := pgp.Encryption().Recipient(key), pgp.Decryption().DecryptionKey(key)
+		if test.password {
+			encryptionBuilder, decryptionBuilder = pgp.Encryption().Password([]byte("password")), pgp.Decryption().Password([]byte("password"))
+		}
+		encHandle, err := encryptionBuilder.New()
+		if err != nil {
+			t.Fatal("Expected no error while creating encryption handle, got:", err)
+		}
+		decHandle, err := decryptionBuilder.New()
+		if err != nil {
+			t.Fatal("Expected no error while creating decryption handle, got:", err)
+		}
+
+		var ciphertext bytes.Buffer
+		writer, err := encHandle.ResumableEncryptingWriter(&ciphertext, resumableTestSource)
+		if err != nil {
+			t.Fatal("Expected no error while creating resumable writer, got:", err)
+		}
+		if _, err := writer.Write(data[:40000]); err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		checkpoint, err := writer.Checkpoint()
+		if err != nil {
+			t.Fatal("Expected no error while saving checkpoint, got:", err)
+		}
+		serialized, err := checkpoint.Serialize()
+		if err != nil {
+			t.Fatal("Expected no error while serializing checkpoint, got:", err)
+		}
+		if _, err := writer.Write(data[40000:]); err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal("Expected no error while closing resumable writer, got:", err)
+		}
+
+		decrypted, err := decHandle.Decrypt(ciphertext.Bytes(), Bytes)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, data, decrypted.Bytes())
+
+		parsed, err := NewEncryptionCheckpoint(serialized)
+		if err != nil {
+			t.Fatal("Expected no error while parsing checkpoint, got:", err)
+		}
+		assert.LessOrEqual(t, parsed.PlaintextOffset, int64(40000))
+		assert.Greater(t, parsed.PlaintextOffset, int64(40000-resumablePartLength)-int64(test.chunkSize))
+		resumed := bytes.NewBuffer(clone(ciphertext.Bytes()[:parsed.CiphertextOffset]))
+		// A different source of the plaintext is refused
+		_, err = ResumeEncryptingWriter(resumed, parsed, []byte("other.bin"))
+		assert.Error(t, err)
+		resumedWriter, err := ResumeEncryptingWriter(resumed, parsed, resumableTestSource)
+		if err != nil {
+			t.Fatal("Expected no error while resuming writer, got:", err)
+		}
+		if _, err := resumedWriter.Write(data[parsed.PlaintextOffset:]); err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		if err := resumedWriter.Close(); err != nil {
+			t.Fatal("Expected no error while closing resumable writer, got:", err)
+		}
+		assert.Exactly(t, ciphertext.Bytes(), resumed.Bytes())
+	}
+}
+
+func TestResumableDecryption(t *testing.T) {
+	data := resumableTestData(t)
+	pgp := resumableTestHandle(packet.AEADModeGCM, 1024)
+	key, err := pgp.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	encHandle, err := pgp.Encryption().Recipient(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	decHandle, err := pgp.Decryption().DecryptionKey(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryption handle, got:", err)
+	}
+	message, err := encHandle.Encrypt(data)
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	ciphertext := message.Bytes()
+
+	reader, err := decHandle.ResumableDecryptingReader(bytes.NewReader(ciphertext))
+	if err != nil {
+		t.Fatal("Expected no error while creating resumable reader, got:", err)
+	}
+	start := make([]byte, 30000)
+	if _, err := io.ReadFull(reader, start); err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	checkpoint := reader.Checkpoint()
+	serialized, err := checkpoint.Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing checkpoint, got:", err)
+	}
+	rest, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, data, append(start, rest...))
+	assert.False(t, reader.GetMetadata().IsUtf8())
+
+	parsed, err := NewDecryptionCheckpoint(serialized)
+	if err != nil {
+		t.Fatal("Expected no error while parsing checkpoint, got:", err)
+	}
+	assert.LessOrEqual(t, parsed.PlaintextOffset, int64(30000))
+	assert.Greater(t, parsed.PlaintextOffset, int64(30000-1024))
+	resumedReader, err := ResumeDecryptingReader(bytes.NewReader(ciphertext[parsed.CiphertextOffset:]), parsed)
+	if err != nil {
+		t.Fatal("Expected no error while resuming reader, got:", err)
+	}
+	rest, err = io.ReadAll(resumedReader)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, data[parsed.PlaintextOffset:], rest)
+
+	// The message of a resumable writer decrypts in chunks as well
+	var resumableCiphertext bytes.Buffer
+	writer, err := encHandle.ResumableEncryptingWriter(&resumableCiphertext, resumableTestSource)
+	if err != nil {
+		t.Fatal("Expected no error while creating resumable writer, got:", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing resumable writer, got:", err)
+	}
+	reader, err = decHandle.ResumableDecryptingReader(&resumableCiphertext)
+	if err != nil {
+		t.Fatal("Expected no error while creating resumable reader, got:", err)
+	}
+	decrypted, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, data, decrypted)
+}
+
+func TestResumableEncryptionEmpty(t *testing.T) {
+	pgp := resumableTestHandle(packet.AEADModeOCB, 0)
+	encHandle, err := pgp.Encryption().Password([]byte("password")).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	decHandle, err := pgp.Decryption().Password([]byte("password")).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryption handle, got:", err)
+	}
+	var ciphertext bytes.Buffer
+	writer, err := encHandle.ResumableEncryptingWriter(&ciphertext, resumableTestSource)
+	if err != nil {
+		t.Fatal("Expected no error while creating resumable writer, got:", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing resumable writer, got:", err)
+	}
+	decrypted, err := decHandle.Decrypt(ciphertext.Bytes(), Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Empty(t, decrypted.Bytes())
+	reader, err := decHandle.ResumableDecryptingReader(&ciphertext)
+	if err != nil {
+		t.Fatal("Expected no error while creating resumable reader, got:", err)
+	}
+	plaintext, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Empty(t, plaintext)
+}
+
+func TestResumableErrors(t *testing.T) {
+	// SEIPDv1 is not resumable
+	encHandle, err := testPGP.Encryption().Recipients(keyRingTestPublic).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	_, err = encHandle.ResumableEncryptingWriter(&bytes.Buffer{}, resumableTestSource)
+	assert.Error(t, err)
+	message, err := encHandle.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, err := testPGP.Decryption().DecryptionKeys(keyRingTestPrivate).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryption handle, got:", err)
+	}
+	_, err = decHandle.ResumableDecryptingReader(bytes.NewReader(message.Bytes()))
+	assert.Error(t, err)
+
+	pgp := resumableTestHandle(packet.AEADModeGCM, 1024)
+	key, err := pgp.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	signingHandle, err := pgp.Encryption().Recipient(key).SigningKey(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	_, err = signingHandle.ResumableEncryptingWriter(&bytes.Buffer{}, resumableTestSource)
+	assert.Error(t, err)
+
+	encHandle, err = pgp.Encryption().Recipient(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	// The plaintext must have a source ID
+	_, err = encHandle.ResumableEncryptingWriter(&bytes.Buffer{}, nil)
+	assert.Error(t, err)
+	decHandle, err = pgp.Decryption().DecryptionKey(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryption handle, got:", err)
+	}
+	message, err = encHandle.Encrypt(resumableTestData(t))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	ciphertext := message.Bytes()
+	tampered := clone(ciphertext)
+	tampered[len(tampered)/2] ^= 1
+	for _, corrupted := range [][]byte{tampered, ciphertext[:len(ciphertext)-10], ciphertext[:len(ciphertext)-2000]} {
+		reader, err := decHandle.ResumableDecryptingReader(bytes.NewReader(corrupted))
+		if err != nil {
+			t.Fatal("Expected no error while creating resumable reader, got:", err)
+		}
+		_, err = io.ReadAll(reader)
+		assert.Error(t, err)
+	}
+
+	_, err = NewEncryptionCheckpoint([]byte("{}"))
+	assert.Error(t, err)
+	_, err = NewDecryptionCheckpoint([]byte("{}"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,259 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// Tags of the packets before and of the encrypted data packet.
+const (
+	packetTagEncryptedKey           = 1
+	packetTagSymmetricKeyEncrypted  = 3
+	packetTagMarker                 = 10
+	packetTagSymmetricallyEncrypted = 9
+	packetTagAEADEncrypted          = 20
+)
+
+// RewrapChanges lists the recipients and passwords to add to or to remove
+// from the key packets of an encrypted message.
+type RewrapChanges struct {
+	// AddRecipients are the keys to encrypt the session key to.
+	AddRecipients *KeyRing
+	// AddHiddenRecipients are the keys to encrypt the session key to,
+	// without their key ids in the key packets.
+	AddHiddenRecipients *KeyRing
+	// AddPasswords are the passwords to encrypt the session key with.
+	AddPasswords [][]byte
+	// RemoveRecipients are the keys whose key packets are removed.
+	// Key packets of hidden recipients cannot be attributed and are kept.
+	RemoveRecipients *KeyRing
+	// RemovePasswords are the passwords whose key packets are removed.
+	RemovePasswords [][]byte
+}
+
+// Rewrap changes the recipients and passwords of an encrypted message
+// without decrypting its data. The decryption handle decrypts the session
+// key from the key packets of the message and must contain a decryption key
+// or a password. The returned message has new key packets according to the
+// changes, and the unchanged data packet.
+// The key packets are compatible with the data packet, i.e., the session key
+// of SEIPDv2 data is encrypted in v6 key packets and the session key of
+// SEIPDv1 data in v3 and v4 key packets.
+func (p *PGPHandle) Rewrap(message *PGPMessage, decryption PGPDecryption, changes *RewrapChanges) (*PGPMessage, error) {
+	v6, err := readDataPacketStart(bytes.NewReader(message.DataPacket), io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	keyPackets, err := p.rewrapKeyPackets(message.KeyPacket, v6, decryption, changes)
+	if err != nil {
+		return nil, err
+	}
+	return &PGPMessage{
+		KeyPacket:                keyPackets,
+		DataPacket:               message.DataPacket,
+		DetachedSignature:        message.DetachedSignature,
+		detachedSignatureIsPlain: message.detachedSignatureIsPlain,
+		omitArmorChecksum:        message.omitArmorChecksum,
+	}, nil
+}
+
+// RewrapStream is the streaming variant of Rewrap. It reads the binary
+// message from the input and writes the message with the new key packets to
+// the output, copying the data packet as it is read.
+func (p *PGPHandle) RewrapStream(output Writer, input Reader, decryption PGPDecryption, changes *RewrapChanges) error {
+	keyPackets, dataHeader, tag, err := readKeyPacketSection(input)
+	if err != nil {
+		return err
+	}
+	dataStart := bytes.NewBuffer(dataHeader)
+	v6, err := readDataPacketBody(tag, io.TeeReader(input, dataStart))
+	if err != nil {
+		return err
+	}
+	if keyPackets, err = p.rewrapKeyPackets(keyPackets, v6, decryption, changes); err != nil {
+		return err
+	}
+	if _, err := output.Write(keyPackets); err != nil {
+		return err
+	}
+	if _, err := output.Write(dataStart.Bytes()); err != nil {
+		return err
+	}
+	_, err = io.Copy(output, input)
+	return err
+}
+
+// readKeyPacketSection reads the packets before the encrypted data packet
+// and the header of the data packet. It returns the key packets, the
+// serialized header and the tag of the data packet.
+// Padding packets are skipped without buffering them, and key packets with
+// partial lengths are returned with a definite length.
+func readKeyPacketSection(input io.Reader) (keyPackets, dataHeader []byte, tag uint8, err error) {
+	var packets bytes.Buffer
+	for {
+		var header bytes.Buffer
+		tag, length, partial, err := readPacketHeader(io.TeeReader(input, &header))
+		if errors.Is(err, io.EOF) {
+			return nil, nil, 0, errors.New("gopenpgp: the message has no encrypted data packet")
+		}
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("gopenpgp: error in reading packet header: %w", unexpectedEOF(err))
+		}
+		if !isKeyPacketSectionTag(tag) {
+			return packets.Bytes(), header.Bytes(), tag, nil
+		}
+		body := &partialBodyReader{reader: &countingReader{reader: input}, remaining: length, partial: partial}
+		if tag == packetTagPadding {
+			if _, err := io.Copy(io.Discard, body); err != nil {
+				return nil, nil, 0, fmt.Errorf("gopenpgp: error in reading padding packet: %w", err)
+			}
+			continue
+		}
+		var contents bytes.Buffer
+		if _, err := io.Copy(&contents, body); err != nil {
+			return nil, nil, 0, fmt.Errorf("gopenpgp: error in reading key packet: %w", err)
+		}
+		if partial {
+			header.Reset()
+			header.WriteByte(0xc0 | tag)
+			header.Write(newFormatLength(contents.Len()))
+		}
+		packets.Write(header.Bytes())
+		packets.Write(contents.Bytes())
+	}
+}
+
+// rewrapKeyPackets returns the key packets with the changes applied.
+// The v6 flag indicates whether the data packet is SEIPDv2 data.
+func (p *PGPHandle) rewrapKeyPackets(keyPackets []byte, v6 bool, decryption PGPDecryption, changes *RewrapChanges) ([]byte, error) {
+	sessionKey, err := decryption.DecryptSessionKey(keyPackets)
+	if err != nil {
+		return nil, err
+	}
+	defer sessionKey.Clear()
+	sessionKey.v6 = v6
+
+	var rewrapped bytes.Buffer
+	reader := bytes.NewReader(keyPackets)
+	for reader.Len() > 0 {
+		var raw bytes.Buffer
+		tag, length, _, err := readPacketHeader(io.TeeReader(reader, &raw))
+		if err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in reading key packets: %w", unexpectedEOF(err))
+		}
+		if _, err := io.CopyN(&raw, reader, length); err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in reading key packets: %w", unexpectedEOF(err))
+		}
+		remove, err := changes.removesKeyPacket(tag, raw.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		if !remove {
+			rewrapped.Write(raw.Bytes())
+		}
+	}
+
+	config := p.profile.EncryptionConfig()
+	config.Time = NewConstantClock(p.defaultTime().Unix())
+	if !v6 {
+		// Only v3 key packets are valid for SEIPDv1 data.
+		config.AEADConfig = nil
+	}
+	if changes.AddRecipients != nil || changes.AddHiddenRecipients != nil {
+		if err := encryptSessionKeyToWriter(changes.AddRecipients, changes.AddHiddenRecipients, sessionKey, &rewrapped, config.Now(), config); err != nil {
+			return nil, err
+		}
+	}
+	for _, password := range changes.AddPasswords {
+		if err := encryptSessionKeyWithPasswordToWriter(password, sessionKey, &rewrapped, config); err != nil {
+			return nil, err
+		}
+	}
+	if !containsKeyPacket(rewrapped.Bytes()) {
+		return nil, errors.New("gopenpgp: the rewrapped message has no key packets")
+	}
+	return rewrapped.Bytes(), nil
+}
+
+// removesKeyPacket returns true if the changes remove the serialized packet.
+func (changes *RewrapChanges) removesKeyPacket(tag uint8, raw []byte) (bool, error) {
+	switch tag {
+	case packetTagEncryptedKey:
+		if changes.RemoveRecipients == nil {
+			return false, nil
+		}
+		p, err := packet.Read(bytes.NewReader(raw))
+		if err != nil {
+			return false, fmt.Errorf("gopenpgp: error in parsing key packet: %w", err)
+		}
+		encryptedKey, ok := p.(*packet.EncryptedKey)
+		if !ok {
+			return false, errors.New("gopenpgp: invalid key packet")
+		}
+		return encryptedKey.KeyId != 0 && len(changes.RemoveRecipients.entities.KeysById(encryptedKey.KeyId)) > 0, nil
+	case packetTagSymmetricKeyEncrypted:
+		for _, password := range changes.RemovePasswords {
+			if _, err := decryptSessionKeyWithPassword(raw, password); err == nil {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// containsKeyPacket returns true if the packets contain a public-key or a
+// symmetric-key encrypted session key packet.
+func containsKeyPacket(packets []byte) bool {
+	reader := bytes.NewReader(packets)
+	for reader.Len() > 0 {
+		tag, length, _, err := readPacketHeader(reader)
+		if err != nil {
+			return false
+		}
+		if tag == packetTagEncryptedKey || tag == packetTagSymmetricKeyEncrypted {
+			return true
+		}
+		if _, err := reader.Seek(length, io.SeekCurrent); err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+func isKeyPacketSectionTag(tag uint8) bool {
+	switch tag {
+	case packetTagEncryptedKey, packetTagSymmetricKeyEncrypted, packetTagMarker, packetTagPadding:
+		return true
+	}
+	return false
+}
+
+// readDataPacketStart reads the start of the encrypted data packet, writes
+// it to the output, and returns true if it contains SEIPDv2 data.
+func readDataPacketStart(input io.Reader, output io.Writer) (bool, error) {
+	tag, _, _, err := readPacketHeader(io.TeeReader(input, output))
+	if err != nil {
+		return false, fmt.Errorf("gopenpgp: error in reading data packet: %w", unexpectedEOF(err))
+	}
+	return readDataPacketBody(tag, io.TeeReader(input, output))
+}
+
+// readDataPacketBody reads the version of the encrypted data packet with the
+// tag, and returns true if it contains SEIPDv2 data.
+func readDataPacketBody(tag uint8, input io.Reader) (bool, error) {
+	switch tag {
+	case packetTagSEIPD:
+		var version [1]byte
+		if _, err := io.ReadFull(input, version[:]); err != nil {
+			return false, fmt.Errorf("gopenpgp: error in reading data packet: %w", unexpectedEOF(err))
+		}
+		return version[0] == seipdVersionAEAD, nil
+	case packetTagSymmetricallyEncrypted, packetTagAEADEncrypted:
+		return false, nil
+	}
+	return false, errors.New("gopenpgp: the message has no encrypted data packet")
+}
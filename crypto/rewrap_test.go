@@ -0,0 +1,181 @@
+package crypto
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+func encryptedKeyVersions(keyPackets []byte) (versions []int) {
+	packets := packet.NewReader(bytes.NewReader(keyPackets))
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			return versions
+		}
+		if encryptedKey, ok := p.(*packet.EncryptedKey); ok {
+			versions = append(versions, encryptedKey.Version)
+		}
+	}
+}
+
+func TestRewrapRecipients(t *testing.T) {
+	newKey, err := testPGP.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	newKeyRing, err := NewKeyRing(newKey)
+	if err != nil {
+		t.Fatal("Expected no error while creating key ring, got:", err)
+	}
+	encHandle, _ := testPGP.Encryption().Recipients(keyRingTestPublic).New()
+	message, err := encHandle.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, _ := testPGP.Decryption().DecryptionKeys(keyRingTestPrivate).New()
+
+	rewrapped, err := testPGP.Rewrap(message, decHandle, &RewrapChanges{
+		AddRecipients:    newKeyRing,
+		RemoveRecipients: keyRingTestPublic,
+	})
+	if err != nil {
+		t.Fatal("Expected no error while rewrapping, got:", err)
+	}
+	assert.Exactly(t, message.DataPacket, rewrapped.DataPacket)
+	assert.Exactly(t, []int{3}, encryptedKeyVersions(rewrapped.KeyPacket))
+
+	newDecHandle, _ := testPGP.Decryption().DecryptionKey(newKey).New()
+	decrypted, err := newDecHandle.Decrypt(rewrapped.Bytes(), Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, testMessageString, decrypted.String())
+	_, err = decHandle.Decrypt(rewrapped.Bytes(), Bytes)
+	assert.Error(t, err)
+
+	// The streaming variant yields an equivalent message
+	var streamed bytes.Buffer
+	if err := testPGP.RewrapStream(&streamed, bytes.NewReader(message.Bytes()), decHandle, &RewrapChanges{
+		AddPasswords: [][]byte{[]byte("password")},
+	}); err != nil {
+		t.Fatal("Expected no error while rewrapping, got:", err)
+	}
+	assert.True(t, bytes.HasSuffix(streamed.Bytes(), message.DataPacket))
+	passwordDecHandle, _ := testPGP.Decryption().Password([]byte("password")).New()
+	for _, handle := range []PGPDecryption{decHandle, passwordDecHandle} {
+		decrypted, err := handle.Decrypt(streamed.Bytes(), Bytes)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, testMessageString, decrypted.String())
+	}
+}
+
+func TestRewrapPasswordsSEIPDv2(t *testing.T) {
+	pgp := PGPWithProfile(profile.RFC9580())
+	key, err := pgp.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRing, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while creating key ring, got:", err)
+	}
+	password, otherPassword := []byte("password"), []byte("other password")
+	encHandle, _ := pgp.Encryption().Password(password).New()
+	message, err := encHandle.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, _ := pgp.Decryption().Password(password).New()
+
+	rewrapped, err := pgp.Rewrap(message, decHandle, &RewrapChanges{
+		AddRecipients:   keyRing,
+		AddPasswords:    [][]byte{otherPassword},
+		RemovePasswords: [][]byte{password},
+	})
+	if err != nil {
+		t.Fatal("Expected no error while rewrapping, got:", err)
+	}
+	assert.Exactly(t, message.DataPacket, rewrapped.DataPacket)
+	assert.Exactly(t, []int{6}, encryptedKeyVersions(rewrapped.KeyPacket))
+	_, err = decHandle.Decrypt(rewrapped.Bytes(), Bytes)
+	assert.Error(t, err)
+	keyDecHandle, _ := pgp.Decryption().DecryptionKey(key).New()
+	otherDecHandle, _ := pgp.Decryption().Password(otherPassword).New()
+	for _, handle := range []PGPDecryption{keyDecHandle, otherDecHandle} {
+		decrypted, err := handle.Decrypt(rewrapped.Bytes(), Bytes)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, testMessageString, decrypted.String())
+	}
+}
+
+func TestRewrapErrors(t *testing.T) {
+	encHandle, _ := testPGP.Encryption().Recipients(keyRingTestPublic).New()
+	message, err := encHandle.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, _ := testPGP.Decryption().DecryptionKeys(keyRingTestPrivate).New()
+
+	// Removing all key packets would make the message undecryptable
+	_, err = testPGP.Rewrap(message, decHandle, &RewrapChanges{RemoveRecipients: keyRingTestPublic})
+	assert.Error(t, err)
+	// The session key must be decrypted
+	wrongDecHandle, _ := testPGP.Decryption().Password([]byte("password")).New()
+	_, err = testPGP.Rewrap(message, wrongDecHandle, &RewrapChanges{AddPasswords: [][]byte{[]byte("password")}})
+	assert.Error(t, err)
+	// The stream must contain an encrypted data packet
+	err = testPGP.RewrapStream(&bytes.Buffer{}, bytes.NewReader(message.KeyPacket), decHandle, &RewrapChanges{})
+	assert.Error(t, err)
+}
+
+func TestReadKeyPacketSection(t *testing.T) {
+	encHandle, _ := testPGP.Encryption().Recipients(keyRingTestPublic).Password([]byte("password")).New()
+	message, err := encHandle.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	reader := bytes.NewReader(message.KeyPacket)
+	_, length, _, err := readPacketHeader(reader)
+	if err != nil {
+		t.Fatal("Expected no error while reading packet header, got:", err)
+	}
+	body := make([]byte, length)
+	if _, err := reader.Read(body); err != nil {
+		t.Fatal("Expected no error while reading key packet, got:", err)
+	}
+	rest := message.KeyPacket[len(message.KeyPacket)-reader.Len():]
+	var padding bytes.Buffer
+	if err := packet.Padding(1000).SerializePadding(&padding, bytes.NewReader(make([]byte, 1000))); err != nil {
+		t.Fatal("Expected no error while writing padding, got:", err)
+	}
+
+	// The first key packet has a partial length, and padding surrounds the key packets
+	partialKeyPacket := slices.Concat([]byte{0xc0 | packetTagEncryptedKey, partialLength(16)}, body[:16], newFormatLength(len(body)-16), body[16:])
+	input := slices.Concat(padding.Bytes(), partialKeyPacket, rest, padding.Bytes(), message.DataPacket)
+	keyPackets, dataHeader, tag, err := readKeyPacketSection(bytes.NewReader(input))
+	if err != nil {
+		t.Fatal("Expected no error while reading key packets, got:", err)
+	}
+	definiteKeyPacket := slices.Concat([]byte{0xc0 | packetTagEncryptedKey}, newFormatLength(len(body)), body)
+	assert.Exactly(t, slices.Concat(definiteKeyPacket, rest), keyPackets)
+	assert.Exactly(t, message.DataPacket[:len(dataHeader)], dataHeader)
+	assert.Exactly(t, uint8(packetTagSEIPD), tag)
+
+	decHandle, _ := testPGP.Decryption().DecryptionKeys(keyRingTestPrivate).New()
+	decrypted, err := decHandle.Decrypt(input, Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, testMessageString, decrypted.String())
+	assert.Exactly(t, int64(2*(3+1000)), decrypted.PaddingLength())
+}
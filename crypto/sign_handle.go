@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"time"
 	"unicode/utf8"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/ProtonMail/gopenpgp/v3/constants"
 
 	"github.com/lovoo/gopenpgp/v3/internal"
+	"github.com/lovoo/gopenpgp/v3/policy"
 )
 
 type signatureHandle struct {
@@ -25,15 +27,18 @@ type signatureHandle struct {
 	ArmorHeaders map[string]string
 	profile      SignProfile
 	clock        Clock
+	// policy is the cryptographic policy enforced for the signatures, if any.
+	policy *policy.Policy
 }
 
 // --- Default signature handle to build from
 
-func defaultSignatureHandle(profile SignProfile, clock Clock) *signatureHandle {
+func defaultSignatureHandle(profile SignProfile, policy *policy.Policy, clock Clock) *signatureHandle {
 	return &signatureHandle{
 		profile:      profile,
 		ArmorHeaders: internal.ArmorHeaders,
 		clock:        clock,
+		policy:       policy,
 	}
 }
 
@@ -45,6 +50,11 @@ func defaultSignatureHandle(profile SignProfile, clock Clock) *signatureHandle {
 // Once close is called on the returned WriteCloser the final signature is written to the output.
 // Thus, the returned WriteCloser must be closed after the plaintext has been written.
 func (sh *signatureHandle) SigningWriter(outputWriter Writer, encoding int8) (messageWriter WriteCloser, err error) {
+	config := sh.profile.SignConfig()
+	config.Time = NewConstantClock(sh.clock().Unix())
+	if err := sh.checkPolicy(config); err != nil {
+		return nil, err
+	}
 	var armorWriter WriteCloser
 	armorOutput := armorOutput(encoding)
 	if armorOutput {
@@ -167,6 +177,13 @@ func (sh *signatureHandle) signCleartext(message []byte) ([]byte, error) {
 		} else {
 			return nil, errors.New("gopenpgp: no signing key found for entity")
 		}
+		if sh.policy != nil {
+			// Cleartext signatures do not consider the hash preferences of the key
+			hash := selectSignatureHash(slices.Clone(candidateSignatureHashes), config.Hash(), key.PublicKey)
+			if err := checkSignerPolicy(sh.policy, hash, key.PublicKey, config.Now()); err != nil {
+				return nil, err
+			}
+		}
 	}
 	writer, err := clearsign.EncodeMultiWithHeader(&buffer, privateKeys, config, sh.ArmorHeaders)
 	if err != nil {
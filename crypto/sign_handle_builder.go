@@ -1,5 +1,7 @@
 package crypto
 
+import "github.com/lovoo/gopenpgp/v3/policy"
+
 // SignHandleBuilder allows to configure a sign handle
 // to sign data with OpenPGP.
 type SignHandleBuilder struct {
@@ -8,9 +10,9 @@ type SignHandleBuilder struct {
 	err          error
 }
 
-func newSignHandleBuilder(profile SignProfile, clock Clock) *SignHandleBuilder {
+func newSignHandleBuilder(profile SignProfile, policy *policy.Policy, clock Clock) *SignHandleBuilder {
 	return &SignHandleBuilder{
-		handle:       defaultSignatureHandle(profile, clock),
+		handle:       defaultSignatureHandle(profile, policy, clock),
 		defaultClock: clock,
 	}
 }
@@ -87,7 +89,7 @@ func (shb *SignHandleBuilder) New() (PGPSign, error) {
 		return nil, shb.err
 	}
 	handle := shb.handle
-	shb.handle = defaultSignatureHandle(shb.handle.profile, shb.defaultClock)
+	shb.handle = defaultSignatureHandle(shb.handle.profile, shb.handle.policy, shb.defaultClock)
 	return handle, nil
 }
 
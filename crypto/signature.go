@@ -11,6 +11,8 @@ import (
 	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
 
 	"github.com/ProtonMail/gopenpgp/v3/constants"
+
+	"github.com/lovoo/gopenpgp/v3/policy"
 )
 
 // VerifiedSignature is a result of a signature verification.
@@ -251,6 +253,7 @@ func createVerifyResult(
 	verificationContext *VerificationContext,
 	verifyTime int64,
 	disableTimeCheck bool,
+	policy *policy.Policy,
 ) (*VerifyResult, error) {
 	if !md.IsSigned {
 		signatureError := newSignatureNotSigned()
@@ -294,6 +297,11 @@ func createVerifyResult(
 				signatureError = newSignatureBadContext(err)
 			}
 		}
+		if signatureError.Status == constants.SIGNATURE_OK && policy != nil {
+			if err := checkSignaturePolicy(policy, signature.CorrespondingSig, signature.SignedBy); err != nil {
+				signatureError = newSignatureFailed(err)
+			}
+		}
 		if signatureError.Status != constants.SIGNATURE_OK {
 			verifiedSignature.SignatureError = &signatureError
 		}
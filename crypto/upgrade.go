@@ -0,0 +1,168 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+// UpgradeMessage re-encrypts a binary message with legacy SEIPDv1 data
+// (CFB with MDC) into a message with SEIPDv2 (AEAD) data, which is written
+// to the output.
+// The decryption handle decrypts the session key of the message and must
+// contain a decryption key or the passwords, and it must be created with
+// PGPHandle.Decryption, since the passwords are read from it. The new session
+// key is encrypted to the same recipients and passwords as the original one,
+// the public keys of the recipients are taken from the recipients key ring.
+// The decrypted packets, i.e., the literal data with its metadata and any
+// embedded signatures, are re-encrypted without changes.
+// The cipher of the new session key is negotiated with the cipher preferences
+// of the recipients as for encryption, while the data is encrypted with
+// SEIPDv2 even if the recipients do not announce support for it.
+// The AEAD parameters are taken from the profile of the handle, or from
+// profile.RFC9580 if the profile does not enable AEAD encryption.
+// The output is streamed, and it is only finalized with the final
+// authentication tag after the integrity of the input has been verified.
+// If an error is returned, the output must be discarded.
+func (p *PGPHandle) UpgradeMessage(output Writer, input Reader, decryption PGPDecryption, recipients *KeyRing) error {
+	dh, ok := decryption.(*decryptionHandle)
+	if !ok {
+		return errors.New("gopenpgp: the decryption handle must be created with PGPHandle.Decryption")
+	}
+	keyPackets, dataHeader, tag, err := readKeyPacketSection(input)
+	if err != nil {
+		return err
+	}
+	if tag != packetTagSEIPD {
+		return errors.New("gopenpgp: the message has no integrity protected data packet")
+	}
+	dataPacket, err := packet.Read(io.MultiReader(bytes.NewReader(dataHeader), input))
+	if err != nil {
+		return fmt.Errorf("gopenpgp: error in reading data packet: %w", err)
+	}
+	seipd, ok := dataPacket.(*packet.SymmetricallyEncrypted)
+	if !ok || seipd.Version != 1 {
+		return errors.New("gopenpgp: the message has no SEIPDv1 data packet")
+	}
+
+	sessionKey, err := decryption.DecryptSessionKey(keyPackets)
+	if err != nil {
+		return err
+	}
+	defer sessionKey.Clear()
+	recipientKeys, recipientPasswords, err := upgradeRecipients(keyPackets, sessionKey, recipients, dh.Passwords)
+	if err != nil {
+		return err
+	}
+	cipherFunc, err := sessionKey.GetCipherFunc()
+	if err != nil {
+		return err
+	}
+	plaintext, err := seipd.Decrypt(cipherFunc, sessionKey.Key)
+	if err != nil {
+		return fmt.Errorf("gopenpgp: error in decrypting message: %w", err)
+	}
+
+	config := p.profile.EncryptionConfig()
+	config.Time = NewConstantClock(p.defaultTime().Unix())
+	if config.AEADConfig == nil {
+		config.AEADConfig = profile.RFC9580().AeadEncryption
+	}
+	newSessionKey, err := generateSessionKey(config, recipientKeys, nil)
+	if err != nil {
+		return err
+	}
+	defer newSessionKey.Clear()
+	// Upgrade to SEIPDv2 regardless of the features of the recipients
+	newSessionKey.v6 = true
+	if recipientKeys.CountEntities() > 0 {
+		if err := encryptSessionKeyToWriter(recipientKeys, nil, newSessionKey, output, config.Now(), config); err != nil {
+			return err
+		}
+	}
+	for _, password := range recipientPasswords {
+		if err := encryptSessionKeyWithPasswordToWriter(password, newSessionKey, output, config); err != nil {
+			return err
+		}
+	}
+	newCipherFunc, err := newSessionKey.GetCipherFunc()
+	if err != nil {
+		return err
+	}
+	cipherSuite := packet.CipherSuite{Cipher: newCipherFunc, Mode: config.AEAD().Mode()}
+	encrypted, err := packet.SerializeSymmetricallyEncrypted(output, newCipherFunc, true, cipherSuite, newSessionKey.Key, config)
+	if err != nil {
+		return fmt.Errorf("gopenpgp: error in encrypting message: %w", err)
+	}
+	if _, err := io.Copy(encrypted, plaintext); err != nil {
+		return fmt.Errorf("gopenpgp: error in upgrading message: %w", err)
+	}
+	// Only finalize the output if the MDC of the input is valid
+	if err := plaintext.Close(); err != nil {
+		return fmt.Errorf("gopenpgp: integrity check of the message failed: %w", err)
+	}
+	return encrypted.Close()
+}
+
+// upgradeRecipients returns the keys of the recipients and the passwords of
+// the key packets, looking them up in the candidate keys and passwords.
+func upgradeRecipients(keyPackets []byte, sessionKey *SessionKey, candidates *KeyRing, passwords [][]byte) (*KeyRing, [][]byte, error) {
+	recipientKeys := &KeyRing{}
+	var recipientPasswords [][]byte
+	packets := packet.NewReader(bytes.NewReader(keyPackets))
+	for {
+		p, err := packets.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("gopenpgp: error in reading key packets: %w", err)
+		}
+		switch p := p.(type) {
+		case *packet.EncryptedKey:
+			if p.KeyId == 0 {
+				return nil, nil, errors.New("gopenpgp: the recipient of an anonymous key packet is unknown")
+			}
+			keys := candidates.getEntities().KeysById(p.KeyId)
+			if len(keys) == 0 {
+				return nil, nil, fmt.Errorf("gopenpgp: no public key of recipient %s", keyIDToHex(p.KeyId))
+			}
+			if !containsEntity(recipientKeys, keys[0].Entity) {
+				recipientKeys.entities = append(recipientKeys.entities, keys[0].Entity)
+			}
+		case *packet.SymmetricKeyEncrypted:
+			password, err := findPassword(p, sessionKey, passwords)
+			if err != nil {
+				return nil, nil, err
+			}
+			recipientPasswords = append(recipientPasswords, password)
+		}
+	}
+	return recipientKeys, recipientPasswords, nil
+}
+
+// findPassword returns the password that decrypts the session key from the
+// key packet.
+func findPassword(keyPacket *packet.SymmetricKeyEncrypted, sessionKey *SessionKey, passwords [][]byte) ([]byte, error) {
+	for _, password := range passwords {
+		if key, _, err := keyPacket.Decrypt(password); err == nil && bytes.Equal(key, sessionKey.Key) {
+			return password, nil
+		}
+	}
+	return nil, errors.New("gopenpgp: no password of a symmetric-key encrypted session key packet")
+}
+
+func containsEntity(keyRing *KeyRing, entity *openpgp.Entity) bool {
+	for _, e := range keyRing.entities {
+		if e == entity {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v3/constants"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+func TestUpgradeMessage(t *testing.T) {
+	encHandle, _ := testPGP.Encryption().
+		Recipients(keyRingTestPublic).
+		Password([]byte("password")).
+		SigningKeys(keyRingTestPrivate).
+		Utf8().
+		New()
+	message, err := encHandle.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, _ := testPGP.Decryption().DecryptionKeys(keyRingTestPrivate).Password([]byte("password")).New()
+
+	pgp := PGPWithProfile(profile.RFC9580())
+	pgp.defaultTime = NewConstantClock(testTime)
+	var upgraded bytes.Buffer
+	if err := pgp.UpgradeMessage(&upgraded, bytes.NewReader(message.Bytes()), decHandle, keyRingTestPublic); err != nil {
+		t.Fatal("Expected no error while upgrading, got:", err)
+	}
+	upgradedMessage := NewPGPMessage(upgraded.Bytes())
+	assert.Exactly(t, []int{6}, encryptedKeyVersions(upgradedMessage.KeyPacket))
+	v6, err := readDataPacketStart(bytes.NewReader(upgradedMessage.DataPacket), &bytes.Buffer{})
+	if err != nil {
+		t.Fatal("Expected no error while reading data packet, got:", err)
+	}
+	assert.True(t, v6)
+
+	keyDecHandle, _ := testPGP.Decryption().DecryptionKeys(keyRingTestPrivate).VerificationKeys(keyRingTestPublic).New()
+	passwordDecHandle, _ := testPGP.Decryption().Password([]byte("password")).VerificationKeys(keyRingTestPublic).New()
+	for _, handle := range []PGPDecryption{keyDecHandle, passwordDecHandle} {
+		decrypted, err := handle.Decrypt(upgraded.Bytes(), Bytes)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, testMessageString, decrypted.String())
+		assert.True(t, decrypted.Metadata().IsUtf8())
+		assert.NoError(t, decrypted.SignatureError())
+	}
+}
+
+func TestUpgradeMessageCipherPreferences(t *testing.T) {
+	// The recipient prefers AES-128 and does not announce SEIPDv2 support
+	legacyPGP := PGPWithProfile(profile.RFC4880())
+	key, err := legacyPGP.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	key, err = legacyPGP.SetKeyPreferences(key, &KeyPreferences{Ciphers: []byte{byte(packet.CipherAES128)}})
+	if err != nil {
+		t.Fatal("Expected no error while setting preferences, got:", err)
+	}
+	encHandle, _ := legacyPGP.Encryption().Recipient(key).New()
+	message, err := encHandle.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, _ := legacyPGP.Decryption().DecryptionKey(key).New()
+	keyRing, _ := NewKeyRing(key)
+
+	var upgraded bytes.Buffer
+	if err := PGPWithProfile(profile.RFC9580()).UpgradeMessage(&upgraded, bytes.NewReader(message.Bytes()), decHandle, keyRing); err != nil {
+		t.Fatal("Expected no error while upgrading, got:", err)
+	}
+	p, err := packet.Read(bytes.NewReader(NewPGPMessage(upgraded.Bytes()).DataPacket))
+	if err != nil {
+		t.Fatal("Expected no error while reading data packet, got:", err)
+	}
+	encrypted, ok := p.(*packet.SymmetricallyEncrypted)
+	if !ok {
+		t.Fatal("Expected a SEIPD packet, got:", p)
+	}
+	assert.Exactly(t, 2, encrypted.Version)
+	assert.Exactly(t, constants.AES128, getAlgo(encrypted.Cipher))
+	decrypted, err := decHandle.Decrypt(upgraded.Bytes(), Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	assert.Exactly(t, testMessageString, decrypted.String())
+}
+
+// wrappedDecryption is a PGPDecryption that is not created by PGPHandle.Decryption.
+type wrappedDecryption struct {
+	PGPDecryption
+}
+
+func TestUpgradeMessageErrors(t *testing.T) {
+	encHandle, _ := testPGP.Encryption().Recipients(keyRingTestPublic).New()
+	message, err := encHandle.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, _ := testPGP.Decryption().DecryptionKeys(keyRingTestPrivate).New()
+	pgp := PGPWithProfile(profile.RFC9580())
+
+	// The public keys of all recipients are required
+	err = pgp.UpgradeMessage(&bytes.Buffer{}, bytes.NewReader(message.Bytes()), decHandle, nil)
+	assert.Error(t, err)
+
+	// The output is not finalized if the integrity check fails
+	tampered := message.Bytes()
+	tampered[len(tampered)-1] ^= 1
+	var upgraded bytes.Buffer
+	err = pgp.UpgradeMessage(&upgraded, bytes.NewReader(tampered), decHandle, keyRingTestPublic)
+	assert.Error(t, err)
+	_, err = decHandle.Decrypt(upgraded.Bytes(), Bytes)
+	assert.Error(t, err)
+
+	// Messages with SEIPDv2 data are not upgraded
+	key, err := pgp.KeyGeneration().AddUserId(keyTestName, keyTestDomain).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	encHandle, _ = pgp.Encryption().Recipient(key).New()
+	message, err = encHandle.Encrypt([]byte(testMessageString))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, _ = pgp.Decryption().DecryptionKey(key).New()
+	keyRing, _ := NewKeyRing(key)
+	err = pgp.UpgradeMessage(&bytes.Buffer{}, bytes.NewReader(message.Bytes()), decHandle, keyRing)
+	assert.Error(t, err)
+
+	// The passwords can only be read from handles of PGPHandle.Decryption
+	err = pgp.UpgradeMessage(&bytes.Buffer{}, bytes.NewReader(message.Bytes()), wrappedDecryption{decHandle}, keyRing)
+	assert.ErrorContains(t, err, "PGPHandle.Decryption")
+}
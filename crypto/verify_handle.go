@@ -13,6 +13,7 @@ import (
 	"github.com/ProtonMail/gopenpgp/v3/constants"
 
 	"github.com/lovoo/gopenpgp/v3/internal"
+	"github.com/lovoo/gopenpgp/v3/policy"
 )
 
 type verifyHandle struct {
@@ -24,14 +25,16 @@ type verifyHandle struct {
 	IsUTF8                       bool
 	clock                        Clock
 	profile                      SignProfile
+	policy                       *policy.Policy
 }
 
 // --- Default verification handle to build from
 
-func defaultVerifyHandle(profile SignProfile, clock Clock) *verifyHandle {
+func defaultVerifyHandle(profile SignProfile, policy *policy.Policy, clock Clock) *verifyHandle {
 	return &verifyHandle{
 		clock:   clock,
 		profile: profile,
+		policy:  policy,
 	}
 }
 
@@ -165,8 +168,9 @@ func (vh *verifyHandle) verifyingReader(
 	if vh.VerificationContext != nil {
 		config.KnownNotations = map[string]bool{constants.SignatureContextName: true}
 	}
+	padding := newPaddingReader(signatureMessage)
 	md, err := openpgp.ReadMessage(
-		signatureMessage,
+		padding,
 		vh.VerifyKeyRing.getEntities(),
 		nil,
 		config,
@@ -182,6 +186,8 @@ func (vh *verifyHandle) verifyingReader(
 		vh.DisableVerifyTimeCheck,
 		false,
 		vh.VerificationContext,
+		padding,
+		vh.policy,
 	}, nil
 }
 
@@ -198,6 +204,7 @@ func (vh *verifyHandle) verifyingDetachedReader(
 		vh.DisableAutomaticTextSanitize,
 		vh.profile.SignConfig(),
 		vh.clock,
+		vh.policy,
 	)
 }
 
@@ -236,6 +243,7 @@ func verifyingDetachedReader(
 	disableAutomaticTextSanitize bool,
 	config *packet.Config,
 	clock Clock,
+	policy *policy.Policy,
 ) (*VerifyDataReader, error) {
 	if config == nil {
 		config = &packet.Config{}
@@ -268,5 +276,7 @@ func verifyingDetachedReader(
 		disableVerifyTimeCheck,
 		false,
 		verificationContext,
+		nil,
+		policy,
 	}, nil
 }
@@ -1,18 +1,22 @@
 package crypto
 
+import "github.com/lovoo/gopenpgp/v3/policy"
+
 // VerifyHandleBuilder configures a VerifyHandle handle.
 type VerifyHandleBuilder struct {
 	handle       *verifyHandle
 	defaultClock Clock
 	err          error
 	profile      SignProfile
+	policy       *policy.Policy
 }
 
-func newVerifyHandleBuilder(profile SignProfile, clock Clock) *VerifyHandleBuilder {
+func newVerifyHandleBuilder(profile SignProfile, policy *policy.Policy, clock Clock) *VerifyHandleBuilder {
 	return &VerifyHandleBuilder{
-		handle:       defaultVerifyHandle(profile, clock),
+		handle:       defaultVerifyHandle(profile, policy, clock),
 		defaultClock: clock,
 		profile:      profile,
+		policy:       policy,
 	}
 }
 
@@ -92,7 +96,7 @@ func (vhb *VerifyHandleBuilder) New() (PGPVerify, error) {
 		return nil, vhb.err
 	}
 	handle := vhb.handle
-	vhb.handle = defaultVerifyHandle(vhb.profile, vhb.defaultClock)
+	vhb.handle = defaultVerifyHandle(vhb.profile, vhb.policy, vhb.defaultClock)
 	return handle, nil
 }
 
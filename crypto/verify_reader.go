@@ -6,6 +6,8 @@ import (
 	"io"
 
 	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+
+	"github.com/lovoo/gopenpgp/v3/policy"
 )
 
 // VerifyDataReader is used for reading data that should be verified with a signature.
@@ -19,6 +21,11 @@ type VerifyDataReader struct {
 	disableTimeCheck    bool
 	readAll             bool
 	verificationContext *VerificationContext
+	// padding tracks the padding packets of the message, if the packets are
+	// read in plaintext.
+	padding *paddingReader
+	// policy is the cryptographic policy the signatures are checked against, if any.
+	policy *policy.Policy
 }
 
 // GetMetadata returns the metadata of the literal data packet that
@@ -53,7 +60,7 @@ func (msg *VerifyDataReader) VerifySignature() (result *VerifyResult, err error)
 	if !msg.readAll {
 		return nil, errors.New("gopenpgp: can't verify the signature until the message reader has been read entirely")
 	}
-	return createVerifyResult(msg.details, msg.verifyKeyRing, msg.verificationContext, msg.verifyTime, msg.disableTimeCheck)
+	return createVerifyResult(msg.details, msg.verifyKeyRing, msg.verificationContext, msg.verifyTime, msg.disableTimeCheck, msg.policy)
 }
 
 // ReadAll reads all plaintext data from the reader
@@ -92,6 +99,7 @@ func (msg *VerifyDataReader) ReadAllAndVerifySignature() (*VerifiedDataResult, e
 		data:             plaintext,
 		metadata:         msg.GetMetadata(),
 		cachedSessionKey: msg.SessionKey(),
+		paddingLength:    msg.PaddingLength(),
 	}, err
 }
 
@@ -106,6 +114,19 @@ func (msg *VerifyDataReader) SessionKey() *SessionKey {
 	return NewSessionKeyFromToken(msg.details.SessionKey, alg)
 }
 
+// PaddingLength returns the total length of the padding packets of the
+// message, including their headers, that were read so far.
+// Padding packets inside an encrypted data packet are only visible if the
+// message is decrypted with a session key and the data packet is a SEIPD
+// packet. Decrypting with keys or passwords only counts the padding packets
+// outside of the encrypted data packet.
+func (msg *VerifyDataReader) PaddingLength() int64 {
+	if msg.padding == nil {
+		return 0
+	}
+	return msg.padding.length
+}
+
 // VerifiedDataResult is a result that contains data and
 // the result of a potential signature verification on the data.
 type VerifiedDataResult struct {
@@ -113,6 +134,7 @@ type VerifiedDataResult struct {
 	metadata         *LiteralMetadata
 	data             []byte
 	cachedSessionKey *SessionKey
+	paddingLength    int64
 }
 
 // Metadata returns the associated literal metadata of the data.
@@ -137,6 +159,12 @@ func (r *VerifiedDataResult) SessionKey() *SessionKey {
 	return r.cachedSessionKey
 }
 
+// PaddingLength returns the total length of the padding packets of the
+// message, see VerifyDataReader.PaddingLength.
+func (r *VerifiedDataResult) PaddingLength() int64 {
+	return r.paddingLength
+}
+
 // VerifyCleartextResult is a result of a cleartext message verification.
 type VerifyCleartextResult struct {
 	VerifyResult
@@ -0,0 +1,297 @@
+package gnupg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+const testTime = 1717200000 // 2024-06-01T00:00:00+00:00
+
+// The test home directory in testdata was created with GnuPG 2.2. All keys
+// are protected with testPassphrase, except for Bob's key.
+var testPassphrase = []byte("password")
+
+var testKeys = []struct {
+	name        string
+	fingerprint string
+	keygrips    []string
+}{
+	{
+		name:        "Alice (RSA)",
+		fingerprint: "f35484f702ed152573529244d2db561895f1d9ad",
+		keygrips:    []string{"8E2351034E1F0BE2699B378819B07FE5B7F495F3", "F9B1753194599F6C2CD5B1BFBFBBCD440EDA815A"},
+	},
+	{
+		name:        "Bob (Curve25519)",
+		fingerprint: "f5e7ca0631aae393930baf6c9e20df659f6fb9e8",
+		keygrips:    []string{"FF3120F12BE8124D286B0B8D08B881887937C276", "9307BA953CB5467DF24E08AFBAC3EFEB38CB5F9F"},
+	},
+	{
+		name:        "Carol (NIST P-256)",
+		fingerprint: "a8092be73c7a73d8ace9ce89bcbde6e9946a9e4a",
+		keygrips:    []string{"27A2A609335D43007939FF429385561DD71DB8A1", "21991479E1D8C27DC176F1D9B12FBD6B497BD063"},
+	},
+	{
+		name:        "Dave (DSA and ElGamal)",
+		fingerprint: "7b21c047a5ecd982d27cd4c91ed2c4d193e1a732",
+		keygrips:    []string{"C6DE3A8F56719FC59029FFD1B6FE8183E2957C44", "33E13F9825CA5D9B3841ECEC88329F92E2C3B7C3"},
+	},
+}
+
+func readTestKeyRing(t *testing.T) *crypto.KeyRing {
+	file, err := os.Open("testdata/" + KeyboxFile)
+	if err != nil {
+		t.Fatal("Expected no error while opening keybox, got:", err)
+	}
+	defer file.Close()
+	keyRing, err := ReadKeybox(file)
+	if err != nil {
+		t.Fatal("Expected no error while reading keybox, got:", err)
+	}
+	return keyRing
+}
+
+func assertTestFingerprints(t *testing.T, keys []*crypto.Key) {
+	if !assert.Len(t, keys, len(testKeys)) {
+		return
+	}
+	for i, testKey := range testKeys {
+		assert.Exactly(t, testKey.fingerprint, keys[i].GetFingerprint(), testKey.name)
+	}
+}
+
+func TestReadKeybox(t *testing.T) {
+	keyRing := readTestKeyRing(t)
+	assertTestFingerprints(t, keyRing.GetKeys())
+	for _, key := range keyRing.GetKeys() {
+		assert.False(t, key.IsPrivate())
+	}
+}
+
+func TestReadKeyboxChecksumMismatch(t *testing.T) {
+	data, err := os.ReadFile("testdata/" + KeyboxFile)
+	if err != nil {
+		t.Fatal("Expected no error while reading keybox, got:", err)
+	}
+	// Flip a bit in the key block of the first OpenPGP blob
+	data[keyboxHeaderLength+200] ^= 0x01
+	_, err = ReadKeybox(bytes.NewReader(data))
+	assert.Error(t, err)
+}
+
+func TestReadKeyring(t *testing.T) {
+	file, err := os.Open("testdata/" + PublicKeyringFile)
+	if err != nil {
+		t.Fatal("Expected no error while opening keyring, got:", err)
+	}
+	defer file.Close()
+	keyRing, err := ReadKeyring(file)
+	if err != nil {
+		t.Fatal("Expected no error while reading keyring, got:", err)
+	}
+	assertTestFingerprints(t, keyRing.GetKeys())
+}
+
+func TestReadSecretKeyring(t *testing.T) {
+	file, err := os.Open("testdata/" + SecretKeyringFile)
+	if err != nil {
+		t.Fatal("Expected no error while opening secret keyring, got:", err)
+	}
+	defer file.Close()
+	keys, err := ReadSecretKeyring(file)
+	if err != nil {
+		t.Fatal("Expected no error while reading secret keyring, got:", err)
+	}
+	if !assert.Len(t, keys, 1) {
+		return
+	}
+	assert.Exactly(t, testKeys[1].fingerprint, keys[0].GetFingerprint())
+	unlocked, err := keys[0].IsUnlocked()
+	if err != nil {
+		t.Fatal("Expected no error while checking the key, got:", err)
+	}
+	assert.True(t, unlocked)
+}
+
+func TestKeygrips(t *testing.T) {
+	for i, key := range readTestKeyRing(t).GetKeys() {
+		keygrips, err := Keygrips(key)
+		if err != nil {
+			t.Fatal("Expected no error while computing keygrips, got:", err)
+		}
+		assert.Exactly(t, testKeys[i].keygrips, keygrips, testKeys[i].name)
+	}
+}
+
+func TestReadMPITruncated(t *testing.T) {
+	for _, input := range [][]byte{{}, {0x01}, {0x00, 0x09, 0x01}} {
+		_, err := readMPI(bytes.NewReader(input))
+		assert.Error(t, err)
+	}
+	mpi, err := readMPI(bytes.NewReader([]byte{0x00, 0x09, 0x01, 0xff}))
+	if err != nil {
+		t.Fatal("Expected no error while reading MPI, got:", err)
+	}
+	assert.Exactly(t, []byte{0x01, 0xff}, mpi)
+}
+
+func TestWriteKeybox(t *testing.T) {
+	keyRing := readTestKeyRing(t)
+	var buffer bytes.Buffer
+	if err := WriteKeybox(&buffer, keyRing, testTime); err != nil {
+		t.Fatal("Expected no error while writing keybox, got:", err)
+	}
+	assert.Exactly(t, []byte(keyboxMagic), buffer.Bytes()[8:12])
+
+	written, err := ReadKeybox(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatal("Expected no error while reading written keybox, got:", err)
+	}
+	assertTestFingerprints(t, written.GetKeys())
+}
+
+func TestWriteKeyboxBlobLayout(t *testing.T) {
+	keyRing := readTestKeyRing(t)
+	publicKey, err := keyRing.GetKeys()[0].GetPublicKey()
+	if err != nil {
+		t.Fatal("Expected no error while serializing key, got:", err)
+	}
+	blob, err := openPGPBlob(publicKey, testTime)
+	if err != nil {
+		t.Fatal("Expected no error while building blob, got:", err)
+	}
+	// Two keys, one user ID and two signatures as written by GnuPG
+	assert.Exactly(t, []byte{0, 0, 0, 126}, blob[8:12])
+	assert.Exactly(t, publicKey, blob[126:len(blob)-keyboxChecksumSize])
+	uidOffset := binary.BigEndian.Uint32(blob[82:])
+	assert.Exactly(t, "Alice <alice@example.org>", string(blob[uidOffset:uidOffset+25]))
+	assert.NoError(t, verifyBlobChecksum(blob))
+}
+
+func TestHomePublicKeys(t *testing.T) {
+	keyRing, err := NewHome("testdata").PublicKeys()
+	if err != nil {
+		t.Fatal("Expected no error while reading public keys, got:", err)
+	}
+	assertTestFingerprints(t, keyRing.GetKeys())
+
+	// Without a keybox the legacy keyring is used
+	legacy, err := os.ReadFile("testdata/" + PublicKeyringFile)
+	if err != nil {
+		t.Fatal("Expected no error while reading keyring, got:", err)
+	}
+	keyRing, err = NewHomeFS(fstest.MapFS{
+		PublicKeyringFile: &fstest.MapFile{Data: legacy},
+	}).PublicKeys()
+	if err != nil {
+		t.Fatal("Expected no error while reading legacy public keys, got:", err)
+	}
+	assertTestFingerprints(t, keyRing.GetKeys())
+}
+
+func TestHomeSecretKey(t *testing.T) {
+	home := NewHome("testdata")
+	// Dave's DSA and ElGamal keys are rejected by default
+	legacyProfile := profile.Default()
+	legacyProfile.AllowAllPublicKeyAlgorithms = true
+	pgp := crypto.PGPWithProfile(legacyProfile)
+	for _, publicKey := range readTestKeyRing(t).GetKeys() {
+		secretKey, err := home.SecretKey(publicKey, testPassphrase)
+		if err != nil {
+			t.Fatal("Expected no error while reading secret key, got:", err)
+		}
+		assert.Exactly(t, publicKey.GetFingerprint(), secretKey.GetFingerprint())
+		unlocked, err := secretKey.IsUnlocked()
+		if err != nil {
+			t.Fatal("Expected no error while checking the key, got:", err)
+		}
+		assert.True(t, unlocked)
+
+		encHandle, err := pgp.Encryption().Recipient(publicKey).SigningKey(secretKey).EncryptionTime(testTime).SignTime(testTime).New()
+		if err != nil {
+			t.Fatal("Expected no error while creating encryption handle, got:", err)
+		}
+		message, err := encHandle.Encrypt([]byte("hello GnuPG"))
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		decHandle, err := pgp.Decryption().DecryptionKey(secretKey).VerificationKey(publicKey).VerifyTime(testTime).New()
+		if err != nil {
+			t.Fatal("Expected no error while creating decryption handle, got:", err)
+		}
+		result, err := decHandle.Decrypt(message.Bytes(), crypto.Bytes)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting, got:", err)
+		}
+		assert.Exactly(t, "hello GnuPG", string(result.Bytes()))
+		assert.NoError(t, result.SignatureError())
+	}
+}
+
+func TestHomeSecretKeyWrongPassphrase(t *testing.T) {
+	publicKey := readTestKeyRing(t).GetKeys()[0]
+	_, err := NewHome("testdata").SecretKey(publicKey, []byte("wrong"))
+	assert.Error(t, err)
+}
+
+func TestHomeSecretKeyOfflinePrimary(t *testing.T) {
+	publicKey := readTestKeyRing(t).GetKeys()[2]
+	subkeyFile := PrivateKeysDir + "/" + testKeys[2].keygrips[1] + ".key"
+	data, err := os.ReadFile("testdata/" + subkeyFile)
+	if err != nil {
+		t.Fatal("Expected no error while reading key file, got:", err)
+	}
+	home := NewHomeFS(fstest.MapFS{subkeyFile: &fstest.MapFile{Data: data}})
+	secretKey, err := home.SecretKey(publicKey, testPassphrase)
+	if err != nil {
+		t.Fatal("Expected no error while reading secret subkey, got:", err)
+	}
+	assert.True(t, secretKey.GetEntity().PrivateKey.Dummy())
+
+	message, err := crypto.PGP().Encryption().Recipient(publicKey).EncryptionTime(testTime).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	encrypted, err := message.Encrypt([]byte("offline"))
+	if err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	decHandle, err := crypto.PGP().Decryption().DecryptionKey(secretKey).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryption handle, got:", err)
+	}
+	result, err := decHandle.Decrypt(encrypted.Bytes(), crypto.Bytes)
+	if err != nil {
+		t.Fatal("Expected no error while decrypting with subkey, got:", err)
+	}
+	assert.Exactly(t, "offline", string(result.Bytes()))
+
+	_, err = NewHomeFS(fstest.MapFS{}).SecretKey(publicKey, testPassphrase)
+	assert.Error(t, err)
+}
+
+func TestSexpAdvancedFormat(t *testing.T) {
+	expression, err := parseSexp([]byte(`(private-key (ecc (curve "NIST P-256") (flags "a\x41\101\n")
+		(q #04 0A#) (d |AQI=|) [hint] 3:abc))`))
+	if err != nil {
+		t.Fatal("Expected no error while parsing S-expression, got:", err)
+	}
+	algorithm := expression.items[1]
+	assert.Exactly(t, "NIST P-256", string(algorithm.value("curve")))
+	assert.Exactly(t, "aAA\n", string(algorithm.value("flags")))
+	assert.Exactly(t, []byte{0x04, 0x0a}, algorithm.value("q"))
+	assert.Exactly(t, []byte{0x01, 0x02}, algorithm.value("d"))
+	assert.Exactly(t, "abc", string(algorithm.items[len(algorithm.items)-1].atom))
+	assert.Exactly(t,
+		"(11:private-key(3:ecc(5:curve10:NIST P-256)(5:flags4:aAA\n)(1:q2:\x04\x0a)(1:d2:\x01\x02)3:abc))",
+		string(expression.canonical()),
+	)
+}
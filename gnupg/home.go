@@ -0,0 +1,136 @@
+// Package gnupg reads and writes the key stores of a GnuPG home directory,
+// so that keys can be exchanged with GnuPG without running gpg.
+//
+// Supported are the keybox (pubring.kbx), the legacy public and secret
+// keyrings (pubring.gpg and secring.gpg) and the secret keys kept by
+// gpg-agent in private-keys-v1.d.
+package gnupg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+// File names in a GnuPG home directory.
+const (
+	KeyboxFile        = "pubring.kbx"
+	PublicKeyringFile = "pubring.gpg"
+	SecretKeyringFile = "secring.gpg"
+	PrivateKeysDir    = "private-keys-v1.d"
+)
+
+// Home gives read access to the keys of a GnuPG home directory.
+type Home struct {
+	fsys fs.FS
+}
+
+// NewHome returns a Home for the GnuPG home directory at dir, e.g. ~/.gnupg.
+func NewHome(dir string) *Home {
+	return &Home{fsys: os.DirFS(dir)}
+}
+
+// NewHomeFS returns a Home reading the GnuPG home directory from fsys.
+func NewHomeFS(fsys fs.FS) *Home {
+	return &Home{fsys: fsys}
+}
+
+// PublicKeys returns the public keys of the home directory.
+// They are read from the keybox if present, otherwise from the legacy keyring.
+func (home *Home) PublicKeys() (*crypto.KeyRing, error) {
+	file, err := home.fsys.Open(KeyboxFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		file, err = home.fsys.Open(PublicKeyringFile)
+		if err != nil {
+			return nil, fmt.Errorf("gopenpgp: unable to open public keys: %w", err)
+		}
+		defer file.Close()
+		return ReadKeyring(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: unable to open public keys: %w", err)
+	}
+	defer file.Close()
+	return ReadKeybox(file)
+}
+
+// SecretKey returns the secret key for the given public key, assembled from
+// the files gpg-agent keeps in private-keys-v1.d.
+// The passphrase is used to unprotect the key files and may be nil for keys
+// stored without protection. The returned key is unlocked.
+// Components without a key file, e.g. an offline primary key, are marked as
+// unavailable with the GnuPG dummy S2K extension.
+func (home *Home) SecretKey(publicKey *crypto.Key, passphrase []byte) (*crypto.Key, error) {
+	if publicKey.GetVersion() != 4 {
+		return nil, fmt.Errorf("gopenpgp: unsupported key version %d", publicKey.GetVersion())
+	}
+	publicKeyBlock, err := publicKey.GetPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	packets, err := splitPackets(publicKeyBlock)
+	if err != nil {
+		return nil, err
+	}
+	var secretKeyBlock []byte
+	found := false
+	for i, rawPacket := range packets {
+		end := len(publicKeyBlock)
+		if i+1 < len(packets) {
+			end = packets[i+1].offset
+		}
+		if rawPacket.tag != tagPublicKey && rawPacket.tag != tagPublicSubkey {
+			secretKeyBlock = append(secretKeyBlock, publicKeyBlock[rawPacket.offset:end]...)
+			continue
+		}
+		tag := uint8(tagSecretKey)
+		if rawPacket.tag == tagPublicSubkey {
+			tag = tagSecretSubkey
+		}
+		body, err := home.secretKeyPacketBody(rawPacket.body, passphrase)
+		if errors.Is(err, fs.ErrNotExist) {
+			secretKeyBlock = appendPacket(secretKeyBlock, tag, dummySecretKeyPacketBody(rawPacket.body))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		secretKeyBlock = appendPacket(secretKeyBlock, tag, body)
+	}
+	if !found {
+		return nil, fmt.Errorf("gopenpgp: no secret key found for %s", publicKey.GetFingerprint())
+	}
+	entity, err := openpgp.ReadEntity(packet.NewReader(bytes.NewReader(secretKeyBlock)))
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: unable to read secret key: %w", err)
+	}
+	return crypto.NewKeyFromEntity(entity)
+}
+
+// secretKeyPacketBody reads the key file for a public key packet body and
+// returns the corresponding unencrypted secret key packet body.
+func (home *Home) secretKeyPacketBody(publicBody []byte, passphrase []byte) ([]byte, error) {
+	grip, err := keygrip(publicBody)
+	if err != nil {
+		return nil, err
+	}
+	name := path.Join(PrivateKeysDir, fmt.Sprintf("%X.key", grip))
+	data, err := fs.ReadFile(home.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := readPrivateKeyFile(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return secretKeyPacketBody(publicBody, algorithm)
+}
@@ -0,0 +1,250 @@
+package gnupg
+
+import (
+	"bytes"
+	"crypto/md5"  //nolint:gosec
+	"crypto/sha1" //nolint:gosec
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+// Keybox blob types, see keybox/keybox-blob.c in the GnuPG sources.
+// Other blob types, i.e. X.509 certificates and deleted blobs, are skipped.
+const (
+	blobTypeHeader  = 1
+	blobTypeOpenPGP = 2
+)
+
+const (
+	keyboxMagic          = "KBXf"
+	keyboxHeaderLength   = 32
+	keyboxHeaderFlagsPGP = 0x0002
+	keyboxBlobVersion    = 1
+	keyboxKeyInfoSize    = 28
+	keyboxUIDInfoSize    = 12
+	keyboxSigInfoSize    = 4
+	keyboxChecksumSize   = sha1.Size
+	// keyboxMaxBlobLength mirrors the limit enforced by GnuPG when reading blobs.
+	keyboxMaxBlobLength = 5 * 1024 * 1024
+)
+
+// ReadKeybox reads a GnuPG keybox file (pubring.kbx) and returns the OpenPGP
+// keys it contains.
+// X.509 certificates and deleted blobs are skipped.
+func ReadKeybox(r io.Reader) (*crypto.KeyRing, error) {
+	var keyBlocks []byte
+	for {
+		blob, err := readBlob(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if blob[4] != blobTypeOpenPGP {
+			continue
+		}
+		keyBlock, err := openPGPBlobKeyBlock(blob)
+		if err != nil {
+			return nil, err
+		}
+		keyBlocks = append(keyBlocks, keyBlock...)
+	}
+	return crypto.NewKeyRingFromBinary(keyBlocks)
+}
+
+// WriteKeybox writes the public keys of the key ring as a GnuPG keybox file,
+// which can be used as pubring.kbx.
+// The creation time of the keybox and its blobs is set to unixTime.
+// Only version 4 keys are supported, as GnuPG does not handle version 6 keys.
+func WriteKeybox(w io.Writer, keyRing *crypto.KeyRing, unixTime int64) error {
+	if _, err := w.Write(keyboxHeaderBlob(unixTime)); err != nil {
+		return fmt.Errorf("gopenpgp: unable to write keybox: %w", err)
+	}
+	for _, key := range keyRing.GetKeys() {
+		if key.GetVersion() != 4 {
+			return fmt.Errorf("gopenpgp: unsupported key version %d in keybox", key.GetVersion())
+		}
+		publicKey, err := key.GetPublicKey()
+		if err != nil {
+			return fmt.Errorf("gopenpgp: unable to serialize public key: %w", err)
+		}
+		blob, err := openPGPBlob(publicKey, unixTime)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(blob); err != nil {
+			return fmt.Errorf("gopenpgp: unable to write keybox: %w", err)
+		}
+	}
+	return nil
+}
+
+// readBlob reads the next keybox blob including its length prefix.
+func readBlob(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errors.New("gopenpgp: truncated keybox blob")
+		}
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(prefix[:4])
+	if length < uint32(len(prefix)) || length > keyboxMaxBlobLength {
+		return nil, fmt.Errorf("gopenpgp: invalid keybox blob length %d", length)
+	}
+	blob := make([]byte, length)
+	copy(blob, prefix[:])
+	if _, err := io.ReadFull(r, blob[len(prefix):]); err != nil {
+		return nil, errors.New("gopenpgp: truncated keybox blob")
+	}
+	if blob[4] == blobTypeHeader && (length < keyboxHeaderLength || string(blob[8:12]) != keyboxMagic) {
+		return nil, errors.New("gopenpgp: invalid keybox header")
+	}
+	return blob, nil
+}
+
+// openPGPBlobKeyBlock verifies the checksum of an OpenPGP keybox blob and
+// returns the binary key block it stores.
+func openPGPBlobKeyBlock(blob []byte) ([]byte, error) {
+	if len(blob) < 20+keyboxChecksumSize {
+		return nil, errors.New("gopenpgp: truncated OpenPGP keybox blob")
+	}
+	if err := verifyBlobChecksum(blob); err != nil {
+		return nil, err
+	}
+	dataOffset := binary.BigEndian.Uint32(blob[8:])
+	dataLength := binary.BigEndian.Uint32(blob[12:])
+	end := uint64(dataOffset) + uint64(dataLength)
+	if end > uint64(len(blob)-keyboxChecksumSize) {
+		return nil, errors.New("gopenpgp: invalid key block offset in keybox blob")
+	}
+	return blob[dataOffset:end], nil
+}
+
+// verifyBlobChecksum checks the trailing SHA-1 checksum of a blob.
+// Blobs written by old GnuPG versions carry an MD5 checksum prefixed by four
+// zero bytes instead.
+func verifyBlobChecksum(blob []byte) error {
+	content := blob[:len(blob)-keyboxChecksumSize]
+	checksum := blob[len(blob)-keyboxChecksumSize:]
+	sha1Sum := sha1.Sum(content) //nolint:gosec
+	if bytes.Equal(checksum, sha1Sum[:]) {
+		return nil
+	}
+	md5Sum := md5.Sum(content) //nolint:gosec
+	if bytes.Equal(checksum[:4], []byte{0, 0, 0, 0}) && bytes.Equal(checksum[4:], md5Sum[:]) {
+		return nil
+	}
+	return errors.New("gopenpgp: keybox blob checksum mismatch")
+}
+
+func keyboxHeaderBlob(unixTime int64) []byte {
+	blob := make([]byte, 0, keyboxHeaderLength)
+	blob = binary.BigEndian.AppendUint32(blob, keyboxHeaderLength)
+	blob = append(blob, blobTypeHeader, keyboxBlobVersion)
+	blob = binary.BigEndian.AppendUint16(blob, keyboxHeaderFlagsPGP)
+	blob = append(blob, keyboxMagic...)
+	blob = binary.BigEndian.AppendUint32(blob, 0)
+	blob = binary.BigEndian.AppendUint32(blob, uint32(unixTime))
+	blob = binary.BigEndian.AppendUint32(blob, uint32(unixTime))
+	blob = binary.BigEndian.AppendUint32(blob, 0)
+	return binary.BigEndian.AppendUint32(blob, 0)
+}
+
+// openPGPBlob builds a version 1 OpenPGP keybox blob for a public key,
+// following the layout documented in keybox/keybox-blob.c.
+func openPGPBlob(keyBlock []byte, unixTime int64) ([]byte, error) {
+	packets, err := splitPackets(keyBlock)
+	if err != nil {
+		return nil, err
+	}
+	var fingerprints [][]byte
+	var uids []rawPacket
+	var signatures int
+	for _, packet := range packets {
+		switch packet.tag {
+		case tagPublicKey, tagPublicSubkey:
+			fingerprint, err := v4Fingerprint(packet.body)
+			if err != nil {
+				return nil, err
+			}
+			fingerprints = append(fingerprints, fingerprint)
+		case tagUserID:
+			uids = append(uids, packet)
+		case tagSignature:
+			signatures++
+		}
+	}
+	if len(fingerprints) == 0 || packets[0].tag != tagPublicKey {
+		return nil, errors.New("gopenpgp: key block does not start with a public key")
+	}
+
+	fixedLength := 20 +
+		len(fingerprints)*keyboxKeyInfoSize +
+		2 + // serial number length
+		4 + len(uids)*keyboxUIDInfoSize +
+		4 + signatures*keyboxSigInfoSize +
+		20
+	blob := make([]byte, 0, fixedLength+len(keyBlock)+keyboxChecksumSize)
+	blob = binary.BigEndian.AppendUint32(blob, uint32(fixedLength+len(keyBlock)+keyboxChecksumSize))
+	blob = append(blob, blobTypeOpenPGP, keyboxBlobVersion)
+	blob = binary.BigEndian.AppendUint16(blob, 0)
+	blob = binary.BigEndian.AppendUint32(blob, uint32(fixedLength))
+	blob = binary.BigEndian.AppendUint32(blob, uint32(len(keyBlock)))
+
+	blob = binary.BigEndian.AppendUint16(blob, uint16(len(fingerprints)))
+	blob = binary.BigEndian.AppendUint16(blob, keyboxKeyInfoSize)
+	for _, fingerprint := range fingerprints {
+		keyIDOffset := len(blob) + 12
+		blob = append(blob, fingerprint...)
+		blob = binary.BigEndian.AppendUint32(blob, uint32(keyIDOffset))
+		blob = binary.BigEndian.AppendUint16(blob, 0)
+		blob = binary.BigEndian.AppendUint16(blob, 0)
+	}
+
+	blob = binary.BigEndian.AppendUint16(blob, 0)
+
+	blob = binary.BigEndian.AppendUint16(blob, uint16(len(uids)))
+	blob = binary.BigEndian.AppendUint16(blob, keyboxUIDInfoSize)
+	for _, uid := range uids {
+		blob = binary.BigEndian.AppendUint32(blob, uint32(fixedLength+uid.bodyOffset))
+		blob = binary.BigEndian.AppendUint32(blob, uint32(len(uid.body)))
+		blob = binary.BigEndian.AppendUint16(blob, 0)
+		blob = append(blob, 0, 0)
+	}
+
+	blob = binary.BigEndian.AppendUint16(blob, uint16(signatures))
+	blob = binary.BigEndian.AppendUint16(blob, keyboxSigInfoSize)
+	for i := 0; i < signatures; i++ {
+		blob = binary.BigEndian.AppendUint32(blob, 0)
+	}
+
+	// Ownertrust, all validity and reserved bytes
+	blob = append(blob, 0, 0, 0, 0)
+	// Recheck after and latest timestamp
+	blob = binary.BigEndian.AppendUint32(blob, 0)
+	blob = binary.BigEndian.AppendUint32(blob, 0)
+	blob = binary.BigEndian.AppendUint32(blob, uint32(unixTime))
+	// Size of the reserved space
+	blob = binary.BigEndian.AppendUint32(blob, 0)
+
+	blob = append(blob, keyBlock...)
+	checksum := sha1.Sum(blob) //nolint:gosec
+	return append(blob, checksum[:]...), nil
+}
+
+// v4Fingerprint computes the fingerprint of a version 4 public key packet body.
+func v4Fingerprint(body []byte) ([]byte, error) {
+	if len(body) == 0 || body[0] != 4 {
+		return nil, errors.New("gopenpgp: unsupported key version in keybox")
+	}
+	h := sha1.New() //nolint:gosec
+	h.Write([]byte{0x99, byte(len(body) >> 8), byte(len(body))})
+	h.Write(body)
+	return h.Sum(nil), nil
+}
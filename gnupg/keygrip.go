@@ -0,0 +1,246 @@
+package gnupg
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/sha1" //nolint:gosec
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+// OpenPGP public key algorithms with a GnuPG keygrip.
+const (
+	algoRSA            = 1
+	algoRSAEncryptOnly = 2
+	algoRSASignOnly    = 3
+	algoElGamal        = 16
+	algoDSA            = 17
+	algoECDH           = 18
+	algoECDSA          = 19
+	algoEdDSA          = 22
+)
+
+// publicKeyMaterial holds the parsed algorithm specific fields of a version
+// 4 public key packet.
+type publicKeyMaterial struct {
+	algorithm uint8
+	// mpis holds the public MPIs in packet order, for ECC keys the point.
+	mpis  [][]byte
+	curve *curveParams
+}
+
+// curveParams holds the domain parameters libgcrypt hashes into the keygrip
+// of an ECC key.
+type curveParams struct {
+	// compact is set for curves whose points are stored with a 0x40 prefix.
+	compact       bool
+	p, a, b, g, n []byte
+}
+
+var curves = map[string]*curveParams{
+	// Ed25519, 1.3.6.1.4.1.11591.15.1
+	"2b06010401da470f01": {
+		compact: true,
+		p:       mustHex("7FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFED"),
+		a:       mustHex("01"),
+		b:       mustHex("2DFC9311D490018C7338BF8688861767FF8FF5B2BEBE27548A14B235ECA6874A"),
+		g: mustHex("04" +
+			"216936D3CD6E53FEC0A4E231FDD6DC5C692CC7609525A7B2C9562D608F25D51A" +
+			"6666666666666666666666666666666666666666666666666666666666666658"),
+		n: mustHex("1000000000000000000000000000000014DEF9DEA2F79CD65812631A5CF5D3ED"),
+	},
+	// Curve25519, 1.3.6.1.4.1.3029.1.5.1
+	"2b060104019755010501": {
+		compact: true,
+		p:       mustHex("7FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFED"),
+		a:       mustHex("01DB41"),
+		b:       mustHex("01"),
+		g: mustHex("04" +
+			"0000000000000000000000000000000000000000000000000000000000000009" +
+			"20AE19A1B8A086B4E01EDD2C7748D14C923D4D7E6D7C61B229E9C5A27ECED3D9"),
+		n: mustHex("1000000000000000000000000000000014DEF9DEA2F79CD65812631A5CF5D3ED"),
+	},
+	// NIST P-256, 1.2.840.10045.3.1.7
+	"2a8648ce3d030107": weierstrassCurve(elliptic.P256()),
+	// NIST P-384, 1.3.132.0.34
+	"2b81040022": weierstrassCurve(elliptic.P384()),
+	// NIST P-521, 1.3.132.0.35
+	"2b81040023": weierstrassCurve(elliptic.P521()),
+}
+
+// weierstrassCurve returns the keygrip parameters of a NIST curve, which
+// libgcrypt describes with a = p - 3.
+func weierstrassCurve(curve elliptic.Curve) *curveParams {
+	params := curve.Params()
+	size := (params.BitSize + 7) / 8
+	g := []byte{0x04}
+	g = append(g, params.Gx.FillBytes(make([]byte, size))...)
+	g = append(g, params.Gy.FillBytes(make([]byte, size))...)
+	return &curveParams{
+		p: params.P.Bytes(),
+		a: new(big.Int).Sub(params.P, big.NewInt(3)).Bytes(),
+		b: params.B.Bytes(),
+		g: g,
+		n: params.N.Bytes(),
+	}
+}
+
+func mustHex(s string) []byte {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// Keygrips returns the GnuPG keygrips of the primary key and all subkeys of
+// the key, in this order, as upper case hex strings.
+// The keygrip names the file of a secret key in the private-keys-v1.d
+// directory of a GnuPG home directory.
+func Keygrips(key *crypto.Key) ([]string, error) {
+	publicKey, err := key.GetPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	packets, err := splitPackets(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	var keygrips []string
+	for _, packet := range packets {
+		if packet.tag != tagPublicKey && packet.tag != tagPublicSubkey {
+			continue
+		}
+		keygrip, err := keygrip(packet.body)
+		if err != nil {
+			return nil, err
+		}
+		keygrips = append(keygrips, strings.ToUpper(hex.EncodeToString(keygrip)))
+	}
+	return keygrips, nil
+}
+
+// keygrip computes the keygrip of a version 4 public key packet body the way
+// libgcrypt's gcry_pk_get_keygrip does.
+func keygrip(body []byte) ([]byte, error) {
+	material, err := parsePublicKeyMaterial(body)
+	if err != nil {
+		return nil, err
+	}
+	h := sha1.New() //nolint:gosec
+	switch material.algorithm {
+	case algoRSA, algoRSAEncryptOnly, algoRSASignOnly:
+		h.Write(stdMPI(material.mpis[0]))
+	case algoDSA:
+		for i, name := range "pqgy" {
+			h.Write(keygripElement(byte(name), stdMPI(material.mpis[i])))
+		}
+	case algoElGamal:
+		for i, name := range "pgy" {
+			h.Write(keygripElement(byte(name), stdMPI(material.mpis[i])))
+		}
+	case algoECDSA, algoECDH, algoEdDSA:
+		curve := material.curve
+		q := material.mpis[0]
+		if curve.compact && len(q) > 0 && q[0] == 0x40 {
+			q = q[1:]
+		}
+		h.Write(keygripElement('p', curve.p))
+		h.Write(keygripElement('a', curve.a))
+		h.Write(keygripElement('b', curve.b))
+		h.Write(keygripElement('g', curve.g))
+		h.Write(keygripElement('n', curve.n))
+		h.Write(keygripElement('q', q))
+	default:
+		return nil, fmt.Errorf("gopenpgp: unsupported public key algorithm %d", material.algorithm)
+	}
+	return h.Sum(nil), nil
+}
+
+// keygripElement encodes a named parameter as canonical S-expression.
+func keygripElement(name byte, value []byte) []byte {
+	element := []byte{'(', '1', ':', name}
+	element = strconv.AppendInt(element, int64(len(value)), 10)
+	element = append(element, ':')
+	element = append(element, value...)
+	return append(element, ')')
+}
+
+// stdMPI returns the value in libgcrypt's standard format, which prefixes
+// values with the most significant bit set with a zero byte.
+func stdMPI(value []byte) []byte {
+	if len(value) > 0 && value[0]&0x80 != 0 {
+		return append([]byte{0}, value...)
+	}
+	return value
+}
+
+// parsePublicKeyMaterial parses the algorithm specific fields of a version 4
+// public key packet body.
+func parsePublicKeyMaterial(body []byte) (*publicKeyMaterial, error) {
+	if len(body) < 6 {
+		return nil, errors.New("gopenpgp: truncated public key packet")
+	}
+	if body[0] != 4 {
+		return nil, fmt.Errorf("gopenpgp: unsupported key version %d", body[0])
+	}
+	material := &publicKeyMaterial{algorithm: body[5]}
+	r := bytes.NewReader(body[6:])
+	var count int
+	switch material.algorithm {
+	case algoRSA, algoRSAEncryptOnly, algoRSASignOnly:
+		count = 2
+	case algoDSA:
+		count = 4
+	case algoElGamal:
+		count = 3
+	case algoECDSA, algoECDH, algoEdDSA:
+		oidLength, err := r.ReadByte()
+		if err != nil || int(oidLength) > r.Len() || oidLength == 0 || oidLength == 0xff {
+			return nil, errors.New("gopenpgp: invalid curve OID in public key packet")
+		}
+		oid := make([]byte, oidLength)
+		_, _ = r.Read(oid)
+		curve, ok := curves[hex.EncodeToString(oid)]
+		if !ok {
+			return nil, fmt.Errorf("gopenpgp: unsupported curve with OID %x", oid)
+		}
+		material.curve = curve
+		count = 1
+	default:
+		return nil, fmt.Errorf("gopenpgp: unsupported public key algorithm %d", material.algorithm)
+	}
+	for i := 0; i < count; i++ {
+		mpi, err := readMPI(r)
+		if err != nil {
+			return nil, err
+		}
+		material.mpis = append(material.mpis, mpi)
+	}
+	return material, nil
+}
+
+// readMPI reads an OpenPGP MPI and returns its value without leading zeros.
+func readMPI(r *bytes.Reader) ([]byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, errors.New("gopenpgp: truncated MPI")
+	}
+	bits := int(header[0])<<8 | int(header[1])
+	length := (bits + 7) / 8
+	if length > r.Len() {
+		return nil, errors.New("gopenpgp: truncated MPI")
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, errors.New("gopenpgp: truncated MPI")
+	}
+	return bytes.TrimLeft(value, "\x00"), nil
+}
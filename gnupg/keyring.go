@@ -0,0 +1,74 @@
+package gnupg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+// ReadKeyring reads a legacy GnuPG public keyring (pubring.gpg) and returns
+// the keys it contains.
+// GnuPG trust packets interleaved with the key material are dropped.
+func ReadKeyring(r io.Reader) (*crypto.KeyRing, error) {
+	keyBlock, err := readLegacyKeyring(r)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.NewKeyRingFromBinary(keyBlock)
+}
+
+// ReadSecretKeyring reads a legacy GnuPG secret keyring (secring.gpg) as
+// used by GnuPG 1.x and 2.0.
+// The keys are returned as stored, so keys protected with a passphrase are
+// locked and have to be unlocked with Key.Unlock before use.
+func ReadSecretKeyring(r io.Reader) ([]*crypto.Key, error) {
+	keyBlock, err := readLegacyKeyring(r)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := openpgp.ReadKeyRing(bytes.NewReader(keyBlock))
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in reading keyring: %w", err)
+	}
+	keys := make([]*crypto.Key, 0, len(entities))
+	for _, entity := range entities {
+		if entity.PrivateKey == nil {
+			return nil, errors.New("gopenpgp: secret keyring contains a public key")
+		}
+		key, err := crypto.NewKeyFromEntity(entity)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// readLegacyKeyring reads a legacy keyring and strips the GnuPG trust packets.
+func readLegacyKeyring(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: unable to read keyring: %w", err)
+	}
+	packets, err := splitPackets(data)
+	if err != nil {
+		return nil, err
+	}
+	keyBlock := make([]byte, 0, len(data))
+	for i, packet := range packets {
+		if packet.tag == tagTrust {
+			continue
+		}
+		end := len(data)
+		if i+1 < len(packets) {
+			end = packets[i+1].offset
+		}
+		keyBlock = append(keyBlock, data[packet.offset:end]...)
+	}
+	return keyBlock, nil
+}
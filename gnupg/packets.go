@@ -0,0 +1,117 @@
+package gnupg
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// OpenPGP packet tags used when walking key blocks.
+const (
+	tagSecretKey    = 5
+	tagPublicKey    = 6
+	tagSecretSubkey = 7
+	tagTrust        = 12
+	tagUserID       = 13
+	tagPublicSubkey = 14
+	tagSignature    = 2
+)
+
+// rawPacket is an OpenPGP packet split out of a binary key block without
+// interpreting its body.
+type rawPacket struct {
+	tag uint8
+	// offset is the position of the packet header in the key block.
+	offset int
+	// bodyOffset is the position of the packet body in the key block.
+	bodyOffset int
+	body       []byte
+}
+
+// splitPackets splits a binary key block into its packets.
+// Partial body lengths are rejected, as they are not allowed in keys.
+func splitPackets(data []byte) ([]rawPacket, error) {
+	var packets []rawPacket
+	for pos := 0; pos < len(data); {
+		start := pos
+		header := data[pos]
+		pos++
+		if header&0x80 == 0 {
+			return nil, errors.New("gopenpgp: invalid packet header")
+		}
+		var tag uint8
+		var length int
+		if header&0x40 == 0 {
+			// Old format packet
+			tag = (header & 0x3f) >> 2
+			lengthType := header & 0x03
+			switch lengthType {
+			case 0, 1, 2:
+				size := 1 << lengthType
+				if len(data)-pos < size {
+					return nil, errors.New("gopenpgp: truncated packet header")
+				}
+				for i := 0; i < size; i++ {
+					length = length<<8 | int(data[pos+i])
+				}
+				pos += size
+			default:
+				length = len(data) - pos
+			}
+		} else {
+			// New format packet
+			tag = header & 0x3f
+			if pos >= len(data) {
+				return nil, errors.New("gopenpgp: truncated packet header")
+			}
+			first := int(data[pos])
+			pos++
+			switch {
+			case first < 192:
+				length = first
+			case first < 224:
+				if pos >= len(data) {
+					return nil, errors.New("gopenpgp: truncated packet header")
+				}
+				length = (first-192)<<8 + int(data[pos]) + 192
+				pos++
+			case first == 255:
+				if len(data)-pos < 4 {
+					return nil, errors.New("gopenpgp: truncated packet header")
+				}
+				length = int(binary.BigEndian.Uint32(data[pos:]))
+				pos += 4
+			default:
+				return nil, errors.New("gopenpgp: partial body length in key block")
+			}
+		}
+		if length < 0 || len(data)-pos < length {
+			return nil, fmt.Errorf("gopenpgp: truncated packet with tag %d", tag)
+		}
+		packets = append(packets, rawPacket{
+			tag:        tag,
+			offset:     start,
+			bodyOffset: pos,
+			body:       data[pos : pos+length],
+		})
+		pos += length
+	}
+	return packets, nil
+}
+
+// appendPacket appends a new format packet with the given tag and body to buf.
+func appendPacket(buf []byte, tag uint8, body []byte) []byte {
+	buf = append(buf, 0xc0|tag)
+	length := len(body)
+	switch {
+	case length < 192:
+		buf = append(buf, byte(length))
+	case length < 8384:
+		length -= 192
+		buf = append(buf, byte(length>>8)+192, byte(length))
+	default:
+		buf = append(buf, 255)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(length))
+	}
+	return append(buf, body...)
+}
@@ -0,0 +1,248 @@
+package gnupg
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1" //nolint:gosec
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/ProtonMail/go-crypto/ocb"
+	"github.com/ProtonMail/go-crypto/openpgp/s2k"
+)
+
+// Protection modes of gpg-agent, see agent/protect.c in the GnuPG sources.
+const (
+	protectionCBC = "openpgp-s2k3-sha1-aes-cbc"
+	protectionOCB = "openpgp-s2k3-ocb-aes"
+)
+
+const (
+	protectionKeySize  = 16
+	protectionNonceOCB = 12
+	protectionTagOCB   = 16
+)
+
+// privateKeyExpression extracts the S-expression of a key file in
+// private-keys-v1.d, which is either stored directly or, since GnuPG 2.2.20,
+// as the "Key" entry of the extended name-value format.
+func privateKeyExpression(data []byte) ([]byte, error) {
+	if len(data) > 0 && data[0] == '(' {
+		return data, nil
+	}
+	var expression []byte
+	inKey := false
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+			// Continuation lines are folded without the leading space
+			if inKey {
+				expression = append(expression, line[1:]...)
+			}
+			continue
+		}
+		inKey = false
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		name, value, found := bytes.Cut(line, []byte(":"))
+		if found && bytes.EqualFold(name, []byte("Key")) {
+			if expression != nil {
+				return nil, errors.New("gopenpgp: duplicate key entry in private key file")
+			}
+			inKey = true
+			expression = append(expression, bytes.TrimLeft(value, " \t")...)
+		}
+	}
+	if expression == nil {
+		return nil, errors.New("gopenpgp: no key entry in private key file")
+	}
+	return expression, nil
+}
+
+// readPrivateKeyFile parses a key file of private-keys-v1.d and returns the
+// algorithm list with the public and the unprotected secret parameters,
+// e.g. (rsa (n ...)(e ...)(d ...)(p ...)(q ...)(u ...)).
+func readPrivateKeyFile(data, passphrase []byte) (*sexp, error) {
+	expression, err := privateKeyExpression(data)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseSexp(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(key.items) < 2 || !key.items[1].list {
+		return nil, errors.New("gopenpgp: invalid private key file")
+	}
+	algorithm := key.items[1]
+	switch key.name() {
+	case "private-key":
+		return algorithm, nil
+	case "protected-private-key":
+		return unprotect(algorithm, passphrase)
+	case "shadowed-private-key":
+		return nil, errors.New("gopenpgp: private key is stored on a smartcard")
+	default:
+		return nil, fmt.Errorf("gopenpgp: unsupported private key type %q", key.name())
+	}
+}
+
+// unprotect decrypts the protected parameters of an algorithm list and
+// returns the list with the secret parameters in place of the protected ones.
+func unprotect(algorithm *sexp, passphrase []byte) (*sexp, error) {
+	protected := algorithm.find("protected")
+	if protected == nil || len(protected.items) < 4 ||
+		!protected.items[2].list || len(protected.items[2].items) < 2 ||
+		protected.items[3].list {
+		return nil, errors.New("gopenpgp: invalid protected private key")
+	}
+	mode := string(protected.items[1].atom)
+	s2kParams := protected.items[2].items[0]
+	iv := protected.items[2].items[1].atom
+	ciphertext := protected.items[3].atom
+
+	key, err := deriveProtectionKey(s2kParams, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var plaintext []byte
+	switch mode {
+	case protectionOCB:
+		aead, err := ocb.NewOCBWithNonceAndTagSize(block, protectionNonceOCB, protectionTagOCB)
+		if err != nil {
+			return nil, err
+		}
+		if len(iv) != protectionNonceOCB {
+			return nil, errors.New("gopenpgp: invalid nonce in protected private key")
+		}
+		// The public parameters and the protection timestamp are
+		// authenticated as additional data
+		publicOnly := rebuildAlgorithm(algorithm, nil)
+		plaintext, err = aead.Open(nil, iv, ciphertext, publicOnly.canonical())
+		if err != nil {
+			return nil, errors.New("gopenpgp: unable to unprotect private key, wrong passphrase")
+		}
+	case protectionCBC:
+		if len(iv) != aes.BlockSize || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+			return nil, errors.New("gopenpgp: invalid protected private key")
+		}
+		plaintext = make([]byte, len(ciphertext))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	default:
+		return nil, fmt.Errorf("gopenpgp: unsupported private key protection %q", mode)
+	}
+
+	// The plaintext holds the list of secret parameters, followed by a hash
+	// over the plain key in CBC mode: ((secret parameters)(hash sha1 mic))
+	plain, err := parseSexp(plaintext)
+	if err != nil || len(plain.items) == 0 || !plain.items[0].list {
+		return nil, errors.New("gopenpgp: unable to unprotect private key, wrong passphrase")
+	}
+	secret := plain.items[0]
+	var mic []byte
+	if mode == protectionCBC {
+		hash := plain.find("hash")
+		if hash == nil || len(hash.items) != 3 || string(hash.items[1].atom) != "sha1" {
+			return nil, errors.New("gopenpgp: unable to unprotect private key, wrong passphrase")
+		}
+		mic = hash.items[2].atom
+	}
+	unprotected := rebuildAlgorithm(algorithm, secret.items)
+	if mic != nil {
+		expected := sha1.Sum(unprotected.canonical()) //nolint:gosec
+		if !bytes.Equal(mic, expected[:]) {
+			return nil, errors.New("gopenpgp: unable to unprotect private key, wrong passphrase")
+		}
+	}
+	return unprotected, nil
+}
+
+// rebuildAlgorithm returns a copy of a protected algorithm list with the
+// protection parameters replaced by the given secret parameters.
+// The protection timestamp is kept, as it is covered by the integrity check.
+func rebuildAlgorithm(algorithm *sexp, secret []*sexp) *sexp {
+	rebuilt := &sexp{list: true}
+	for _, item := range algorithm.items {
+		if item.name() == "protected" {
+			rebuilt.items = append(rebuilt.items, secret...)
+			continue
+		}
+		rebuilt.items = append(rebuilt.items, item)
+	}
+	return rebuilt
+}
+
+// deriveProtectionKey derives the AES-128 key from the passphrase with the
+// iterated and salted S2K parameters (sha1 salt count).
+func deriveProtectionKey(params *sexp, passphrase []byte) ([]byte, error) {
+	if !params.list || len(params.items) != 3 || params.name() != "sha1" || len(params.items[1].atom) != 8 {
+		return nil, errors.New("gopenpgp: unsupported S2K parameters in protected private key")
+	}
+	count, err := strconv.Atoi(string(params.items[2].atom))
+	if err != nil || count <= 0 {
+		return nil, errors.New("gopenpgp: invalid S2K count in protected private key")
+	}
+	key := make([]byte, protectionKeySize)
+	s2k.Iterated(key, sha1.New(), passphrase, params.items[1].atom, count) //nolint:gosec
+	return key, nil
+}
+
+// secretKeyPacketBody appends the unencrypted secret parameters taken from
+// the algorithm list of a private key file to a version 4 public key packet
+// body.
+func secretKeyPacketBody(publicBody []byte, algorithm *sexp) ([]byte, error) {
+	material, err := parsePublicKeyMaterial(publicBody)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	switch material.algorithm {
+	case algoRSA, algoRSAEncryptOnly, algoRSASignOnly:
+		names = []string{"d", "p", "q", "u"}
+	case algoDSA, algoElGamal:
+		names = []string{"x"}
+	case algoECDSA, algoECDH, algoEdDSA:
+		names = []string{"d"}
+	}
+	var secret []byte
+	for _, name := range names {
+		value := algorithm.value(name)
+		if value == nil {
+			return nil, fmt.Errorf("gopenpgp: private key file lacks parameter %q", name)
+		}
+		secret = appendMPI(secret, value)
+	}
+	var checksum uint16
+	for _, b := range secret {
+		checksum += uint16(b)
+	}
+	body := append([]byte{}, publicBody...)
+	body = append(body, 0) // Unencrypted
+	body = append(body, secret...)
+	return append(body, byte(checksum>>8), byte(checksum)), nil
+}
+
+// dummySecretKeyPacketBody appends the GnuPG extension marking secret key
+// material as not available to a version 4 public key packet body.
+func dummySecretKeyPacketBody(publicBody []byte) []byte {
+	body := append([]byte{}, publicBody...)
+	// S2K usage, AES-128, GNU S2K with SHA-1 and mode 1 (no secret key)
+	return append(body, 254, 7, 101, 2, 'G', 'N', 'U', 1)
+}
+
+// appendMPI appends the value encoded as OpenPGP MPI.
+func appendMPI(buf, value []byte) []byte {
+	value = bytes.TrimLeft(value, "\x00")
+	bits := new(big.Int).SetBytes(value).BitLen()
+	buf = append(buf, byte(bits>>8), byte(bits))
+	return append(buf, value...)
+}
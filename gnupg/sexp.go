@@ -0,0 +1,286 @@
+package gnupg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+)
+
+// sexp is a node of an S-expression as used by libgcrypt: either an atom
+// (a byte string) or a list of nodes.
+type sexp struct {
+	atom  []byte
+	items []*sexp
+	list  bool
+}
+
+// name returns the first atom of a list, which names the list.
+func (s *sexp) name() string {
+	if !s.list || len(s.items) == 0 || s.items[0].list {
+		return ""
+	}
+	return string(s.items[0].atom)
+}
+
+// find returns the first sub-list with the given name.
+func (s *sexp) find(name string) *sexp {
+	for _, item := range s.items {
+		if item.list && item.name() == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// value returns the atom following the name of the sub-list with the given
+// name, e.g. the data of (n #00C1...#).
+func (s *sexp) value(name string) []byte {
+	item := s.find(name)
+	if item == nil || len(item.items) < 2 || item.items[1].list {
+		return nil
+	}
+	return item.items[1].atom
+}
+
+// canonical encodes the S-expression in the canonical format.
+func (s *sexp) canonical() []byte {
+	return s.appendCanonical(nil)
+}
+
+func (s *sexp) appendCanonical(buf []byte) []byte {
+	if !s.list {
+		buf = strconv.AppendInt(buf, int64(len(s.atom)), 10)
+		buf = append(buf, ':')
+		return append(buf, s.atom...)
+	}
+	buf = append(buf, '(')
+	for _, item := range s.items {
+		buf = item.appendCanonical(buf)
+	}
+	return append(buf, ')')
+}
+
+// parseSexp parses an S-expression in canonical or advanced format.
+// Trailing data after the expression, such as padding, is ignored.
+func parseSexp(data []byte) (*sexp, error) {
+	p := &sexpParser{data: data}
+	p.skipSpace()
+	if p.pos >= len(p.data) || p.data[p.pos] != '(' {
+		return nil, errors.New("gopenpgp: S-expression does not start with a list")
+	}
+	return p.parse()
+}
+
+type sexpParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *sexpParser) skipSpace() {
+	for p.pos < len(p.data) && isSexpSpace(p.data[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *sexpParser) parse() (*sexp, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, errors.New("gopenpgp: truncated S-expression")
+	}
+	switch c := p.data[p.pos]; {
+	case c == '(':
+		p.pos++
+		list := &sexp{list: true}
+		for {
+			p.skipSpace()
+			if p.pos >= len(p.data) {
+				return nil, errors.New("gopenpgp: unterminated S-expression list")
+			}
+			if p.data[p.pos] == ')' {
+				p.pos++
+				return list, nil
+			}
+			if p.data[p.pos] == '[' {
+				// Display hints are not needed and dropped
+				if err := p.skipDisplayHint(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			item, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			list.items = append(list.items, item)
+		}
+	default:
+		atom, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		return &sexp{atom: atom}, nil
+	}
+}
+
+func (p *sexpParser) skipDisplayHint() error {
+	p.pos++
+	if _, err := p.parseAtom(); err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.pos >= len(p.data) || p.data[p.pos] != ']' {
+		return errors.New("gopenpgp: unterminated S-expression display hint")
+	}
+	p.pos++
+	return nil
+}
+
+func (p *sexpParser) parseAtom() ([]byte, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, errors.New("gopenpgp: truncated S-expression")
+	}
+	c := p.data[p.pos]
+	switch {
+	case c >= '0' && c <= '9':
+		start := p.pos
+		for p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' {
+			p.pos++
+		}
+		length, err := strconv.Atoi(string(p.data[start:p.pos]))
+		if err != nil || p.pos >= len(p.data) {
+			return nil, errors.New("gopenpgp: invalid S-expression length")
+		}
+		if p.data[p.pos] != ':' {
+			// Length prefixes of the other encodings are only a hint
+			return p.parseAtom()
+		}
+		p.pos++
+		if length > len(p.data)-p.pos {
+			return nil, errors.New("gopenpgp: truncated S-expression atom")
+		}
+		atom := p.data[p.pos : p.pos+length]
+		p.pos += length
+		return atom, nil
+	case c == '#':
+		return p.parseDelimited('#', func(data []byte) ([]byte, error) {
+			return hex.DecodeString(string(stripSpace(data)))
+		})
+	case c == '|':
+		return p.parseDelimited('|', func(data []byte) ([]byte, error) {
+			return base64.StdEncoding.DecodeString(string(stripSpace(data)))
+		})
+	case c == '"':
+		return p.parseString()
+	case isTokenChar(c):
+		start := p.pos
+		for p.pos < len(p.data) && (isTokenChar(p.data[p.pos]) || p.data[p.pos] >= '0' && p.data[p.pos] <= '9') {
+			p.pos++
+		}
+		return p.data[start:p.pos], nil
+	}
+	return nil, errors.New("gopenpgp: invalid character in S-expression")
+}
+
+func (p *sexpParser) parseDelimited(delimiter byte, decode func([]byte) ([]byte, error)) ([]byte, error) {
+	end := bytes.IndexByte(p.data[p.pos+1:], delimiter)
+	if end < 0 {
+		return nil, errors.New("gopenpgp: unterminated S-expression atom")
+	}
+	atom, err := decode(p.data[p.pos+1 : p.pos+1+end])
+	if err != nil {
+		return nil, errors.New("gopenpgp: invalid S-expression atom encoding")
+	}
+	p.pos += end + 2
+	return atom, nil
+}
+
+// parseString parses a quoted string with C-like escape sequences.
+func (p *sexpParser) parseString() ([]byte, error) {
+	var atom []byte
+	for p.pos++; p.pos < len(p.data); p.pos++ {
+		c := p.data[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return atom, nil
+		case '\\':
+			p.pos++
+			if p.pos >= len(p.data) {
+				return nil, errors.New("gopenpgp: unterminated S-expression string")
+			}
+			escaped, err := p.parseEscape()
+			if err != nil {
+				return nil, err
+			}
+			atom = append(atom, escaped...)
+		default:
+			atom = append(atom, c)
+		}
+	}
+	return nil, errors.New("gopenpgp: unterminated S-expression string")
+}
+
+func (p *sexpParser) parseEscape() ([]byte, error) {
+	switch c := p.data[p.pos]; c {
+	case 'b':
+		return []byte{'\b'}, nil
+	case 't':
+		return []byte{'\t'}, nil
+	case 'v':
+		return []byte{'\v'}, nil
+	case 'n':
+		return []byte{'\n'}, nil
+	case 'f':
+		return []byte{'\f'}, nil
+	case 'r':
+		return []byte{'\r'}, nil
+	case '"', '\'', '\\':
+		return []byte{c}, nil
+	case '\n', '\r':
+		// Line continuation, optionally followed by the other line break character
+		if p.pos+1 < len(p.data) && (p.data[p.pos+1] == '\n' || p.data[p.pos+1] == '\r') && p.data[p.pos+1] != c {
+			p.pos++
+		}
+		return nil, nil
+	case 'x':
+		if len(p.data)-p.pos < 3 {
+			return nil, errors.New("gopenpgp: invalid S-expression escape sequence")
+		}
+		value, err := strconv.ParseUint(string(p.data[p.pos+1:p.pos+3]), 16, 8)
+		if err != nil {
+			return nil, errors.New("gopenpgp: invalid S-expression escape sequence")
+		}
+		p.pos += 2
+		return []byte{byte(value)}, nil
+	default:
+		if len(p.data)-p.pos < 3 {
+			return nil, errors.New("gopenpgp: invalid S-expression escape sequence")
+		}
+		value, err := strconv.ParseUint(string(p.data[p.pos:p.pos+3]), 8, 8)
+		if err != nil {
+			return nil, errors.New("gopenpgp: invalid S-expression escape sequence")
+		}
+		p.pos += 2
+		return []byte{byte(value)}, nil
+	}
+}
+
+func isSexpSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isTokenChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || bytes.IndexByte([]byte("-./_:*+="), c) >= 0
+}
+
+func stripSpace(data []byte) []byte {
+	return bytes.Map(func(r rune) rune {
+		if r < 0x80 && isSexpSpace(byte(r)) {
+			return -1
+		}
+		return r
+	}, data)
+}
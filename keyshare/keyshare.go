@@ -0,0 +1,202 @@
+// Package keyshare backs up private keys with Shamir's secret sharing.
+//
+// A private key is split into shares for a set of custodians, of which a
+// threshold number of shares is required to recover the key. Each share is
+// encrypted to its custodian and armored as "PGP SECRET KEY SHARE".
+package keyshare
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lovoo/gopenpgp/v3/armor"
+	"github.com/lovoo/gopenpgp/v3/constants"
+	"github.com/lovoo/gopenpgp/v3/crypto"
+	"github.com/lovoo/gopenpgp/v3/internal"
+)
+
+// shareVersion is the version of the serialized share format:
+// version, threshold, index, fingerprint length, fingerprint, SHA-256 digest
+// of the serialized key and share data.
+const shareVersion = 1
+
+// Share is a decrypted share of a private key.
+type Share struct {
+	// Fingerprint is the fingerprint of the primary key of the shared key.
+	Fingerprint []byte
+	// Threshold is the number of shares required to recover the key.
+	Threshold int
+	// Index identifies the share, starting at 1.
+	Index int
+	// Digest is the SHA-256 digest of the serialized private key, which
+	// verifies the recovered key.
+	Digest []byte
+	// Data is the secret share of the serialized private key.
+	Data []byte
+}
+
+// Split splits the unlocked private key into one share per custodian, of
+// which threshold shares are required to recover the key.
+// Each share is encrypted to the public key of the custodian with the
+// encryption handle of pgp and returned armored, in the order of the
+// custodians.
+func Split(pgp *crypto.PGPHandle, key *crypto.Key, threshold int, custodians ...*crypto.Key) ([][]byte, error) {
+	unlocked, err := key.IsUnlocked()
+	if err != nil {
+		return nil, err
+	}
+	if !unlocked {
+		return nil, errors.New("gopenpgp: splitting a key requires an unlocked key")
+	}
+	serialized, err := key.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	defer clear(serialized)
+	digest := sha256.Sum256(serialized)
+	shares, err := split(serialized, len(custodians), threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	armoredShares := make([][]byte, len(custodians))
+	for i, custodian := range custodians {
+		share := &Share{
+			Fingerprint: key.GetFingerprintBytes(),
+			Threshold:   threshold,
+			Index:       i + 1,
+			Digest:      digest[:],
+			Data:        shares[i],
+		}
+		armoredShares[i], err = share.encrypt(pgp, custodian)
+		share.Clear()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return armoredShares, nil
+}
+
+// DecryptShare decrypts an armored share with the private key of the
+// custodian.
+func DecryptShare(pgp *crypto.PGPHandle, armored []byte, custodian *crypto.Key) (*Share, error) {
+	block, err := internal.UnarmorBytes(armored)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != constants.SecretKeyShareHeader {
+		return nil, fmt.Errorf("gopenpgp: unexpected armor type %q of key share", block.Type)
+	}
+	message, err := io.ReadAll(block.Body)
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: unable to unarmor key share: %w", err)
+	}
+	decHandle, err := pgp.Decryption().DecryptionKey(custodian).New()
+	if err != nil {
+		return nil, err
+	}
+	result, err := decHandle.Decrypt(message, crypto.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return ParseShare(result.Bytes())
+}
+
+// ParseShare parses a share, as serialized by Serialize.
+func ParseShare(data []byte) (*Share, error) {
+	if len(data) < 4 || data[0] != shareVersion {
+		return nil, errors.New("gopenpgp: unsupported key share")
+	}
+	fingerprintLength := int(data[3])
+	dataOffset := 4 + fingerprintLength + sha256.Size
+	if len(data) < dataOffset {
+		return nil, errors.New("gopenpgp: truncated key share")
+	}
+	share := &Share{
+		Threshold:   int(data[1]),
+		Index:       int(data[2]),
+		Fingerprint: append([]byte{}, data[4:4+fingerprintLength]...),
+		Digest:      append([]byte{}, data[4+fingerprintLength:dataOffset]...),
+		Data:        append([]byte{}, data[dataOffset:]...),
+	}
+	if share.Index == 0 || share.Threshold < 2 {
+		return nil, errors.New("gopenpgp: invalid key share")
+	}
+	return share, nil
+}
+
+// Serialize serializes the share, e.g. to hand it over for recovery.
+func (share *Share) Serialize() []byte {
+	data := []byte{shareVersion, byte(share.Threshold), byte(share.Index), byte(len(share.Fingerprint))}
+	data = append(data, share.Fingerprint...)
+	data = append(data, share.Digest...)
+	return append(data, share.Data...)
+}
+
+// Clear zeroes the secret share data.
+func (share *Share) Clear() {
+	clear(share.Data)
+}
+
+func (share *Share) encrypt(pgp *crypto.PGPHandle, custodian *crypto.Key) ([]byte, error) {
+	encHandle, err := pgp.Encryption().Recipient(custodian).New()
+	if err != nil {
+		return nil, err
+	}
+	serialized := share.Serialize()
+	defer clear(serialized)
+	message, err := encHandle.Encrypt(serialized)
+	if err != nil {
+		return nil, err
+	}
+	return armor.ArmorWithTypeBytes(message.Bytes(), constants.SecretKeyShareHeader)
+}
+
+// Recover recovers the private key from at least threshold shares.
+// The recovered key must match the digest and fingerprint of the shares.
+func Recover(shares ...*Share) (*crypto.Key, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("gopenpgp: no key shares provided")
+	}
+	first := shares[0]
+	if first.Threshold < 2 || first.Threshold > 255 {
+		return nil, errors.New("gopenpgp: invalid key share")
+	}
+	if len(shares) < first.Threshold {
+		return nil, fmt.Errorf("gopenpgp: %d key shares provided, %d required", len(shares), first.Threshold)
+	}
+	shares = shares[:first.Threshold]
+	xs := make([]byte, len(shares))
+	data := make([][]byte, len(shares))
+	for i, share := range shares {
+		if !bytes.Equal(share.Fingerprint, first.Fingerprint) || !bytes.Equal(share.Digest, first.Digest) ||
+			share.Threshold != first.Threshold {
+			return nil, errors.New("gopenpgp: key shares belong to different keys")
+		}
+		if len(share.Data) != len(first.Data) || share.Index < 1 || share.Index > 255 {
+			return nil, errors.New("gopenpgp: invalid key share")
+		}
+		if bytes.IndexByte(xs[:i], byte(share.Index)) != -1 {
+			return nil, fmt.Errorf("gopenpgp: duplicate key share %d", share.Index)
+		}
+		xs[i], data[i] = byte(share.Index), share.Data
+	}
+
+	secret := combine(xs, data)
+	defer clear(secret)
+	if digest := sha256.Sum256(secret); !bytes.Equal(digest[:], first.Digest) {
+		return nil, errors.New("gopenpgp: key shares do not recover the shared key")
+	}
+	key, err := crypto.NewKey(secret)
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: unable to recover key from shares: %w", err)
+	}
+	if !bytes.Equal(key.GetFingerprintBytes(), first.Fingerprint) {
+		key.ClearPrivateParams()
+		return nil, errors.New("gopenpgp: recovered key does not match the fingerprint of the shares")
+	}
+	return key, nil
+}
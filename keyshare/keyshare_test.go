@@ -0,0 +1,144 @@
+package keyshare
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/constants"
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+const testMessage = "escrow"
+
+func generateTestKey(t *testing.T, name string) *crypto.Key {
+	key, err := crypto.PGP().KeyGeneration().AddUserId(name, name+"@example.com").New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	return key
+}
+
+func TestShamir(t *testing.T) {
+	secret := make([]byte, 64)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatal("Expected no error while generating secret, got:", err)
+	}
+	shares, err := split(secret, 5, 3)
+	if err != nil {
+		t.Fatal("Expected no error while splitting secret, got:", err)
+	}
+	for _, xs := range [][]byte{{1, 2, 3}, {5, 3, 1}, {2, 4, 5}} {
+		data := make([][]byte, len(xs))
+		for i, x := range xs {
+			data[i] = shares[x-1]
+		}
+		assert.Exactly(t, secret, combine(xs, data))
+	}
+	assert.NotEqual(t, secret, combine([]byte{1, 2}, [][]byte{shares[0], shares[1]}))
+
+	_, err = split(secret, 2, 3)
+	assert.Error(t, err)
+	_, err = split(secret, 3, 1)
+	assert.Error(t, err)
+}
+
+func TestSplitRecover(t *testing.T) {
+	pgp := crypto.PGP()
+	key := generateTestKey(t, "owner")
+	custodians := []*crypto.Key{generateTestKey(t, "alice"), generateTestKey(t, "bob"), generateTestKey(t, "carol")}
+	armoredShares, err := Split(pgp, key, 2, custodians...)
+	if err != nil {
+		t.Fatal("Expected no error while splitting key, got:", err)
+	}
+	if !assert.Len(t, armoredShares, 3) {
+		return
+	}
+	assert.True(t, strings.HasPrefix(string(armoredShares[0]), "-----BEGIN "+constants.SecretKeyShareHeader+"-----"))
+
+	shares := make([]*Share, len(custodians))
+	for i, custodian := range custodians {
+		shares[i], err = DecryptShare(pgp, armoredShares[i], custodian)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting share, got:", err)
+		}
+		assert.Exactly(t, i+1, shares[i].Index)
+		assert.Exactly(t, key.GetFingerprintBytes(), shares[i].Fingerprint)
+	}
+	_, err = DecryptShare(pgp, armoredShares[0], custodians[1])
+	assert.Error(t, err)
+
+	for _, pair := range [][]int{{0, 1}, {2, 0}, {1, 2}} {
+		// Shares are handed over serialized
+		share, err := ParseShare(shares[pair[1]].Serialize())
+		if err != nil {
+			t.Fatal("Expected no error while parsing share, got:", err)
+		}
+		recovered, err := Recover(shares[pair[0]], share)
+		if err != nil {
+			t.Fatal("Expected no error while recovering key, got:", err)
+		}
+		assert.Exactly(t, key.GetFingerprint(), recovered.GetFingerprint())
+
+		signer, err := pgp.Sign().SigningKey(recovered).New()
+		if err != nil {
+			t.Fatal("Expected no error while creating signer, got:", err)
+		}
+		_, err = signer.Sign([]byte(testMessage), crypto.Bytes)
+		assert.NoError(t, err)
+	}
+}
+
+func TestRecoverErrors(t *testing.T) {
+	pgp := crypto.PGP()
+	key := generateTestKey(t, "owner")
+	custodian := generateTestKey(t, "alice")
+	decryptShares := func(key *crypto.Key) []*Share {
+		armoredShares, err := Split(pgp, key, 2, custodian, custodian, custodian)
+		if err != nil {
+			t.Fatal("Expected no error while splitting key, got:", err)
+		}
+		shares := make([]*Share, len(armoredShares))
+		for i, armoredShare := range armoredShares {
+			if shares[i], err = DecryptShare(pgp, armoredShare, custodian); err != nil {
+				t.Fatal("Expected no error while decrypting share, got:", err)
+			}
+		}
+		return shares
+	}
+	shares := decryptShares(key)
+	otherShares := decryptShares(generateTestKey(t, "other"))
+
+	_, err := Recover(shares[0])
+	assert.Error(t, err)
+	_, err = Recover(shares[0], shares[0])
+	assert.Error(t, err)
+	_, err = Recover(shares[0], otherShares[1])
+	assert.Error(t, err)
+
+	tampered := *shares[1]
+	tampered.Data = bytes.Clone(tampered.Data)
+	tampered.Data[len(tampered.Data)/2] ^= 1
+	_, err = Recover(shares[0], &tampered)
+	assert.Error(t, err)
+
+	// Constructed shares with an invalid threshold
+	for _, threshold := range []int{-1, 0, 1, 256} {
+		constructed := *shares[0]
+		constructed.Threshold = threshold
+		_, err = Recover(&constructed, shares[1])
+		assert.Error(t, err)
+	}
+
+	locked, err := pgp.LockKey(key, []byte("passphrase"))
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	_, err = Split(pgp, locked, 2, custodian, custodian)
+	assert.Error(t, err)
+	_, err = Split(pgp, key, 3, custodian, custodian)
+	assert.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package keyshare
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+// Arithmetic in GF(256) with the AES polynomial x^8 + x^4 + x^3 + x + 1,
+// using logarithm tables with the generator 3.
+var (
+	gfExp [510]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfExp[i+255] = x
+		gfLog[x] = byte(i)
+		// Multiply by the generator 3 = x + 1
+		x ^= gfMulX(x)
+	}
+}
+
+// gfMulX multiplies by x modulo the AES polynomial.
+func gfMulX(a byte) byte {
+	if a&0x80 != 0 {
+		return a<<1 ^ 0x1b
+	}
+	return a << 1
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// split splits the secret into n shares, of which any threshold shares
+// recover the secret. The share with index i is evaluated at x = i + 1.
+func split(secret []byte, n, threshold int) ([][]byte, error) {
+	if threshold < 2 || threshold > n || n > 255 {
+		return nil, errors.New("gopenpgp: invalid secret sharing threshold")
+	}
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret))
+	}
+	coefficients := make([]byte, threshold)
+	defer clear(coefficients)
+	for j, b := range secret {
+		// Random polynomial of degree threshold - 1 with the secret at x = 0
+		coefficients[0] = b
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			x := byte(i + 1)
+			// Horner's method
+			y := coefficients[threshold-1]
+			for k := threshold - 2; k >= 0; k-- {
+				y = gfMul(y, x) ^ coefficients[k]
+			}
+			shares[i][j] = y
+		}
+	}
+	return shares, nil
+}
+
+// combine recovers the secret from shares evaluated at the distinct non-zero
+// points xs, by Lagrange interpolation at x = 0.
+func combine(xs []byte, shares [][]byte) []byte {
+	secret := make([]byte, len(shares[0]))
+	for i, share := range shares {
+		// Lagrange basis polynomial of xs[i] at x = 0
+		basis := byte(1)
+		for k, x := range xs {
+			if k != i {
+				basis = gfMul(basis, gfDiv(x, x^xs[i]))
+			}
+		}
+		for j, y := range share {
+			secret[j] ^= gfMul(basis, y)
+		}
+	}
+	return secret
+}
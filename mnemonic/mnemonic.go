@@ -0,0 +1,146 @@
+// Package mnemonic encodes entropy as BIP39 mnemonics of English words and
+// derives seeds from them, e.g. to regenerate keys deterministically with
+// crypto.KeyGenerationBuilder.Seed.
+package mnemonic
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Parameters of BIP39.
+const (
+	// bitsPerWord is the number of bits encoded by each word.
+	bitsPerWord = 11
+	// seedIterations is the number of PBKDF2 iterations to derive a seed.
+	seedIterations = 2048
+	// seedLength is the length of derived seeds in bytes.
+	seedLength = 64
+)
+
+var words = strings.Split(strings.TrimSpace(english), "\n")
+
+var wordIndices = func() map[string]int {
+	indices := make(map[string]int, len(words))
+	for i, word := range words {
+		indices[word] = i
+	}
+	return indices
+}()
+
+// Generate returns a new mnemonic for random entropy of the given size in
+// bits, i.e. 128 bits for 12 words up to 256 bits for 24 words.
+func Generate(entropyBits int) (string, error) {
+	if entropyBits%8 != 0 {
+		return "", errors.New("gopenpgp: mnemonic entropy must be 128 to 256 bits in steps of 32 bits")
+	}
+	if err := validEntropySize(entropyBits / 8); err != nil {
+		return "", err
+	}
+	entropy := make([]byte, entropyBits/8)
+	defer clear(entropy)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", fmt.Errorf("gopenpgp: error in generating mnemonic entropy: %w", err)
+	}
+	return FromEntropy(entropy)
+}
+
+// FromEntropy encodes the entropy of 16 to 32 bytes, in steps of 4 bytes,
+// as mnemonic with a SHA-256 checksum.
+func FromEntropy(entropy []byte) (string, error) {
+	if err := validEntropySize(len(entropy)); err != nil {
+		return "", err
+	}
+	checksum := sha256.Sum256(entropy)
+	// The checksum is the first bit of the digest per 4 bytes of entropy
+	data := append(append([]byte{}, entropy...), checksum[0])
+	defer clear(data)
+	wordCount := (len(entropy)*8 + len(entropy)/4) / bitsPerWord
+
+	mnemonic := make([]string, wordCount)
+	for i := range mnemonic {
+		mnemonic[i] = words[readBits(data, i*bitsPerWord)]
+	}
+	return strings.Join(mnemonic, " "), nil
+}
+
+// ToEntropy decodes the mnemonic and returns its entropy.
+// An error is returned for unknown words or if the checksum does not match.
+func ToEntropy(mnemonic string) ([]byte, error) {
+	mnemonicWords := strings.Fields(strings.ToLower(mnemonic))
+	entropyLength := len(mnemonicWords) * bitsPerWord * 32 / 33 / 8
+	if len(mnemonicWords)%3 != 0 || validEntropySize(entropyLength) != nil {
+		return nil, fmt.Errorf("gopenpgp: invalid mnemonic length of %d words", len(mnemonicWords))
+	}
+	// Entropy and checksum with room for the trailing bits of the last word
+	data := make([]byte, entropyLength+2)
+	for i, word := range mnemonicWords {
+		index, ok := wordIndices[word]
+		if !ok {
+			clear(data)
+			return nil, fmt.Errorf("gopenpgp: unknown mnemonic word %q", word)
+		}
+		writeBits(data, i*bitsPerWord, index)
+	}
+	entropy := data[:entropyLength]
+	checksumBits := entropyLength / 4
+	checksum := sha256.Sum256(entropy)
+	if (data[entropyLength]^checksum[0])>>(8-checksumBits) != 0 {
+		clear(data)
+		return nil, errors.New("gopenpgp: invalid mnemonic checksum")
+	}
+	clear(data[entropyLength:])
+	return entropy, nil
+}
+
+// IsValid returns true if the mnemonic consists of known words and its
+// checksum matches.
+func IsValid(mnemonic string) bool {
+	entropy, err := ToEntropy(mnemonic)
+	clear(entropy)
+	return err == nil
+}
+
+// ToSeed validates the mnemonic and derives the 64-byte seed of BIP39 from
+// it and the optional passphrase.
+func ToSeed(mnemonic, passphrase string) ([]byte, error) {
+	if !IsValid(mnemonic) {
+		return nil, errors.New("gopenpgp: invalid mnemonic")
+	}
+	normalized := norm.NFKD.String(strings.Join(strings.Fields(strings.ToLower(mnemonic)), " "))
+	salt := norm.NFKD.String("mnemonic" + passphrase)
+	return pbkdf2.Key([]byte(normalized), []byte(salt), seedIterations, seedLength, sha512.New), nil
+}
+
+func validEntropySize(length int) error {
+	if length < 16 || length > 32 || length%4 != 0 {
+		return errors.New("gopenpgp: mnemonic entropy must be 16 to 32 bytes in steps of 4 bytes")
+	}
+	return nil
+}
+
+// readBits reads the 11-bit word index at the bit offset of data.
+func readBits(data []byte, offset int) int {
+	index := 0
+	for bit := offset; bit < offset+bitsPerWord; bit++ {
+		index = index<<1 | int(data[bit/8]>>(7-bit%8)&1)
+	}
+	return index
+}
+
+// writeBits writes the 11-bit word index at the bit offset of data.
+func writeBits(data []byte, offset, index int) {
+	for i := 0; i < bitsPerWord; i++ {
+		bit := offset + i
+		if index>>(bitsPerWord-1-i)&1 == 1 {
+			data[bit/8] |= 1 << (7 - bit%8)
+		}
+	}
+}
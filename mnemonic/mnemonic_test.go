@@ -0,0 +1,91 @@
+package mnemonic
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors of BIP39 with the passphrase "TREZOR".
+var mnemonicTestVectors = []struct {
+	entropy  string
+	mnemonic string
+	seed     string
+}{
+	{
+		entropy:  "00000000000000000000000000000000",
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		entropy:  "80808080808080808080808080808080",
+		mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		seed:     "d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+	},
+	{
+		entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		mnemonic: "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal will",
+		seed:     "f2b94508732bcbacbcc020faefecfc89feafa6649a5491b8c952cede496c214a0c7b3c392d168748f2d4a612bada0753b52a1c7ac53c1e93abd5c6320b9e95dd",
+	},
+	{
+		entropy:  "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+		seed:     "dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+	},
+}
+
+func TestMnemonicVectors(t *testing.T) {
+	for _, vector := range mnemonicTestVectors {
+		entropy, _ := hex.DecodeString(vector.entropy)
+		mnemonic, err := FromEntropy(entropy)
+		if err != nil {
+			t.Fatal("Expected no error while encoding mnemonic, got:", err)
+		}
+		assert.Exactly(t, vector.mnemonic, mnemonic)
+
+		decoded, err := ToEntropy(strings.ToUpper(mnemonic))
+		if err != nil {
+			t.Fatal("Expected no error while decoding mnemonic, got:", err)
+		}
+		assert.Exactly(t, vector.entropy, hex.EncodeToString(decoded))
+
+		seed, err := ToSeed(mnemonic, "TREZOR")
+		if err != nil {
+			t.Fatal("Expected no error while deriving seed, got:", err)
+		}
+		assert.Exactly(t, vector.seed, hex.EncodeToString(seed))
+	}
+}
+
+func TestMnemonicGenerate(t *testing.T) {
+	for _, bits := range []int{128, 160, 192, 224, 256} {
+		mnemonic, err := Generate(bits)
+		if err != nil {
+			t.Fatal("Expected no error while generating mnemonic, got:", err)
+		}
+		assert.Len(t, strings.Fields(mnemonic), bits*33/32/bitsPerWord)
+		assert.True(t, IsValid(mnemonic))
+	}
+	_, err := Generate(96)
+	assert.Error(t, err)
+	_, err = Generate(130)
+	assert.Error(t, err)
+}
+
+func TestMnemonicInvalid(t *testing.T) {
+	for _, mnemonic := range []string{
+		"",
+		// Checksum mismatch
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		// Unknown word
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abut",
+		// Invalid length
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+	} {
+		assert.False(t, IsValid(mnemonic), mnemonic)
+		_, err := ToSeed(mnemonic, "")
+		assert.Error(t, err, mnemonic)
+	}
+}
@@ -0,0 +1,98 @@
+package mobile
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+func TestResumableMobileStreams(t *testing.T) {
+	pgp := crypto.PGPWithProfile(profile.RFC9580())
+	key, err := pgp.KeyGeneration().AddUserId("mobile", "mobile@example.org").New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	encHandle, err := pgp.Encryption().Recipient(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating encryption handle, got:", err)
+	}
+	decHandle, err := pgp.Decryption().DecryptionKey(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating decryption handle, got:", err)
+	}
+	testData := bytes.Repeat([]byte("Hello World!"), 100000)
+
+	outBuf := &bytes.Buffer{}
+	writer, err := encHandle.ResumableEncryptingWriter(NewMobile2GoWriter(outBuf), []byte("test.bin"))
+	if err != nil {
+		t.Fatal("Expected no error while creating resumable writer, got:", err)
+	}
+	if _, err := writer.Write(testData[:500000]); err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	checkpoint, err := writer.Checkpoint()
+	if err != nil {
+		t.Fatal("Expected no error while saving checkpoint, got:", err)
+	}
+	serialized, err := checkpoint.Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing checkpoint, got:", err)
+	}
+
+	// Resume the interrupted encryption from the stored checkpoint
+	checkpoint, err = crypto.NewEncryptionCheckpoint(serialized)
+	if err != nil {
+		t.Fatal("Expected no error while parsing checkpoint, got:", err)
+	}
+	outBuf.Truncate(int(checkpoint.CiphertextOffset))
+	writer, err = crypto.ResumeEncryptingWriter(NewMobile2GoWriter(outBuf), checkpoint, []byte("test.bin"))
+	if err != nil {
+		t.Fatal("Expected no error while resuming writer, got:", err)
+	}
+	if _, err := writer.Write(testData[checkpoint.PlaintextOffset:]); err != nil {
+		t.Fatal("Expected no error while encrypting, got:", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal("Expected no error while closing resumable writer, got:", err)
+	}
+	ciphertext := outBuf.Bytes()
+
+	reader, err := decHandle.ResumableDecryptingReader(NewMobile2GoReader(&testMobileReader{bytes.NewReader(ciphertext), false}))
+	if err != nil {
+		t.Fatal("Expected no error while creating resumable reader, got:", err)
+	}
+	if _, err := io.ReadFull(reader, make([]byte, 700000)); err != nil {
+		t.Fatal("Expected no error while decrypting, got:", err)
+	}
+	serialized, err = reader.Checkpoint().Serialize()
+	if err != nil {
+		t.Fatal("Expected no error while serializing checkpoint, got:", err)
+	}
+
+	// Resume the interrupted decryption from the stored checkpoint
+	decCheckpoint, err := crypto.NewDecryptionCheckpoint(serialized)
+	if err != nil {
+		t.Fatal("Expected no error while parsing checkpoint, got:", err)
+	}
+	mobileReader := NewMobile2GoReader(&testMobileReader{bytes.NewReader(ciphertext[decCheckpoint.CiphertextOffset:]), false})
+	reader, err = crypto.ResumeDecryptingReader(mobileReader, decCheckpoint)
+	if err != nil {
+		t.Fatal("Expected no error while resuming reader, got:", err)
+	}
+	iosReader := NewGo2IOSReader(reader)
+	var readData []byte
+	for reachedEnd := false; !reachedEnd; {
+		res, err := iosReader.Read(4096)
+		if err != nil {
+			t.Fatal("Expected no error while reading, got:", err)
+		}
+		reachedEnd = res.IsEOF
+		readData = append(readData, res.Data[:res.N]...)
+	}
+	if expected := testData[decCheckpoint.PlaintextOffset:]; !bytes.Equal(expected, readData) {
+		t.Fatalf("expected %d bytes of resumed plaintext, got %d", len(expected), len(readData))
+	}
+}
@@ -0,0 +1,210 @@
+// Package openssh bridges OpenPGP authentication keys to OpenSSH.
+//
+// It exports authentication keys in the authorized_keys format, provides
+// golang.org/x/crypto/ssh signers for them and creates and verifies
+// signatures in the OpenSSH SSHSIG format, as produced by ssh-keygen -Y sign.
+package openssh
+
+import (
+	gocrypto "crypto"
+	stdecdsa "crypto/ecdsa"
+	stded25519 "crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/ecdsa"
+	"github.com/ProtonMail/go-crypto/openpgp/ed25519"
+	"github.com/ProtonMail/go-crypto/openpgp/eddsa"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+// authenticationKey is a valid OpenPGP key or subkey flagged for
+// authentication.
+type authenticationKey struct {
+	publicKey  *packet.PublicKey
+	privateKey *packet.PrivateKey
+	created    time.Time
+}
+
+// authenticationKeys returns the primary key and subkeys of the key that are
+// valid for authentication at unixTime.
+func authenticationKeys(key *crypto.Key, unixTime int64) ([]authenticationKey, error) {
+	entity := key.GetEntity()
+	now := time.Unix(unixTime, 0)
+	primarySelfSignature, err := entity.VerifyPrimaryKey(now, nil)
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: invalid primary key: %w", err)
+	}
+	var keys []authenticationKey
+	if primarySelfSignature.FlagsValid && primarySelfSignature.FlagAuthenticate {
+		keys = append(keys, authenticationKey{
+			publicKey:  entity.PrimaryKey,
+			privateKey: entity.PrivateKey,
+			created:    primarySelfSignature.CreationTime,
+		})
+	}
+	for i := range entity.Subkeys {
+		subkey := &entity.Subkeys[i]
+		binding, err := subkey.Verify(now, nil)
+		if err != nil || !binding.FlagsValid || !binding.FlagAuthenticate {
+			continue
+		}
+		keys = append(keys, authenticationKey{
+			publicKey:  subkey.PublicKey,
+			privateKey: subkey.PrivateKey,
+			created:    binding.CreationTime,
+		})
+	}
+	return keys, nil
+}
+
+// PublicKeys returns the authentication keys of the key that are valid at
+// unixTime as SSH public keys.
+func PublicKeys(key *crypto.Key, unixTime int64) ([]ssh.PublicKey, error) {
+	authKeys, err := authenticationKeys(key, unixTime)
+	if err != nil {
+		return nil, err
+	}
+	publicKeys := make([]ssh.PublicKey, 0, len(authKeys))
+	for _, authKey := range authKeys {
+		publicKey, err := sshPublicKey(authKey.publicKey)
+		if err != nil {
+			return nil, err
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+	return publicKeys, nil
+}
+
+// AuthorizedKeys exports the authentication keys of all keys in the key ring
+// that are valid at unixTime in the OpenSSH authorized_keys format.
+// As with gpg --export-ssh-key, each line is commented with the short key ID
+// of the OpenPGP key, e.g. "openpgp:0xAB4C8D48".
+// Keys without valid authentication key are skipped.
+func AuthorizedKeys(keyRing *crypto.KeyRing, unixTime int64) ([]byte, error) {
+	var authorizedKeys strings.Builder
+	for _, key := range keyRing.GetKeys() {
+		authKeys, err := authenticationKeys(key, unixTime)
+		if err != nil {
+			continue
+		}
+		for _, authKey := range authKeys {
+			publicKey, err := sshPublicKey(authKey.publicKey)
+			if err != nil {
+				return nil, err
+			}
+			line := strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(publicKey)), "\n")
+			fmt.Fprintf(&authorizedKeys, "%s openpgp:0x%08X\n", line, uint32(authKey.publicKey.KeyId))
+		}
+	}
+	return []byte(authorizedKeys.String()), nil
+}
+
+// NewSigner returns an SSH signer for the newest authentication key of the
+// unlocked private key that is valid at unixTime.
+func NewSigner(key *crypto.Key, unixTime int64) (ssh.Signer, error) {
+	if !key.IsPrivate() {
+		return nil, errors.New("gopenpgp: an SSH signer requires a private key")
+	}
+	authKeys, err := authenticationKeys(key, unixTime)
+	if err != nil {
+		return nil, err
+	}
+	var selected *authenticationKey
+	for i := range authKeys {
+		privateKey := authKeys[i].privateKey
+		if privateKey == nil || privateKey.Dummy() {
+			continue
+		}
+		if selected == nil || !authKeys[i].created.Before(selected.created) {
+			selected = &authKeys[i]
+		}
+	}
+	if selected == nil {
+		return nil, errors.New("gopenpgp: no valid private authentication key found")
+	}
+	if selected.privateKey.Encrypted {
+		return nil, errors.New("gopenpgp: authentication key is locked")
+	}
+	signer, err := cryptoSigner(selected.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewSignerFromSigner(signer)
+}
+
+// sshPublicKey converts an OpenPGP public key to an SSH public key.
+func sshPublicKey(publicKey *packet.PublicKey) (ssh.PublicKey, error) {
+	switch pub := publicKey.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return ssh.NewPublicKey(pub)
+	case *ecdsa.PublicKey:
+		curve, err := ellipticCurve(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		return ssh.NewPublicKey(&stdecdsa.PublicKey{Curve: curve, X: pub.X, Y: pub.Y})
+	case *eddsa.PublicKey:
+		if curve, err := publicKey.Curve(); err != nil || curve != packet.Curve25519 {
+			return nil, errors.New("gopenpgp: unsupported EdDSA curve for SSH")
+		}
+		return ssh.NewPublicKey(stded25519.PublicKey(pub.X))
+	case *ed25519.PublicKey:
+		return ssh.NewPublicKey(stded25519.PublicKey(pub.Point))
+	default:
+		return nil, fmt.Errorf("gopenpgp: public key algorithm %d is not supported by SSH", publicKey.PubKeyAlgo)
+	}
+}
+
+// cryptoSigner converts an unlocked OpenPGP private key to a signer of the
+// standard library.
+func cryptoSigner(privateKey *packet.PrivateKey) (gocrypto.Signer, error) {
+	switch priv := privateKey.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		return priv, nil
+	case *ecdsa.PrivateKey:
+		curve, err := ellipticCurve(&privateKey.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		return &stdecdsa.PrivateKey{
+			PublicKey: stdecdsa.PublicKey{Curve: curve, X: priv.X, Y: priv.Y},
+			D:         priv.D,
+		}, nil
+	case *eddsa.PrivateKey:
+		if curve, err := privateKey.Curve(); err != nil || curve != packet.Curve25519 {
+			return nil, errors.New("gopenpgp: unsupported EdDSA curve for SSH")
+		}
+		return stded25519.NewKeyFromSeed(priv.D), nil
+	case *ed25519.PrivateKey:
+		return stded25519.PrivateKey(priv.Key), nil
+	default:
+		return nil, fmt.Errorf("gopenpgp: public key algorithm %d is not supported by SSH", privateKey.PubKeyAlgo)
+	}
+}
+
+// ellipticCurve returns the NIST curve of an ECDSA key, the only curves
+// supported by SSH.
+func ellipticCurve(publicKey *packet.PublicKey) (elliptic.Curve, error) {
+	curve, err := publicKey.Curve()
+	if err != nil {
+		return nil, err
+	}
+	switch curve {
+	case packet.CurveNistP256:
+		return elliptic.P256(), nil
+	case packet.CurveNistP384:
+		return elliptic.P384(), nil
+	case packet.CurveNistP521:
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("gopenpgp: curve %s is not supported by SSH", curve)
+	}
+}
@@ -0,0 +1,148 @@
+package openssh
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+const testTime = 1717200000 // 2024-06-01T00:00:00+00:00
+
+// The keys in testdata were generated with GnuPG 2.2 and each have an
+// authentication subkey. The authorized keys were exported with
+// gpg --export-ssh-key and the signatures of testMessage were made with
+// ssh-keygen -Y sign -n file.
+var testKeyNames = []string{"erin", "frank", "grace"}
+
+const testMessage = "hello\n"
+
+func readTestFile(t *testing.T, name string) []byte {
+	data, err := os.ReadFile("testdata/" + name) //nolint
+	if err != nil {
+		t.Fatal("Expected no error while reading test file, got:", err)
+	}
+	return data
+}
+
+func readTestKey(t *testing.T, name string) *crypto.Key {
+	key, err := crypto.NewKeyFromArmored(string(readTestFile(t, name+"_private_key.asc")))
+	if err != nil {
+		t.Fatal("Expected no error while reading key, got:", err)
+	}
+	return key
+}
+
+func TestAuthorizedKeys(t *testing.T) {
+	for _, name := range testKeyNames {
+		keyRing, err := crypto.NewKeyRing(readTestKey(t, name))
+		if err != nil {
+			t.Fatal("Expected no error while building keyring, got:", err)
+		}
+		authorizedKeys, err := AuthorizedKeys(keyRing, testTime)
+		if err != nil {
+			t.Fatal("Expected no error while exporting authorized keys, got:", err)
+		}
+		assert.Exactly(t, string(readTestFile(t, name+"_authorized_keys")), string(authorizedKeys), name)
+	}
+}
+
+func TestAuthorizedKeysWithoutAuthenticationKey(t *testing.T) {
+	key, err := crypto.PGP().KeyGeneration().AddUserId("test", "test@example.org").GenerationTime(testTime).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	keyRing, err := crypto.NewKeyRing(key)
+	if err != nil {
+		t.Fatal("Expected no error while building keyring, got:", err)
+	}
+	authorizedKeys, err := AuthorizedKeys(keyRing, testTime)
+	if err != nil {
+		t.Fatal("Expected no error while exporting authorized keys, got:", err)
+	}
+	assert.Empty(t, authorizedKeys)
+	_, err = NewSigner(key, testTime)
+	assert.Error(t, err)
+}
+
+func TestVerifySSHKeygenSignature(t *testing.T) {
+	for _, name := range testKeyNames {
+		key := readTestKey(t, name)
+		signature := readTestFile(t, name+"_message.sig")
+		if err := Verify(key, "file", []byte(testMessage), signature, testTime); err != nil {
+			t.Fatal("Expected no error while verifying signature of "+name+", got:", err)
+		}
+		assert.Error(t, Verify(key, "git", []byte(testMessage), signature, testTime))
+		assert.Error(t, Verify(key, "file", []byte("tampered\n"), signature, testTime))
+	}
+	// Signature made by another key
+	assert.Error(t, Verify(readTestKey(t, "erin"), "file", []byte(testMessage), readTestFile(t, "grace_message.sig"), testTime))
+}
+
+func TestSignVerify(t *testing.T) {
+	for _, name := range testKeyNames {
+		key := readTestKey(t, name)
+		signature, err := Sign(key, "git", []byte("commit"), testTime)
+		if err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+		signer, err := VerifySignature("git", []byte("commit"), signature)
+		if err != nil {
+			t.Fatal("Expected no error while verifying signature, got:", err)
+		}
+		publicKeys, err := PublicKeys(key, testTime)
+		if err != nil {
+			t.Fatal("Expected no error while exporting public keys, got:", err)
+		}
+		if assert.Len(t, publicKeys, 1) {
+			assert.Exactly(t, publicKeys[0].Marshal(), signer.Marshal())
+		}
+		assert.NoError(t, Verify(key, "git", []byte("commit"), signature, testTime))
+	}
+}
+
+func TestGeneratedAuthenticationSubkey(t *testing.T) {
+	for _, algorithm := range []int{crypto.KeyGenerationCurve25519Legacy, crypto.KeyGenerationCurve25519} {
+		key, err := crypto.PGP().KeyGeneration().
+			AddUserId("test", "test@example.org").
+			GenerationTime(testTime).
+			OverrideProfileAlgorithm(algorithm).
+			AuthenticationSubkey().
+			New().
+			GenerateKey()
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		publicKeys, err := PublicKeys(key, testTime)
+		if err != nil {
+			t.Fatal("Expected no error while exporting public keys, got:", err)
+		}
+		if !assert.Len(t, publicKeys, 1) {
+			continue
+		}
+		assert.Exactly(t, "ssh-ed25519", publicKeys[0].Type())
+
+		signature, err := Sign(key, "file", []byte(testMessage), testTime)
+		if err != nil {
+			t.Fatal("Expected no error while signing, got:", err)
+		}
+		assert.NoError(t, Verify(key, "file", []byte(testMessage), signature, testTime))
+
+		// The authentication subkey is not used for OpenPGP signatures
+		signingKey, ok := key.GetEntity().SigningKey(key.GetEntity().PrimaryKey.CreationTime, nil)
+		assert.True(t, ok)
+		assert.Exactly(t, key.GetEntity().PrimaryKey.KeyId, signingKey.PublicKey.KeyId)
+	}
+}
+
+func TestSignLockedKey(t *testing.T) {
+	key := readTestKey(t, "erin")
+	locked, err := crypto.PGP().LockKey(key, []byte("password"))
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	_, err = Sign(locked, "file", []byte(testMessage), testTime)
+	assert.Error(t, err)
+}
@@ -0,0 +1,198 @@
+package openssh
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+// Constants of the SSHSIG format, see PROTOCOL.sshsig in the OpenSSH sources.
+const (
+	sshsigMagic       = "SSHSIG"
+	sshsigVersion     = 1
+	sshsigHashSHA256  = "sha256"
+	sshsigHashSHA512  = "sha512"
+	sshsigArmorHeader = "-----BEGIN SSH SIGNATURE-----"
+	sshsigArmorFooter = "-----END SSH SIGNATURE-----"
+	sshsigLineLength  = 70
+)
+
+// sshsigBlob is the wire format of an SSHSIG signature.
+type sshsigBlob struct {
+	Magic         [6]byte
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      []byte
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// sshsigSignedData is the data that is signed by an SSHSIG signature.
+type sshsigSignedData struct {
+	Magic         [6]byte
+	Namespace     string
+	Reserved      []byte
+	HashAlgorithm string
+	Hash          []byte
+}
+
+// Sign signs the message with the newest authentication key of the unlocked
+// private key that is valid at unixTime and returns an armored signature in
+// the SSHSIG format.
+// The namespace prevents the use of the signature in another context, e.g.
+// "git" for git commits or "file" for ssh-keygen -Y sign.
+func Sign(key *crypto.Key, namespace string, message []byte, unixTime int64) ([]byte, error) {
+	signer, err := NewSigner(key, unixTime)
+	if err != nil {
+		return nil, err
+	}
+	return SignWithSigner(signer, namespace, message)
+}
+
+// SignWithSigner signs the message with an SSH signer and returns an armored
+// signature in the SSHSIG format.
+func SignWithSigner(signer ssh.Signer, namespace string, message []byte) ([]byte, error) {
+	if namespace == "" {
+		return nil, errors.New("gopenpgp: SSHSIG requires a namespace")
+	}
+	digest := sha512.Sum512(message)
+	signedData := sshsigSignedData{
+		Namespace:     namespace,
+		HashAlgorithm: sshsigHashSHA512,
+		Hash:          digest[:],
+	}
+	copy(signedData.Magic[:], sshsigMagic)
+
+	var signature *ssh.Signature
+	var err error
+	if algorithmSigner, ok := signer.(ssh.AlgorithmSigner); ok && signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// RSA signatures have to use SHA-2, as ssh-keygen does
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, ssh.Marshal(signedData), ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = signer.Sign(rand.Reader, ssh.Marshal(signedData))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in signing SSHSIG: %w", err)
+	}
+
+	blob := sshsigBlob{
+		Version:       sshsigVersion,
+		PublicKey:     signer.PublicKey().Marshal(),
+		Namespace:     namespace,
+		HashAlgorithm: sshsigHashSHA512,
+		Signature:     ssh.Marshal(signature),
+	}
+	copy(blob.Magic[:], sshsigMagic)
+	return armorSignature(ssh.Marshal(blob)), nil
+}
+
+// Verify verifies an armored SSHSIG signature of the message in the given
+// namespace.
+// The signature has to be made by an authentication key of the key that is
+// valid at unixTime.
+func Verify(key *crypto.Key, namespace string, message, signature []byte, unixTime int64) error {
+	publicKeys, err := PublicKeys(key, unixTime)
+	if err != nil {
+		return err
+	}
+	signer, err := VerifySignature(namespace, message, signature)
+	if err != nil {
+		return err
+	}
+	for _, publicKey := range publicKeys {
+		if bytes.Equal(publicKey.Marshal(), signer.Marshal()) {
+			return nil
+		}
+	}
+	return errors.New("gopenpgp: SSHSIG is not made by an authentication key of the key")
+}
+
+// VerifySignature verifies an armored SSHSIG signature of the message in
+// the given namespace and returns the public key that made the signature.
+// The caller has to check that the returned public key is trusted.
+func VerifySignature(namespace string, message, signature []byte) (ssh.PublicKey, error) {
+	data, err := unarmorSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	var blob sshsigBlob
+	if err := ssh.Unmarshal(data, &blob); err != nil {
+		return nil, fmt.Errorf("gopenpgp: invalid SSHSIG: %w", err)
+	}
+	if string(blob.Magic[:]) != sshsigMagic || blob.Version != sshsigVersion {
+		return nil, errors.New("gopenpgp: invalid SSHSIG header")
+	}
+	if blob.Namespace != namespace {
+		return nil, fmt.Errorf("gopenpgp: SSHSIG namespace %q does not match %q", blob.Namespace, namespace)
+	}
+	publicKey, err := ssh.ParsePublicKey(blob.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: invalid public key in SSHSIG: %w", err)
+	}
+	var sig ssh.Signature
+	if err := ssh.Unmarshal(blob.Signature, &sig); err != nil {
+		return nil, fmt.Errorf("gopenpgp: invalid SSHSIG: %w", err)
+	}
+	if sig.Format == ssh.KeyAlgoRSA {
+		return nil, errors.New("gopenpgp: SSHSIG with SHA-1 RSA signature is not allowed")
+	}
+
+	var h hash.Hash
+	switch blob.HashAlgorithm {
+	case sshsigHashSHA256:
+		h = sha256.New()
+	case sshsigHashSHA512:
+		h = sha512.New()
+	default:
+		return nil, fmt.Errorf("gopenpgp: unsupported SSHSIG hash algorithm %q", blob.HashAlgorithm)
+	}
+	h.Write(message)
+	signedData := sshsigSignedData{
+		Namespace:     blob.Namespace,
+		Reserved:      blob.Reserved,
+		HashAlgorithm: blob.HashAlgorithm,
+		Hash:          h.Sum(nil),
+	}
+	copy(signedData.Magic[:], sshsigMagic)
+	if err := publicKey.Verify(ssh.Marshal(signedData), &sig); err != nil {
+		return nil, fmt.Errorf("gopenpgp: invalid SSHSIG: %w", err)
+	}
+	return publicKey, nil
+}
+
+func armorSignature(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var armored strings.Builder
+	armored.WriteString(sshsigArmorHeader + "\n")
+	for len(encoded) > sshsigLineLength {
+		armored.WriteString(encoded[:sshsigLineLength] + "\n")
+		encoded = encoded[sshsigLineLength:]
+	}
+	armored.WriteString(encoded + "\n")
+	armored.WriteString(sshsigArmorFooter + "\n")
+	return []byte(armored.String())
+}
+
+func unarmorSignature(armored []byte) ([]byte, error) {
+	text := strings.TrimSpace(string(armored))
+	if !strings.HasPrefix(text, sshsigArmorHeader) || !strings.HasSuffix(text, sshsigArmorFooter) {
+		return nil, errors.New("gopenpgp: SSHSIG armor not found")
+	}
+	text = strings.TrimSuffix(strings.TrimPrefix(text, sshsigArmorHeader), sshsigArmorFooter)
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: invalid SSHSIG armor: %w", err)
+	}
+	return data, nil
+}
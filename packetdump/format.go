@@ -0,0 +1,94 @@
+package packetdump
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Print writes the packets in a human-readable text format, with nested
+// packets indented.
+func Print(w io.Writer, packets []*Packet) error {
+	for _, p := range packets {
+		indent := strings.Repeat("  ", p.Depth)
+		if _, err := fmt.Fprintf(w, "%s:%s packet: tag %d, length %d\n", indent, p.Type, p.Tag, p.Length); err != nil {
+			return err
+		}
+		for _, field := range p.fields() {
+			if _, err := fmt.Fprintf(w, "%s\t%s: %s\n", indent, field[0], field[1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fields returns the names and values of the fields that are set.
+func (p *Packet) fields() [][2]string {
+	var fields [][2]string
+	add := func(name, value string) {
+		if value != "" {
+			fields = append(fields, [2]string{name, value})
+		}
+	}
+	addInt := func(name string, value int) {
+		if value != 0 {
+			add(name, fmt.Sprint(value))
+		}
+	}
+	addInt("version", p.Version)
+	add("algorithm", p.Algorithm)
+	addInt("bit length", p.BitLength)
+	add("key id", p.KeyID)
+	add("fingerprint", p.Fingerprint)
+	add("created", p.Created)
+	add("signature type", p.SignatureType)
+	add("hash algorithm", p.HashAlgorithm)
+	add("salt", p.Salt)
+	for _, subpacket := range p.HashedSubpackets {
+		add(subpacket.label("hashed"), subpacket.Value)
+	}
+	for _, subpacket := range p.UnhashedSubpackets {
+		add(subpacket.label("unhashed"), subpacket.Value)
+	}
+	if p.Tag == 4 {
+		add("last", fmt.Sprint(p.Last))
+	}
+	add("protection", p.Protection)
+	add("cipher", p.Cipher)
+	add("aead mode", p.AEADMode)
+	addInt("chunk size", p.ChunkSize)
+	if p.S2K != nil {
+		add("s2k mode", p.S2K.Mode)
+		add("s2k hash", p.S2K.Hash)
+		add("s2k salt", p.S2K.Salt)
+		addInt("s2k count", p.S2K.Count)
+		addInt("s2k passes", p.S2K.Passes)
+		addInt("s2k parallelism", p.S2K.Parallelism)
+		addInt("s2k memory exponent", p.S2K.MemoryExponent)
+		add("s2k gnu extension", p.S2K.GNU)
+	}
+	if p.Decrypted {
+		add("decrypted", "true")
+	}
+	add("compression", p.Compression)
+	add("format", p.Format)
+	add("file name", p.FileName)
+	add("modification time", p.ModificationTime)
+	if p.Tag == 11 {
+		add("data length", fmt.Sprint(p.DataLength))
+	}
+	if p.UserID != "" {
+		add("user id", fmt.Sprintf("%q", p.UserID))
+	}
+	add("digest", p.Digest)
+	add("error", p.Error)
+	return fields
+}
+
+func (subpacket *Subpacket) label(area string) string {
+	if subpacket.Critical {
+		area = "critical " + area
+	}
+	return fmt.Sprintf("%s subpacket %s (%d)", area, subpacket.Name, subpacket.Type)
+}
@@ -0,0 +1,170 @@
+package packetdump
+
+import "fmt"
+
+var packetTypes = map[uint8]string{
+	1:  "public-key encrypted session key",
+	2:  "signature",
+	3:  "symmetric-key encrypted session key",
+	4:  "one-pass signature",
+	5:  "secret key",
+	6:  "public key",
+	7:  "secret subkey",
+	8:  "compressed data",
+	9:  "symmetrically encrypted data",
+	10: "marker",
+	11: "literal data",
+	12: "trust",
+	13: "user id",
+	14: "public subkey",
+	17: "user attribute",
+	18: "symmetrically encrypted and integrity protected data",
+	19: "modification detection code",
+	20: "aead encrypted data",
+	21: "padding",
+}
+
+var publicKeyAlgorithms = map[uint8]string{
+	1:   "RSA",
+	2:   "RSA encrypt-only",
+	3:   "RSA sign-only",
+	16:  "ElGamal",
+	17:  "DSA",
+	18:  "ECDH",
+	19:  "ECDSA",
+	22:  "EdDSA",
+	25:  "X25519",
+	26:  "X448",
+	27:  "Ed25519",
+	28:  "Ed448",
+	30:  "ML-DSA-65+Ed25519",
+	31:  "ML-DSA-87+Ed448",
+	35:  "ML-KEM-768+X25519",
+	36:  "ML-KEM-1024+X448",
+	100: "AEAD (experimental)",
+	101: "HMAC (experimental)",
+	128: "AEAD",
+	129: "HMAC",
+}
+
+var ciphers = map[uint8]string{
+	0:  "plaintext",
+	1:  "IDEA",
+	2:  "3DES",
+	3:  "CAST5",
+	4:  "Blowfish",
+	7:  "AES128",
+	8:  "AES192",
+	9:  "AES256",
+	10: "Twofish",
+	11: "Camellia128",
+	12: "Camellia192",
+	13: "Camellia256",
+}
+
+var aeadModes = map[uint8]string{
+	1: "EAX",
+	2: "OCB",
+	3: "GCM",
+}
+
+var hashes = map[uint8]string{
+	1:  "MD5",
+	2:  "SHA1",
+	3:  "RIPEMD160",
+	8:  "SHA256",
+	9:  "SHA384",
+	10: "SHA512",
+	11: "SHA224",
+	12: "SHA3-256",
+	14: "SHA3-512",
+}
+
+var compressionAlgorithms = map[uint8]string{
+	0: "uncompressed",
+	1: "ZIP",
+	2: "ZLIB",
+	3: "BZip2",
+}
+
+var s2kModes = map[uint8]string{
+	0:   "simple",
+	1:   "salted",
+	3:   "iterated and salted",
+	4:   "argon2",
+	101: "GNU extension",
+}
+
+var s2kUsages = map[uint8]string{
+	0:   "unprotected",
+	253: "AEAD",
+	254: "CFB with SHA1 checksum",
+	255: "CFB with checksum",
+}
+
+var signatureTypes = map[uint8]string{
+	0x00: "binary document",
+	0x01: "text document",
+	0x02: "standalone",
+	0x10: "generic certification",
+	0x11: "persona certification",
+	0x12: "casual certification",
+	0x13: "positive certification",
+	0x18: "subkey binding",
+	0x19: "primary key binding",
+	0x1f: "direct key",
+	0x20: "key revocation",
+	0x28: "subkey revocation",
+	0x30: "certification revocation",
+	0x40: "timestamp",
+	0x50: "third-party confirmation",
+}
+
+var subpacketTypes = map[uint8]string{
+	2:  "signature creation time",
+	3:  "signature expiration time",
+	4:  "exportable certification",
+	5:  "trust signature",
+	6:  "regular expression",
+	7:  "revocable",
+	9:  "key expiration time",
+	11: "preferred symmetric ciphers",
+	12: "revocation key",
+	16: "issuer key id",
+	20: "notation data",
+	21: "preferred hash algorithms",
+	22: "preferred compression algorithms",
+	23: "key server preferences",
+	24: "preferred key server",
+	25: "primary user id",
+	26: "policy uri",
+	27: "key flags",
+	28: "signer's user id",
+	29: "reason for revocation",
+	30: "features",
+	31: "signature target",
+	32: "embedded signature",
+	33: "issuer fingerprint",
+	34: "preferred aead algorithms",
+	35: "intended recipient fingerprint",
+	37: "attested certifications",
+	38: "key block",
+	39: "preferred aead ciphersuites",
+}
+
+// typeName returns the name of the packet or subpacket type.
+func typeName(names map[uint8]string, id uint8) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// name returns the name of the identifier with the identifier in
+// parentheses, e.g. "AES256 (9)".
+func name(names map[uint8]string, id uint8) string {
+	if name, ok := names[id]; ok {
+		return fmt.Sprintf("%s (%d)", name, id)
+	}
+	return fmt.Sprintf("unknown (%d)", id)
+}
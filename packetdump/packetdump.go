@@ -0,0 +1,427 @@
+// Package packetdump lists the packets of OpenPGP keys, messages and
+// signatures for debugging, similar to gpg --list-packets.
+//
+// No key is required to list the packets. Encrypted data is decrypted and
+// its packets are listed as well, if the session key is provided.
+package packetdump
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+
+	gopenpgparmor "github.com/lovoo/gopenpgp/v3/armor"
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+// maxDepth limits the nesting of compressed and encrypted data.
+const maxDepth = 8
+
+// Packet describes an OpenPGP packet. Fields that do not apply to the packet
+// type are empty.
+type Packet struct {
+	Tag    uint8  `json:"tag"`
+	Type   string `json:"type"`
+	Length int    `json:"length"`
+	// Depth is the nesting level of the packet in compressed or encrypted
+	// data, starting at zero.
+	Depth   int `json:"depth"`
+	Version int `json:"version,omitempty"`
+
+	// Keys, signatures and encrypted session keys
+	Algorithm   string `json:"algorithm,omitempty"`
+	BitLength   int    `json:"bitLength,omitempty"`
+	KeyID       string `json:"keyId,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Created     string `json:"created,omitempty"`
+
+	// Signatures and one-pass signatures
+	SignatureType      string      `json:"signatureType,omitempty"`
+	HashAlgorithm      string      `json:"hashAlgorithm,omitempty"`
+	Salt               string      `json:"salt,omitempty"`
+	HashedSubpackets   []Subpacket `json:"hashedSubpackets,omitempty"`
+	UnhashedSubpackets []Subpacket `json:"unhashedSubpackets,omitempty"`
+	// Last is set for one-pass signatures that are not followed by another
+	// one-pass signature of the same data.
+	Last bool `json:"last,omitempty"`
+
+	// Secret keys, encrypted session keys and encrypted data
+	Protection string `json:"protection,omitempty"`
+	Cipher     string `json:"cipher,omitempty"`
+	AEADMode   string `json:"aeadMode,omitempty"`
+	ChunkSize  int    `json:"chunkSize,omitempty"`
+	S2K        *S2K   `json:"s2k,omitempty"`
+	// Decrypted is set if the encrypted data is decrypted with the session
+	// key and its packets follow.
+	Decrypted bool `json:"decrypted,omitempty"`
+
+	// Compressed and literal data
+	Compression      string `json:"compression,omitempty"`
+	Format           string `json:"format,omitempty"`
+	FileName         string `json:"fileName,omitempty"`
+	ModificationTime string `json:"modificationTime,omitempty"`
+	DataLength       int    `json:"dataLength,omitempty"`
+
+	UserID string `json:"userId,omitempty"`
+	// Digest is the SHA-1 digest of modification detection code packets.
+	Digest string `json:"digest,omitempty"`
+	// Error is set if the packet cannot be parsed, decrypted or decompressed.
+	Error string `json:"error,omitempty"`
+}
+
+// S2K describes the string-to-key function of a locked secret key or a
+// symmetric-key encrypted session key.
+type S2K struct {
+	Mode  string `json:"mode"`
+	Hash  string `json:"hash,omitempty"`
+	Salt  string `json:"salt,omitempty"`
+	Count int    `json:"count,omitempty"`
+	// Argon2 parameters
+	Passes         int `json:"passes,omitempty"`
+	Parallelism    int `json:"parallelism,omitempty"`
+	MemoryExponent int `json:"memoryExponent,omitempty"`
+	// GNU is the GNU extension, e.g. "gnu-dummy" for stripped keys.
+	GNU string `json:"gnu,omitempty"`
+}
+
+// Dump lists the packets of the armored or binary OpenPGP input.
+// If the session key is not nil, encrypted data is decrypted and its packets
+// are listed as well.
+// On errors, the packets up to the error are returned with the error.
+func Dump(input io.Reader, sessionKey *crypto.SessionKey) ([]*Packet, error) {
+	input, armored := gopenpgparmor.IsPGPArmored(input)
+	if armored {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in reading input: %w", err)
+		}
+		if block, _ := clearsign.Decode(data); block != nil {
+			input = block.ArmoredSignature.Body
+		} else {
+			block, err := armor.Decode(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("gopenpgp: unable to unarmor: %w", err)
+			}
+			input = block.Body
+		}
+	}
+	dumper := &dumper{sessionKey: sessionKey}
+	err := dumper.dump(input, 0)
+	return dumper.packets, err
+}
+
+type dumper struct {
+	sessionKey *crypto.SessionKey
+	packets    []*Packet
+}
+
+func (d *dumper) dump(input io.Reader, depth int) error {
+	reader := packet.NewOpaqueReader(bufio.NewReader(input))
+	for {
+		op, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("gopenpgp: error in reading packet: %w", err)
+		}
+		p := &Packet{
+			Tag:    op.Tag,
+			Type:   typeName(packetTypes, op.Tag),
+			Length: len(op.Contents),
+			Depth:  depth,
+		}
+		d.packets = append(d.packets, p)
+		if err := p.parse(op); err != nil {
+			p.Error = err.Error()
+			continue
+		}
+		if nested := d.nestedPackets(p, op); nested != nil && depth < maxDepth {
+			err = d.dump(nested, depth+1)
+			if closer, ok := nested.(io.Closer); ok {
+				// Closing checks the integrity of decrypted data
+				if closeErr := closer.Close(); err == nil {
+					err = closeErr
+				}
+			}
+			if err != nil {
+				p.Error = err.Error()
+			}
+		}
+	}
+}
+
+// nestedPackets returns the reader of the packets in compressed data and in
+// encrypted data, if it can be decrypted with the session key.
+func (d *dumper) nestedPackets(p *Packet, op *packet.OpaquePacket) io.Reader {
+	switch op.Tag {
+	case 8, 9, 18, 20:
+	default:
+		return nil
+	}
+	if op.Tag != 8 && d.sessionKey == nil {
+		return nil
+	}
+	parsed, err := op.Parse()
+	if err != nil {
+		p.Error = err.Error()
+		return nil
+	}
+	var plaintext io.ReadCloser
+	switch parsed := parsed.(type) {
+	case *packet.Compressed:
+		return parsed.Body
+	case *packet.SymmetricallyEncrypted:
+		cipher := parsed.Cipher
+		if parsed.Version == 1 {
+			if cipher, err = d.sessionKey.GetCipherFunc(); err != nil {
+				break
+			}
+		}
+		plaintext, err = parsed.Decrypt(cipher, d.sessionKey.Key)
+	case *packet.AEADEncrypted:
+		// The cipher is the first octet after the version
+		plaintext, err = parsed.Decrypt(packet.CipherFunction(op.Contents[1]), d.sessionKey.Key)
+	}
+	if err != nil {
+		p.Error = fmt.Sprintf("gopenpgp: unable to decrypt with session key: %v", err)
+		return nil
+	}
+	p.Decrypted = plaintext != nil
+	return plaintext
+}
+
+func (p *Packet) parse(op *packet.OpaquePacket) error {
+	r := &bodyReader{data: op.Contents}
+	switch op.Tag {
+	case 1:
+		p.parseEncryptedKey(r)
+	case 2:
+		if err := p.parseSignature(r); err != nil {
+			return err
+		}
+	case 3:
+		p.parseSymmetricKeyEncryptedKey(r)
+	case 4:
+		p.parseOnePassSignature(r)
+	case 5, 6, 7, 14:
+		return p.parseKey(op)
+	case 8:
+		p.Compression = name(compressionAlgorithms, r.byte())
+	case 11:
+		format := r.byte()
+		p.Format = string(rune(format))
+		p.FileName = printable(r.bytes(int(r.byte())))
+		p.ModificationTime = r.time()
+		p.DataLength = len(r.rest())
+	case 13:
+		p.UserID = string(op.Contents)
+	case 18:
+		if p.Version = int(r.byte()); p.Version == 2 {
+			p.parseAEADParameters(r)
+			p.Salt = r.hex(32)
+		}
+	case 19:
+		p.Digest = r.hex(len(r.data))
+	case 20:
+		p.Version = int(r.byte())
+		p.parseAEADParameters(r)
+	}
+	return r.err
+}
+
+func (p *Packet) parseAEADParameters(r *bodyReader) {
+	p.Cipher = name(ciphers, r.byte())
+	p.AEADMode = name(aeadModes, r.byte())
+	p.ChunkSize = 1 << (r.byte() + 6)
+}
+
+func (p *Packet) parseEncryptedKey(r *bodyReader) {
+	p.Version = int(r.byte())
+	switch p.Version {
+	case 3:
+		p.KeyID = r.hex(8)
+	case 6:
+		if length := int(r.byte()); length > 0 {
+			r.byte() // key version
+			p.Fingerprint = r.hex(length - 1)
+		}
+	}
+	p.Algorithm = name(publicKeyAlgorithms, r.byte())
+}
+
+func (p *Packet) parseSymmetricKeyEncryptedKey(r *bodyReader) {
+	p.Version = int(r.byte())
+	if p.Version == 6 {
+		r.byte() // length of the following fields
+	}
+	p.Cipher = name(ciphers, r.byte())
+	if p.Version >= 5 {
+		p.AEADMode = name(aeadModes, r.byte())
+	}
+	if p.Version == 6 {
+		r.byte() // length of the S2K specifier
+	}
+	p.S2K = parseS2K(r)
+}
+
+func (p *Packet) parseOnePassSignature(r *bodyReader) {
+	p.Version = int(r.byte())
+	p.SignatureType = name(signatureTypes, r.byte())
+	p.HashAlgorithm = name(hashes, r.byte())
+	p.Algorithm = name(publicKeyAlgorithms, r.byte())
+	if p.Version == 6 {
+		p.Salt = r.hex(int(r.byte()))
+		p.Fingerprint = r.hex(32)
+	} else {
+		p.KeyID = r.hex(8)
+	}
+	p.Last = r.byte() != 0
+}
+
+func (p *Packet) parseSignature(r *bodyReader) error {
+	p.Version = int(r.byte())
+	switch p.Version {
+	case 3:
+		r.byte() // length of the hashed material
+		p.SignatureType = name(signatureTypes, r.byte())
+		p.Created = r.time()
+		p.KeyID = r.hex(8)
+		p.Algorithm = name(publicKeyAlgorithms, r.byte())
+		p.HashAlgorithm = name(hashes, r.byte())
+	case 4, 6:
+		p.SignatureType = name(signatureTypes, r.byte())
+		p.Algorithm = name(publicKeyAlgorithms, r.byte())
+		p.HashAlgorithm = name(hashes, r.byte())
+		lengthSize := 2
+		if p.Version == 6 {
+			lengthSize = 4
+		}
+		var err error
+		if p.HashedSubpackets, err = parseSubpackets(r.bytes(r.uint(lengthSize))); err != nil {
+			return err
+		}
+		if p.UnhashedSubpackets, err = parseSubpackets(r.bytes(r.uint(lengthSize))); err != nil {
+			return err
+		}
+		r.bytes(2) // left 16 bits of the hash
+		if p.Version == 6 {
+			p.Salt = r.hex(int(r.byte()))
+		}
+	default:
+		return fmt.Errorf("gopenpgp: unsupported signature version %d", p.Version)
+	}
+	return nil
+}
+
+func (p *Packet) parseKey(op *packet.OpaquePacket) error {
+	parsed, err := op.Parse()
+	if err != nil {
+		return err
+	}
+	var publicKey *packet.PublicKey
+	switch parsed := parsed.(type) {
+	case *packet.PublicKey:
+		publicKey = parsed
+	case *packet.PrivateKey:
+		publicKey = &parsed.PublicKey
+	default:
+		return errors.New("gopenpgp: unexpected key packet")
+	}
+	p.Version = publicKey.Version
+	p.Algorithm = name(publicKeyAlgorithms, uint8(publicKey.PubKeyAlgo))
+	if bitLength, err := publicKey.BitLength(); err == nil {
+		p.BitLength = int(bitLength)
+	}
+	p.KeyID = fmt.Sprintf("%016X", publicKey.KeyId)
+	p.Fingerprint = strings.ToUpper(fmt.Sprintf("%x", publicKey.Fingerprint))
+	p.Created = formatTime(uint32(publicKey.CreationTime.Unix()))
+	if op.Tag == 5 || op.Tag == 7 {
+		var hashed bytes.Buffer
+		if err := publicKey.SerializeForHash(&hashed); err != nil {
+			return err
+		}
+		// The hashed public key is prefixed with 0x99 and a 2-byte length or
+		// 0x9b and a 4-byte length
+		prefixLength := 3
+		if publicKey.Version == 6 {
+			prefixLength = 5
+		}
+		r := &bodyReader{data: op.Contents[hashed.Len()-prefixLength:]}
+		p.parseProtection(r)
+		return r.err
+	}
+	return nil
+}
+
+// parseProtection parses the protection of the secret key material, which
+// follows the public key.
+func (p *Packet) parseProtection(r *bodyReader) {
+	usage := r.byte()
+	p.Protection = name(s2kUsages, usage)
+	switch usage {
+	case 0:
+		return
+	case 253, 254, 255:
+	default:
+		// Legacy protection with the cipher as S2K usage
+		p.Protection = fmt.Sprintf("legacy (%d)", usage)
+		p.Cipher = name(ciphers, usage)
+		return
+	}
+	if p.Version == 6 {
+		r.byte() // length of the following fields
+	}
+	p.Cipher = name(ciphers, r.byte())
+	if usage == 253 {
+		p.AEADMode = name(aeadModes, r.byte())
+	}
+	if p.Version == 6 {
+		r.byte() // length of the S2K specifier
+	}
+	p.S2K = parseS2K(r)
+}
+
+func parseS2K(r *bodyReader) *S2K {
+	mode := r.byte()
+	s2k := &S2K{Mode: name(s2kModes, mode)}
+	switch mode {
+	case 0:
+		s2k.Hash = name(hashes, r.byte())
+	case 1:
+		s2k.Hash = name(hashes, r.byte())
+		s2k.Salt = r.hex(8)
+	case 3:
+		s2k.Hash = name(hashes, r.byte())
+		s2k.Salt = r.hex(8)
+		count := int(r.byte())
+		s2k.Count = (16 + count&15) << (count>>4 + 6)
+	case 4:
+		s2k.Salt = r.hex(16)
+		s2k.Passes = int(r.byte())
+		s2k.Parallelism = int(r.byte())
+		s2k.MemoryExponent = int(r.byte())
+	case 101:
+		s2k.Hash = name(hashes, r.byte())
+		if string(r.bytes(3)) != "GNU" {
+			s2k.GNU = "unknown"
+			break
+		}
+		switch extension := r.byte(); extension {
+		case 1:
+			s2k.GNU = "gnu-dummy"
+		case 2:
+			s2k.GNU = "gnu-divert-to-card"
+		default:
+			s2k.GNU = fmt.Sprintf("unknown (%d)", extension)
+		}
+	}
+	return s2k
+}
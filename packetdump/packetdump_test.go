@@ -0,0 +1,197 @@
+package packetdump
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/constants"
+	"github.com/lovoo/gopenpgp/v3/crypto"
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+const testTime = 1700000000
+
+var testMessage = []byte("packet dump test message")
+
+func generateKey(t *testing.T, pgp *crypto.PGPHandle) *crypto.Key {
+	key, err := pgp.KeyGeneration().AddUserId("packetdump", "packetdump@example.com").GenerationTime(testTime).New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	return key
+}
+
+func typesOf(packets []*Packet) []string {
+	types := make([]string, len(packets))
+	for i, p := range packets {
+		types[i] = strings.Repeat(">", p.Depth) + p.Type
+	}
+	return types
+}
+
+func issuerFingerprints(signature *Packet) []string {
+	var fingerprints []string
+	for _, subpacket := range signature.HashedSubpackets {
+		if subpacket.Type == 33 {
+			_, fingerprint, _ := strings.Cut(subpacket.Value, " ")
+			fingerprints = append(fingerprints, fingerprint)
+		}
+	}
+	return fingerprints
+}
+
+func TestDumpLockedKey(t *testing.T) {
+	pgp := crypto.PGPWithProfile(profile.RFC9580())
+	key, err := pgp.LockKey(generateKey(t, pgp), []byte("passphrase"))
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	armored, err := key.Armor()
+	if err != nil {
+		t.Fatal("Expected no error while armoring key, got:", err)
+	}
+	packets, err := Dump(strings.NewReader(armored), nil)
+	if err != nil {
+		t.Fatal("Expected no error while dumping key, got:", err)
+	}
+	assert.Exactly(t, []string{"secret key", "signature", "user id", "signature", "secret subkey", "signature"}, typesOf(packets))
+
+	primary := packets[0]
+	assert.Exactly(t, 6, primary.Version)
+	assert.Exactly(t, "Ed25519 (27)", primary.Algorithm)
+	assert.Exactly(t, strings.ToUpper(key.GetFingerprint()), primary.Fingerprint)
+	assert.Exactly(t, "2023-11-14T22:13:20Z", primary.Created)
+	assert.Exactly(t, "AEAD (253)", primary.Protection)
+	assert.Exactly(t, "AES128 (7)", primary.Cipher)
+	assert.Exactly(t, "OCB (2)", primary.AEADMode)
+	if assert.NotNil(t, primary.S2K) {
+		assert.Exactly(t, "argon2 (4)", primary.S2K.Mode)
+		assert.Len(t, primary.S2K.Salt, 32)
+	}
+
+	directKeySignature := packets[1]
+	assert.Exactly(t, "direct key (31)", directKeySignature.SignatureType)
+	assert.Len(t, directKeySignature.Salt, 64)
+	assert.Exactly(t, []string{primary.Fingerprint}, issuerFingerprints(directKeySignature))
+	assert.Exactly(t, "packetdump <packetdump@example.com>", packets[2].UserID)
+}
+
+func TestDumpEncryptedMessage(t *testing.T) {
+	for name, test := range map[string]struct {
+		pgp        *crypto.PGPHandle
+		sessionKey *crypto.SessionKey
+		types      []string
+	}{
+		"SEIPDv1": {
+			pgp:        crypto.PGPWithProfile(profile.RFC4880()),
+			sessionKey: crypto.NewSessionKeyFromToken(bytes.Repeat([]byte{1}, 32), constants.AES256),
+			types: []string{
+				"public-key encrypted session key", "symmetric-key encrypted session key",
+				"symmetrically encrypted and integrity protected data",
+				">compressed data", ">>one-pass signature", ">>literal data", ">>signature",
+			},
+		},
+		"SEIPDv2": {
+			pgp:        crypto.PGPWithProfile(profile.RFC9580()),
+			sessionKey: crypto.NewSessionKeyFromTokenWithAead(bytes.Repeat([]byte{1}, 32), constants.AES256, true),
+			types: []string{
+				"public-key encrypted session key", "symmetric-key encrypted session key",
+				"symmetrically encrypted and integrity protected data",
+				">compressed data", ">>one-pass signature", ">>literal data", ">>signature",
+			},
+		},
+	} {
+		key := generateKey(t, test.pgp)
+		encHandle, err := test.pgp.Encryption().
+			Recipient(key).
+			Password([]byte("password")).
+			SigningKey(key).
+			SessionKey(test.sessionKey).
+			Compress().
+			New()
+		if err != nil {
+			t.Fatal("Expected no error while creating encryption handle, got:", err)
+		}
+		message, err := encHandle.Encrypt(testMessage)
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+
+		packets, err := Dump(bytes.NewReader(message.Bytes()), nil)
+		if err != nil {
+			t.Fatal("Expected no error while dumping message, got:", err)
+		}
+		assert.Exactly(t, test.types[:3], typesOf(packets), name)
+		assert.False(t, packets[2].Decrypted, name)
+
+		packets, err = Dump(bytes.NewReader(message.Bytes()), test.sessionKey)
+		if err != nil {
+			t.Fatal("Expected no error while dumping message, got:", err)
+		}
+		assert.Exactly(t, test.types, typesOf(packets), name)
+		assert.True(t, packets[2].Decrypted, name)
+		assert.Empty(t, packets[2].Error, name)
+		assert.Exactly(t, len(testMessage), packets[5].DataLength, name)
+		assert.Exactly(t, "b", packets[5].Format, name)
+		assert.Contains(t, issuerFingerprints(packets[6]), strings.ToUpper(key.GetFingerprint()), name)
+
+		encrypted := packets[2]
+		if encrypted.Version == 2 {
+			assert.Exactly(t, "AES256 (9)", encrypted.Cipher, name)
+			assert.NotEmpty(t, encrypted.AEADMode, name)
+			assert.NotZero(t, encrypted.ChunkSize, name)
+		}
+		if assert.NotNil(t, packets[1].S2K, name) {
+			assert.NotEmpty(t, packets[1].S2K.Salt, name)
+		}
+
+		wrongSessionKey := *test.sessionKey
+		wrongSessionKey.Key = bytes.Repeat([]byte{2}, 32)
+		packets, _ = Dump(bytes.NewReader(message.Bytes()), &wrongSessionKey)
+		assert.NotEmpty(t, packets[2].Error, name)
+	}
+}
+
+func TestDumpCleartextMessage(t *testing.T) {
+	pgp := crypto.PGP()
+	key := generateKey(t, pgp)
+	signer, err := pgp.Sign().SigningKey(key).New()
+	if err != nil {
+		t.Fatal("Expected no error while creating signer, got:", err)
+	}
+	cleartext, err := signer.SignCleartext(testMessage)
+	if err != nil {
+		t.Fatal("Expected no error while signing, got:", err)
+	}
+	packets, err := Dump(bytes.NewReader(cleartext), nil)
+	if err != nil {
+		t.Fatal("Expected no error while dumping message, got:", err)
+	}
+	if assert.Len(t, packets, 1) {
+		assert.Exactly(t, "text document (1)", packets[0].SignatureType)
+	}
+
+	var output bytes.Buffer
+	if err := Print(&output, packets); err != nil {
+		t.Fatal("Expected no error while printing packets, got:", err)
+	}
+	assert.Contains(t, output.String(), ":signature packet: tag 2")
+	assert.Contains(t, output.String(), "\tsignature type: text document (1)\n")
+
+	serialized, err := json.Marshal(packets)
+	if err != nil {
+		t.Fatal("Expected no error while encoding packets, got:", err)
+	}
+	assert.Contains(t, string(serialized), `"signatureType":"text document (1)"`)
+}
+
+func TestDumpInvalidInput(t *testing.T) {
+	_, err := Dump(strings.NewReader("-----BEGIN PGP MESSAGE-----\n\n!!!\n-----END PGP MESSAGE-----\n"), nil)
+	assert.Error(t, err)
+	_, err = Dump(bytes.NewReader([]byte{0x00, 0x01, 0x02}), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package packetdump
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+var errTruncated = errors.New("gopenpgp: truncated packet")
+
+// bodyReader reads the fields of a packet body. Reading beyond the end of the
+// body sets err and returns zero values.
+type bodyReader struct {
+	data []byte
+	err  error
+}
+
+func (r *bodyReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.data) {
+		r.err = errTruncated
+		return nil
+	}
+	value := r.data[:n]
+	r.data = r.data[n:]
+	return value
+}
+
+func (r *bodyReader) byte() uint8 {
+	value := r.bytes(1)
+	if value == nil {
+		return 0
+	}
+	return value[0]
+}
+
+func (r *bodyReader) uint(size int) int {
+	value := r.bytes(size)
+	if value == nil {
+		return 0
+	}
+	var padded [8]byte
+	copy(padded[8-size:], value)
+	return int(binary.BigEndian.Uint64(padded[:]))
+}
+
+func (r *bodyReader) hex(n int) string {
+	return strings.ToUpper(hex.EncodeToString(r.bytes(n)))
+}
+
+func (r *bodyReader) time() string {
+	return formatTime(uint32(r.uint(4)))
+}
+
+func (r *bodyReader) rest() []byte {
+	return r.bytes(len(r.data))
+}
+
+func formatTime(unixTime uint32) string {
+	return time.Unix(int64(unixTime), 0).UTC().Format(time.RFC3339)
+}
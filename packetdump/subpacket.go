@@ -0,0 +1,126 @@
+package packetdump
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Subpacket is a subpacket of a signature.
+type Subpacket struct {
+	Type     uint8  `json:"type"`
+	Name     string `json:"name"`
+	Critical bool   `json:"critical,omitempty"`
+	// Value is the human-readable value of the subpacket, e.g. a time or a
+	// list of algorithms, or the hex encoded data of unknown subpackets.
+	Value string `json:"value"`
+}
+
+// parseSubpackets parses the hashed or unhashed subpacket area of a
+// signature.
+func parseSubpackets(area []byte) ([]Subpacket, error) {
+	var subpackets []Subpacket
+	r := &bodyReader{data: area}
+	for len(r.data) > 0 && r.err == nil {
+		var length int
+		switch first := int(r.byte()); {
+		case first < 192:
+			length = first
+		case first < 255:
+			length = (first-192)<<8 + int(r.byte()) + 192
+		default:
+			length = r.uint(4)
+		}
+		data := r.bytes(length)
+		if r.err != nil || length == 0 {
+			return subpackets, errTruncated
+		}
+		subpacketType := data[0] & 0x7f
+		subpackets = append(subpackets, Subpacket{
+			Type:     subpacketType,
+			Name:     typeName(subpacketTypes, subpacketType),
+			Critical: data[0]&0x80 != 0,
+			Value:    subpacketValue(subpacketType, data[1:]),
+		})
+	}
+	return subpackets, r.err
+}
+
+func subpacketValue(subpacketType uint8, data []byte) string {
+	r := &bodyReader{data: data}
+	var value string
+	switch subpacketType {
+	case 2:
+		value = r.time()
+	case 3, 9:
+		value = fmt.Sprintf("%ds", r.uint(4))
+	case 4, 7, 25:
+		value = fmt.Sprint(r.byte() != 0)
+	case 5:
+		value = fmt.Sprintf("level %d, amount %d", r.byte(), r.byte())
+	case 6, 24, 26, 28:
+		value = printable(r.rest())
+	case 11:
+		value = names(ciphers, r.rest())
+	case 21:
+		value = names(hashes, r.rest())
+	case 22:
+		value = names(compressionAlgorithms, r.rest())
+	case 34:
+		value = names(aeadModes, r.rest())
+	case 39:
+		var suites []string
+		for len(r.data) >= 2 {
+			suites = append(suites, name(ciphers, r.byte())+" "+name(aeadModes, r.byte()))
+		}
+		value = strings.Join(suites, ", ")
+	case 12:
+		class := r.byte()
+		value = fmt.Sprintf("class 0x%02x, %s, %s", class, name(publicKeyAlgorithms, r.byte()), r.hex(len(r.data)))
+	case 16:
+		value = r.hex(8)
+	case 33, 35:
+		version := r.byte()
+		value = fmt.Sprintf("v%d %s", version, r.hex(len(r.data)))
+	case 20:
+		flags := r.bytes(4)
+		nameLength, valueLength := r.uint(2), r.uint(2)
+		notationName, notationValue := r.bytes(nameLength), r.bytes(valueLength)
+		if len(flags) == 4 && flags[0]&0x80 != 0 {
+			value = printable(notationName) + "=" + printable(notationValue)
+		} else {
+			value = printable(notationName) + "=" + strings.ToUpper(hex.EncodeToString(notationValue))
+		}
+	case 29:
+		code := r.byte()
+		value = fmt.Sprintf("%d %s", code, printable(r.rest()))
+	case 31:
+		value = fmt.Sprintf("%s, %s, %s", name(publicKeyAlgorithms, r.byte()), name(hashes, r.byte()), r.hex(len(r.data)))
+	case 32:
+		value = fmt.Sprintf("%d bytes", len(data))
+	default:
+		value = strings.ToUpper(hex.EncodeToString(data))
+	}
+	if r.err != nil {
+		return "invalid " + strings.ToUpper(hex.EncodeToString(data))
+	}
+	return value
+}
+
+func names(names map[uint8]string, ids []byte) string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = name(names, id)
+	}
+	return strings.Join(values, ", ")
+}
+
+// printable returns the data as quoted string if it is valid UTF-8, or hex
+// encoded.
+func printable(data []byte) string {
+	if utf8.Valid(data) {
+		return fmt.Sprintf("%q", data)
+	}
+	return strings.ToUpper(hex.EncodeToString(data))
+}
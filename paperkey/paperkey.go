@@ -0,0 +1,312 @@
+// Package paperkey exports the secret parts of private keys in the compact
+// format of the paperkey tool, e.g. for printing them on paper, and restores
+// private keys from them and the public keys.
+//
+// The format is extended to v6 keys with their 32-byte fingerprints.
+package paperkey
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+
+	"github.com/lovoo/gopenpgp/v3/crypto"
+)
+
+// Constants of the paperkey format.
+const (
+	formatVersion = 0
+	// Packet tags of public and secret keys and subkeys.
+	tagSecretKey    = 5
+	tagPublicKey    = 6
+	tagSecretSubkey = 7
+	tagPublicSubkey = 14
+	// lineBytes is the number of bytes per line of the text format.
+	lineBytes = 22
+)
+
+// secretPart is the secret part of a secret key or subkey packet.
+type secretPart struct {
+	version     byte
+	fingerprint []byte
+	// data starts at the S2K usage octet and includes the rest of the packet.
+	data []byte
+}
+
+// Extract returns the secret parts of the primary key and subkeys of the
+// private key in the raw paperkey format.
+// Locked keys stay locked, their secret parts are encrypted.
+func Extract(key *crypto.Key) ([]byte, error) {
+	if !key.IsPrivate() {
+		return nil, errors.New("gopenpgp: a public key has no secret parts")
+	}
+	serialized, err := key.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	output := []byte{formatVersion}
+	reader := packet.NewOpaqueReader(bytes.NewReader(serialized))
+	for {
+		op, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in reading key: %w", err)
+		}
+		if op.Tag != tagSecretKey && op.Tag != tagSecretSubkey {
+			continue
+		}
+		part, err := parseSecretPart(op)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, part.version)
+		output = append(output, part.fingerprint...)
+		output = binary.BigEndian.AppendUint16(output, uint16(len(part.data)))
+		output = append(output, part.data...)
+	}
+	return output, nil
+}
+
+func parseSecretPart(op *packet.OpaquePacket) (*secretPart, error) {
+	p, err := op.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in reading key: %w", err)
+	}
+	privateKey, ok := p.(*packet.PrivateKey)
+	if !ok {
+		return nil, errors.New("gopenpgp: error in reading key")
+	}
+	var publicKey bytes.Buffer
+	if err := privateKey.PublicKey.SerializeForHash(&publicKey); err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in serializing key: %w", err)
+	}
+	// The hashed public key is prefixed with 0x99 and a 2-byte length or
+	// 0x9b and a 4-byte length
+	publicLength := publicKey.Len() - 3
+	if privateKey.Version == 6 {
+		publicLength = publicKey.Len() - 5
+	}
+	data := op.Contents[publicLength:]
+	if len(data) > 0xffff {
+		return nil, errors.New("gopenpgp: secret key part too long")
+	}
+	return &secretPart{
+		version:     byte(privateKey.Version),
+		fingerprint: privateKey.Fingerprint,
+		data:        data,
+	}, nil
+}
+
+// ExtractText returns the secret parts of the private key in the printable
+// text format of paperkey, with a CRC-24 checksum per line and of all data.
+func ExtractText(key *crypto.Key) (string, error) {
+	raw, err := Extract(key)
+	if err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	text.WriteString("# Secret portions of key " + strings.ToUpper(key.GetFingerprint()) + "\n")
+	text.WriteString("# Base16 data extracted by gopenpgp\n")
+	text.WriteString("# File format:\n")
+	text.WriteString("# a) 1 octet:  Version of the paperkey format (currently 0).\n")
+	text.WriteString("# b) 1 octet:  OpenPGP key or subkey version (4 or 6)\n")
+	text.WriteString("# c) n octets: Key fingerprint (20 octets for version 4, 32 octets for version 6)\n")
+	text.WriteString("# d) 2 octets: 16-bit big endian length of the following secret data\n")
+	text.WriteString("# e) n octets: Secret data: a partial OpenPGP secret key or subkey packet,\n")
+	text.WriteString("#              starting with the string-to-key usage octet and continuing\n")
+	text.WriteString("#              until the end of the packet.\n")
+	text.WriteString("# Repeat fields b through e as needed to cover all subkeys.\n")
+	text.WriteString("#\n")
+	text.WriteString("# Each base16 line ends with a CRC-24 of that line.\n")
+	text.WriteString("# The entire block of data ends with a CRC-24 of the entire block of data.\n\n")
+
+	line := 0
+	for offset := 0; offset < len(raw); offset += lineBytes {
+		chunk := raw[offset:min(offset+lineBytes, len(raw))]
+		line++
+		fmt.Fprintf(&text, "%3d: ", line)
+		for _, b := range chunk {
+			fmt.Fprintf(&text, "%02X ", b)
+		}
+		fmt.Fprintf(&text, "%06X\n", crc24(chunk))
+	}
+	fmt.Fprintf(&text, "%3d: %06X\n", line+1, crc24(raw))
+	return text.String(), nil
+}
+
+// ParseText returns the raw secret parts of the text format and verifies
+// the checksums.
+func ParseText(text string) ([]byte, error) {
+	var raw []byte
+	line := 0
+	for _, textLine := range strings.Split(text, "\n") {
+		textLine = strings.TrimSpace(textLine)
+		if textLine == "" || strings.HasPrefix(textLine, "#") {
+			continue
+		}
+		number, fields, found := strings.Cut(textLine, ":")
+		if !found {
+			return nil, fmt.Errorf("gopenpgp: invalid paper key line %q", textLine)
+		}
+		line++
+		if n, err := strconv.Atoi(strings.TrimSpace(number)); err != nil || n != line {
+			return nil, fmt.Errorf("gopenpgp: unexpected paper key line number %q", number)
+		}
+		values := strings.Fields(fields)
+		if len(values) == 0 {
+			return nil, fmt.Errorf("gopenpgp: empty paper key line %d", line)
+		}
+		checksum, err := strconv.ParseUint(values[len(values)-1], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("gopenpgp: invalid checksum in paper key line %d", line)
+		}
+		if len(values) == 1 {
+			// Checksum of all data
+			if uint32(checksum) != crc24(raw) {
+				return nil, errors.New("gopenpgp: invalid paper key checksum")
+			}
+			return raw, nil
+		}
+		chunk, err := hex.DecodeString(strings.Join(values[:len(values)-1], ""))
+		if err != nil {
+			return nil, fmt.Errorf("gopenpgp: invalid data in paper key line %d", line)
+		}
+		if uint32(checksum) != crc24(chunk) {
+			return nil, fmt.Errorf("gopenpgp: invalid checksum in paper key line %d", line)
+		}
+		raw = append(raw, chunk...)
+	}
+	return nil, errors.New("gopenpgp: paper key checksum not found")
+}
+
+// Restore reconstructs the private key from the public key and the raw
+// secret parts.
+func Restore(publicKey *crypto.Key, raw []byte) (*crypto.Key, error) {
+	parts, err := parseSecretParts(raw)
+	if err != nil {
+		return nil, err
+	}
+	serialized, err := publicKey.GetPublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var restored bytes.Buffer
+	reader := packet.NewOpaqueReader(bytes.NewReader(serialized))
+	for {
+		op, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in reading public key: %w", err)
+		}
+		if op.Tag == tagPublicKey || op.Tag == tagPublicSubkey {
+			if err := restoreSecretPacket(op, parts); err != nil {
+				return nil, err
+			}
+		}
+		if err := op.Serialize(&restored); err != nil {
+			return nil, fmt.Errorf("gopenpgp: error in serializing key: %w", err)
+		}
+	}
+	if restored.Len() > 0 && restored.Bytes()[0]&0x3f != tagSecretKey {
+		return nil, errors.New("gopenpgp: paper key does not match the public key")
+	}
+	key, err := crypto.NewKey(restored.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gopenpgp: error in restoring key: %w", err)
+	}
+	return key, nil
+}
+
+// RestoreText reconstructs the private key from the public key and the
+// secret parts in the text format.
+func RestoreText(publicKey *crypto.Key, text string) (*crypto.Key, error) {
+	raw, err := ParseText(text)
+	if err != nil {
+		return nil, err
+	}
+	return Restore(publicKey, raw)
+}
+
+// restoreSecretPacket turns a public key packet into a secret key packet,
+// if the secret part of the key is known.
+func restoreSecretPacket(op *packet.OpaquePacket, parts map[string]*secretPart) error {
+	p, err := op.Parse()
+	if err != nil {
+		return fmt.Errorf("gopenpgp: error in reading public key: %w", err)
+	}
+	publicKey, ok := p.(*packet.PublicKey)
+	if !ok {
+		return errors.New("gopenpgp: error in reading public key")
+	}
+	part, ok := parts[hex.EncodeToString(publicKey.Fingerprint)]
+	if !ok {
+		return nil
+	}
+	op.Contents = append(op.Contents, part.data...)
+	if op.Tag == tagPublicKey {
+		op.Tag = tagSecretKey
+	} else {
+		op.Tag = tagSecretSubkey
+	}
+	return nil
+}
+
+func parseSecretParts(raw []byte) (map[string]*secretPart, error) {
+	if len(raw) == 0 || raw[0] != formatVersion {
+		return nil, errors.New("gopenpgp: unsupported paper key format")
+	}
+	parts := make(map[string]*secretPart)
+	raw = raw[1:]
+	for len(raw) > 0 {
+		part := &secretPart{version: raw[0]}
+		var fingerprintLength int
+		switch part.version {
+		case 4:
+			fingerprintLength = 20
+		case 6:
+			fingerprintLength = 32
+		default:
+			return nil, fmt.Errorf("gopenpgp: unsupported key version %d in paper key", part.version)
+		}
+		if len(raw) < 1+fingerprintLength+2 {
+			return nil, errors.New("gopenpgp: truncated paper key")
+		}
+		part.fingerprint = raw[1 : 1+fingerprintLength]
+		raw = raw[1+fingerprintLength:]
+		length := int(binary.BigEndian.Uint16(raw))
+		if len(raw) < 2+length {
+			return nil, errors.New("gopenpgp: truncated paper key")
+		}
+		part.data = raw[2 : 2+length]
+		raw = raw[2+length:]
+		parts[hex.EncodeToString(part.fingerprint)] = part
+	}
+	return parts, nil
+}
+
+// crc24 computes the OpenPGP CRC-24 checksum, as used by paperkey.
+func crc24(data []byte) uint32 {
+	crc := uint32(0xb704ce)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864cfb
+			}
+		}
+	}
+	return crc & 0xffffff
+}
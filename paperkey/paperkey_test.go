@@ -0,0 +1,140 @@
+package paperkey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lovoo/gopenpgp/v3/constants"
+	"github.com/lovoo/gopenpgp/v3/crypto"
+	"github.com/lovoo/gopenpgp/v3/profile"
+)
+
+const testMessage = "paper"
+
+func TestExtractRestore(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		builder  *crypto.KeyGenerationBuilder
+		security int8
+	}{
+		{"v4 Curve25519", crypto.PGP().KeyGeneration(), constants.StandardSecurity},
+		{"v4 RSA", crypto.PGP().KeyGeneration().OverrideProfileAlgorithm(crypto.KeyGenerationRSA4096), constants.StandardSecurity},
+		{"v6 Curve25519", crypto.PGPWithProfile(profile.RFC9580()).KeyGeneration(), constants.StandardSecurity},
+		{"v6 Curve448", crypto.PGPWithProfile(profile.RFC9580()).KeyGeneration(), constants.HighSecurity},
+		{"v6 ML-DSA", crypto.PGPWithProfile(profile.PostQuantum()).KeyGeneration(), constants.StandardSecurity},
+	} {
+		name := test.name
+		key, err := test.builder.AddUserId("paper", "paper@example.com").New().GenerateKeyWithSecurity(test.security)
+		if err != nil {
+			t.Fatal("Expected no error while generating key, got:", err)
+		}
+		text, err := ExtractText(key)
+		if err != nil {
+			t.Fatal("Expected no error while extracting paper key, got:", err)
+		}
+		publicKey, err := key.ToPublic()
+		if err != nil {
+			t.Fatal("Expected no error while extracting public key, got:", err)
+		}
+		restored, err := RestoreText(publicKey, text)
+		if err != nil {
+			t.Fatal("Expected no error while restoring key of "+name+", got:", err)
+		}
+		assert.True(t, restored.IsPrivate(), name)
+		assert.Exactly(t, key.GetFingerprint(), restored.GetFingerprint(), name)
+		// Generated RSA keys are serialized differently until they are parsed
+		parsedKey, err := key.Copy()
+		if err != nil {
+			t.Fatal("Expected no error while copying key, got:", err)
+		}
+		serialized, err := parsedKey.Serialize()
+		if err != nil {
+			t.Fatal("Expected no error while serializing key, got:", err)
+		}
+		restoredSerialized, err := restored.Serialize()
+		if err != nil {
+			t.Fatal("Expected no error while serializing key, got:", err)
+		}
+		assert.Exactly(t, serialized, restoredSerialized, name)
+
+		encHandle, err := crypto.PGP().Encryption().Recipient(publicKey).New()
+		if err != nil {
+			t.Fatal("Expected no error while creating encryption handle, got:", err)
+		}
+		message, err := encHandle.Encrypt([]byte(testMessage))
+		if err != nil {
+			t.Fatal("Expected no error while encrypting, got:", err)
+		}
+		decHandle, err := crypto.PGP().Decryption().DecryptionKey(restored).New()
+		if err != nil {
+			t.Fatal("Expected no error while creating decryption handle, got:", err)
+		}
+		result, err := decHandle.Decrypt(message.Bytes(), crypto.Bytes)
+		if err != nil {
+			t.Fatal("Expected no error while decrypting with restored key, got:", err)
+		}
+		assert.Exactly(t, testMessage, string(result.Bytes()), name)
+	}
+}
+
+func TestLockedKey(t *testing.T) {
+	key, err := crypto.PGP().KeyGeneration().AddUserId("paper", "paper@example.com").New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	locked, err := crypto.PGP().LockKey(key, []byte("passphrase"))
+	if err != nil {
+		t.Fatal("Expected no error while locking key, got:", err)
+	}
+	raw, err := Extract(locked)
+	if err != nil {
+		t.Fatal("Expected no error while extracting paper key, got:", err)
+	}
+	restored, err := Restore(locked, raw)
+	if err != nil {
+		t.Fatal("Expected no error while restoring key, got:", err)
+	}
+	isLocked, err := restored.IsLocked()
+	if err != nil {
+		t.Fatal("Expected no error while checking key, got:", err)
+	}
+	assert.True(t, isLocked)
+	_, err = restored.Unlock([]byte("passphrase"))
+	assert.NoError(t, err)
+
+	// The secret parts do not belong to another key
+	other, err := crypto.PGP().KeyGeneration().AddUserId("other", "other@example.com").New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	_, err = Restore(other, raw)
+	assert.Error(t, err)
+}
+
+func TestParseTextErrors(t *testing.T) {
+	key, err := crypto.PGP().KeyGeneration().AddUserId("paper", "paper@example.com").New().GenerateKey()
+	if err != nil {
+		t.Fatal("Expected no error while generating key, got:", err)
+	}
+	text, err := ExtractText(key)
+	if err != nil {
+		t.Fatal("Expected no error while extracting paper key, got:", err)
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "  2: ") {
+			// Swap the first two data bytes of the second line
+			fields := strings.Fields(line)
+			fields[1], fields[2] = fields[2], fields[1]
+			lines[i] = "  " + strings.Join(fields, " ")
+		}
+	}
+	_, err = ParseText(strings.Join(lines, "\n"))
+	assert.Error(t, err)
+
+	// Missing final checksum
+	_, err = ParseText(strings.Join(lines[:len(lines)-2], "\n"))
+	assert.Error(t, err)
+}
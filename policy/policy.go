@@ -0,0 +1,229 @@
+// Package policy provides cryptographic policies that decide which
+// algorithms and key sizes are accepted by GopenPGP.
+package policy
+
+import (
+	"crypto"
+	"fmt"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// Context is the context in which an algorithm is used.
+type Context int8
+
+const (
+	// DataSignature is the context of signatures over messages and data.
+	DataSignature Context = iota
+	// SelfSignature is the context of the self-signatures and binding
+	// signatures of keys.
+	SelfSignature
+	// Encryption is the context of encrypting messages.
+	Encryption
+	// Decryption is the context of decrypting messages.
+	Decryption
+)
+
+var allContexts = []Context{DataSignature, SelfSignature, Encryption, Decryption}
+
+// String returns the name of the context.
+func (c Context) String() string {
+	switch c {
+	case DataSignature:
+		return "data signature"
+	case SelfSignature:
+		return "self-signature"
+	case Encryption:
+		return "encryption"
+	case Decryption:
+		return "decryption"
+	}
+	return fmt.Sprintf("context %d", int8(c))
+}
+
+// Policy decides which hash algorithms, ciphers, public-key algorithms and
+// key sizes are accepted in each context.
+// Every rule has a cutoff date: an algorithm is rejected for objects created
+// at or after the cutoff, i.e., signatures created, or messages encrypted or
+// decrypted at that time. A zero cutoff rejects the algorithm at all times.
+// Algorithms without a rule are accepted.
+// A policy must not be modified while it is in use.
+type Policy struct {
+	hashes     map[Context]map[crypto.Hash]time.Time
+	ciphers    map[Context]map[packet.CipherFunction]time.Time
+	publicKeys map[Context]map[packet.PublicKeyAlgorithm]time.Time
+	keySizes   map[Context]map[packet.PublicKeyAlgorithm]minKeySize
+}
+
+type minKeySize struct {
+	bits   int
+	cutoff time.Time
+}
+
+// New creates an empty policy that accepts all algorithms.
+func New() *Policy {
+	return &Policy{
+		hashes:     make(map[Context]map[crypto.Hash]time.Time),
+		ciphers:    make(map[Context]map[packet.CipherFunction]time.Time),
+		publicKeys: make(map[Context]map[packet.PublicKeyAlgorithm]time.Time),
+		keySizes:   make(map[Context]map[packet.PublicKeyAlgorithm]minKeySize),
+	}
+}
+
+// Default returns a policy that rejects algorithms considered broken:
+//   - MD5 signatures at all times,
+//   - SHA-1 and RIPEMD-160 data signatures created after 2013-02-01 and
+//     self-signatures created after 2023-02-01,
+//   - encryption with 3DES and CAST5, while decryption is still accepted,
+//   - RSA, DSA and ElGamal keys with less than 2048 bits after 2014-01-01.
+func Default() *Policy {
+	collision := time.Date(2013, 2, 1, 0, 0, 0, 0, time.UTC)
+	secondPreimage := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	weakKeys := time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := New().RejectHash(crypto.MD5, time.Time{})
+	for _, hash := range []crypto.Hash{crypto.SHA1, crypto.RIPEMD160} {
+		p.RejectHash(hash, collision, DataSignature).
+			RejectHash(hash, secondPreimage, SelfSignature)
+	}
+	for _, cipher := range []packet.CipherFunction{packet.Cipher3DES, packet.CipherCAST5} {
+		p.RejectCipher(cipher, time.Time{}, Encryption)
+	}
+	for _, algo := range []packet.PublicKeyAlgorithm{
+		packet.PubKeyAlgoRSA,
+		packet.PubKeyAlgoRSAEncryptOnly,
+		packet.PubKeyAlgoRSASignOnly,
+		packet.PubKeyAlgoDSA,
+		packet.PubKeyAlgoElGamal,
+	} {
+		p.MinKeySize(algo, 2048, weakKeys)
+	}
+	return p
+}
+
+// RejectHash rejects the hash algorithm in the contexts at and after the cutoff.
+// If no context is given, the hash algorithm is rejected in all contexts.
+func (p *Policy) RejectHash(hash crypto.Hash, cutoff time.Time, contexts ...Context) *Policy {
+	for _, context := range contextsOrAll(contexts) {
+		if p.hashes[context] == nil {
+			p.hashes[context] = make(map[crypto.Hash]time.Time)
+		}
+		p.hashes[context][hash] = cutoff
+	}
+	return p
+}
+
+// RejectCipher rejects the symmetric cipher in the contexts at and after the cutoff.
+// If no context is given, the cipher is rejected in all contexts.
+func (p *Policy) RejectCipher(cipher packet.CipherFunction, cutoff time.Time, contexts ...Context) *Policy {
+	for _, context := range contextsOrAll(contexts) {
+		if p.ciphers[context] == nil {
+			p.ciphers[context] = make(map[packet.CipherFunction]time.Time)
+		}
+		p.ciphers[context][cipher] = cutoff
+	}
+	return p
+}
+
+// RejectPublicKeyAlgorithm rejects the public-key algorithm in the contexts
+// at and after the cutoff.
+// If no context is given, the algorithm is rejected in all contexts.
+func (p *Policy) RejectPublicKeyAlgorithm(algo packet.PublicKeyAlgorithm, cutoff time.Time, contexts ...Context) *Policy {
+	for _, context := range contextsOrAll(contexts) {
+		if p.publicKeys[context] == nil {
+			p.publicKeys[context] = make(map[packet.PublicKeyAlgorithm]time.Time)
+		}
+		p.publicKeys[context][algo] = cutoff
+	}
+	return p
+}
+
+// MinKeySize rejects keys of the public-key algorithm with less than bits
+// bits in the contexts at and after the cutoff.
+// Key sizes are only checked for RSA, DSA and ElGamal keys.
+// If no context is given, the key size is required in all contexts.
+func (p *Policy) MinKeySize(algo packet.PublicKeyAlgorithm, bits int, cutoff time.Time, contexts ...Context) *Policy {
+	for _, context := range contextsOrAll(contexts) {
+		if p.keySizes[context] == nil {
+			p.keySizes[context] = make(map[packet.PublicKeyAlgorithm]minKeySize)
+		}
+		p.keySizes[context][algo] = minKeySize{bits: bits, cutoff: cutoff}
+	}
+	return p
+}
+
+// CheckHash returns a *Violation if the hash algorithm is rejected in the
+// context for an object created at the given time.
+func (p *Policy) CheckHash(hash crypto.Hash, context Context, at time.Time) error {
+	if cutoff, ok := p.hashes[context][hash]; ok && !at.Before(cutoff) {
+		return &Violation{Context: context, Hash: hash, Time: at, Cutoff: cutoff}
+	}
+	return nil
+}
+
+// CheckCipher returns a *Violation if the symmetric cipher is rejected in
+// the context at the given time.
+func (p *Policy) CheckCipher(cipher packet.CipherFunction, context Context, at time.Time) error {
+	if cutoff, ok := p.ciphers[context][cipher]; ok && !at.Before(cutoff) {
+		return &Violation{Context: context, Cipher: cipher, Time: at, Cutoff: cutoff}
+	}
+	return nil
+}
+
+// CheckPublicKey returns a *Violation if the public-key algorithm or the key
+// size in bits is rejected in the context at the given time.
+// A key size of zero is unknown and not checked.
+func (p *Policy) CheckPublicKey(algo packet.PublicKeyAlgorithm, bits int, context Context, at time.Time) error {
+	if cutoff, ok := p.publicKeys[context][algo]; ok && !at.Before(cutoff) {
+		return &Violation{Context: context, PublicKeyAlgorithm: algo, Time: at, Cutoff: cutoff}
+	}
+	if minSize, ok := p.keySizes[context][algo]; ok && bits > 0 && bits < minSize.bits && !at.Before(minSize.cutoff) {
+		return &Violation{Context: context, PublicKeyAlgorithm: algo, Bits: bits, Time: at, Cutoff: minSize.cutoff}
+	}
+	return nil
+}
+
+// Violation is the error returned if a policy rejects an algorithm or a
+// key size. Only the field of the rejected algorithm is set.
+type Violation struct {
+	// Context is the context in which the algorithm was rejected.
+	Context Context
+	// Hash is the rejected hash algorithm.
+	Hash crypto.Hash
+	// Cipher is the rejected symmetric cipher.
+	Cipher packet.CipherFunction
+	// PublicKeyAlgorithm is the algorithm of the rejected key.
+	PublicKeyAlgorithm packet.PublicKeyAlgorithm
+	// Bits is the size of the rejected key, if the key is rejected for its size.
+	Bits int
+	// Time is the time that was checked against the cutoff.
+	Time time.Time
+	// Cutoff is the cutoff date of the rule, zero if the rule applies at all times.
+	Cutoff time.Time
+}
+
+// Error returns the description of the violation.
+func (v *Violation) Error() string {
+	var subject string
+	switch {
+	case v.Hash != 0:
+		subject = fmt.Sprintf("hash algorithm %s", v.Hash)
+	case v.Cipher != 0:
+		subject = fmt.Sprintf("cipher %d", v.Cipher)
+	case v.Bits != 0:
+		subject = fmt.Sprintf("%d-bit key of public-key algorithm %d", v.Bits, v.PublicKeyAlgorithm)
+	default:
+		subject = fmt.Sprintf("public-key algorithm %d", v.PublicKeyAlgorithm)
+	}
+	if v.Cutoff.IsZero() {
+		return fmt.Sprintf("gopenpgp: policy rejects %s for %s", subject, v.Context)
+	}
+	return fmt.Sprintf("gopenpgp: policy rejects %s for %s since %s", subject, v.Context, v.Cutoff.Format(time.DateOnly))
+}
+
+func contextsOrAll(contexts []Context) []Context {
+	if len(contexts) == 0 {
+		return allContexts
+	}
+	return contexts
+}
@@ -0,0 +1,57 @@
+package policy
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	p := Default()
+	before := time.Date(2012, 6, 1, 0, 0, 0, 0, time.UTC)
+	after := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, p.CheckHash(crypto.SHA1, DataSignature, before))
+	assert.Error(t, p.CheckHash(crypto.SHA1, DataSignature, after))
+	assert.NoError(t, p.CheckHash(crypto.SHA1, SelfSignature, after))
+	assert.Error(t, p.CheckHash(crypto.MD5, SelfSignature, before))
+	assert.NoError(t, p.CheckHash(crypto.SHA256, DataSignature, after))
+
+	assert.Error(t, p.CheckCipher(packet.Cipher3DES, Encryption, before))
+	assert.NoError(t, p.CheckCipher(packet.Cipher3DES, Decryption, after))
+	assert.NoError(t, p.CheckCipher(packet.CipherAES256, Encryption, after))
+
+	assert.NoError(t, p.CheckPublicKey(packet.PubKeyAlgoRSA, 1024, DataSignature, before))
+	assert.Error(t, p.CheckPublicKey(packet.PubKeyAlgoRSA, 1024, Encryption, after))
+	assert.NoError(t, p.CheckPublicKey(packet.PubKeyAlgoRSA, 2048, Encryption, after))
+	assert.NoError(t, p.CheckPublicKey(packet.PubKeyAlgoEdDSA, 0, DataSignature, after))
+}
+
+func TestViolation(t *testing.T) {
+	cutoff := time.Date(2013, 2, 1, 0, 0, 0, 0, time.UTC)
+	p := New().
+		RejectHash(crypto.SHA1, cutoff, DataSignature).
+		RejectPublicKeyAlgorithm(packet.PubKeyAlgoDSA, time.Time{})
+	at := time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	err := p.CheckHash(crypto.SHA1, DataSignature, at)
+	var violation *Violation
+	if !errors.As(err, &violation) {
+		t.Fatal("Expected a violation, got:", err)
+	}
+	assert.Exactly(t, &Violation{Context: DataSignature, Hash: crypto.SHA1, Time: at, Cutoff: cutoff}, violation)
+	assert.Exactly(t, "gopenpgp: policy rejects hash algorithm SHA-1 for data signature since 2013-02-01", err.Error())
+
+	for _, context := range []Context{DataSignature, SelfSignature, Encryption, Decryption} {
+		err = p.CheckPublicKey(packet.PubKeyAlgoDSA, 2048, context, at)
+		if !errors.As(err, &violation) {
+			t.Fatal("Expected a violation, got:", err)
+		}
+		assert.Exactly(t, context, violation.Context)
+		assert.True(t, violation.Cutoff.IsZero())
+	}
+}
@@ -80,3 +80,38 @@ func RFC9580() *Custom {
 		V6: true,
 	}
 }
+
+// PostQuantum returns a custom profile for this library
+// that generates post-quantum hybrid keys as defined in the
+// OpenPGP PQC draft (draft-ietf-openpgp-pqc).
+// Keys consist of a composite ML-DSA-65 + Ed25519 signing key and a composite
+// ML-KEM-768 + X25519 encryption subkey, or ML-DSA-87 + Ed448 and
+// ML-KEM-1024 + X448 for the high security level.
+// Messages are encrypted with AEAD as in RFC9580.
+func PostQuantum() *Custom {
+	setKeyAlgorithm := func(cfg *packet.Config, securityLevel int8) {
+		switch securityLevel {
+		case constants.HighSecurity:
+			cfg.Algorithm = packet.PubKeyAlgoMldsa87Ed448
+		default:
+			cfg.Algorithm = packet.PubKeyAlgoMldsa65Ed25519
+		}
+	}
+	return &Custom{
+		SetKeyAlgorithm:      setKeyAlgorithm,
+		Hash:                 crypto.SHA512,
+		CipherEncryption:     packet.CipherAES256,
+		CompressionAlgorithm: packet.CompressionZLIB,
+		AeadKeyEncryption:    &packet.AEADConfig{},
+		AeadEncryption:       &packet.AEADConfig{},
+		S2kKeyEncryption: &s2k.Config{
+			S2KMode:      s2k.Argon2S2K,
+			Argon2Config: &s2k.Argon2Config{},
+		},
+		S2kEncryption: &s2k.Config{
+			S2KMode:      s2k.Argon2S2K,
+			Argon2Config: &s2k.Argon2Config{},
+		},
+		V6: true,
+	}
+}